@@ -0,0 +1,308 @@
+package main
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/api"
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/db"
+	"github.com/blockroma/soroban-indexer/pkg/poller"
+	"github.com/blockroma/soroban-indexer/pkg/reindex"
+	"github.com/blockroma/soroban-indexer/pkg/replay"
+	"github.com/blockroma/soroban-indexer/pkg/source"
+	"github.com/blockroma/soroban-indexer/pkg/verify"
+	"github.com/blockroma/soroban-indexer/pkg/worker"
+)
+
+func main() {
+	// Parse CLI flags
+	startLedger := flag.Uint("start-ledger", 0, "Start ledger for backfill mode (0 = live polling)")
+	endLedger := flag.Uint("end-ledger", 0, "End ledger for backfill mode (0 = current ledger)")
+	batchSize := flag.Uint("batch-size", 100, "Batch size for backfill mode")
+	rateLimit := flag.Uint("rate-limit", 10, "Max requests per second for backfill mode")
+	workers := flag.Int("workers", 4, "Shards processed concurrently in backfill mode")
+	job := flag.String("job", "", "Backfill job name; re-running a job resumes it from its checkpoints (default: backfill-<start>-<end>)")
+	maxRetries := flag.Int("max-retries", 3, "Attempts per failed backfill batch before giving up")
+	cursorName := flag.String("cursor", getEnv("CURSOR_NAME", "live"), "Named cursor for live polling")
+	datastore := flag.String("datastore", "", "Backfill from a local Galexie datastore export instead of getLedgers")
+	archiveDir := flag.String("archive", getEnv("ARCHIVE_DIR", ""), "Archive raw RPC payloads to this directory (replay reads from it)")
+	ingestMode := flag.String("mode", getEnv("INGEST_MODE", "events"), "Ingestion source: events (getEvents) or ledgers (getLedgers)")
+	flag.Parse()
+
+	logger := logrus.New()
+	logger.SetFormatter(&logrus.JSONFormatter{})
+
+	// Set log level from environment variable
+	logLevel := getEnv("LOG_LEVEL", "info")
+	switch logLevel {
+	case "debug":
+		logger.SetLevel(logrus.DebugLevel)
+	case "info":
+		logger.SetLevel(logrus.InfoLevel)
+	case "warn":
+		logger.SetLevel(logrus.WarnLevel)
+	case "error":
+		logger.SetLevel(logrus.ErrorLevel)
+	default:
+		logger.SetLevel(logrus.InfoLevel)
+	}
+
+	// Get configuration from environment (minimal env vars)
+	rpcURL := getEnv("STELLAR_RPC_URL", "http://stellar-rpc:8000")
+	postgresURL := getEnv("POSTGRES_DSN", "")
+
+	if postgresURL == "" {
+		logger.Fatal("POSTGRES_DSN environment variable is required")
+	}
+
+	mode, err := poller.ParseIngestMode(*ingestMode)
+	if err != nil {
+		logger.WithError(err).Fatal("Invalid ingest mode")
+	}
+
+	// Determine mode
+	isBackfillMode := *startLedger > 0
+	if isBackfillMode {
+		logger.WithFields(logrus.Fields{
+			"startLedger": *startLedger,
+			"endLedger":   *endLedger,
+			"batchSize":   *batchSize,
+			"rateLimit":   *rateLimit,
+			"workers":     *workers,
+			"job":         *job,
+		}).Info("Starting in BACKFILL mode")
+	} else {
+		logger.Info("Starting in LIVE POLLING mode")
+	}
+
+	logger.WithFields(logrus.Fields{
+		"rpcURL": rpcURL,
+		"mode":   mode,
+	}).Info("Starting Stellar RPC Indexer")
+
+	// Connect to database
+	database, err := db.Connect(postgresURL)
+	if err != nil {
+		logger.WithError(err).Fatal("Failed to connect to database")
+	}
+	defer database.Close()
+
+	var store *archive.Store
+	if *archiveDir != "" {
+		if store, err = archive.Open(*archiveDir); err != nil {
+			logger.WithError(err).Fatal("Failed to open archive")
+		}
+	}
+
+	// Replay rebuilds derived tables from the archive and never touches the RPC
+	if flag.Arg(0) == "replay" {
+		if store == nil {
+			logger.Fatal("replay requires -archive or ARCHIVE_DIR")
+		}
+		if err := replay.Command(context.Background(), replay.New(database.DB, store, logger), flag.Args()[1:], os.Stdout); err != nil {
+			logger.WithError(err).Error("Replay failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Reindex rebuilds derived token tables from stored rows and never touches the RPC
+	if flag.Arg(0) == "reindex" {
+		if err := reindex.Command(context.Background(), reindex.New(database.DB, logger), flag.Args()[1:], os.Stdout); err != nil {
+			logger.WithError(err).Error("Reindex failed")
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Create RPC client
+	rpcClient := client.NewClient(rpcURL)
+
+	// Set logger on circuit breaker for detailed failure logging
+	rpcClient.SetLogger(worker.NewLogrusAdapter(logger))
+
+	// Check RPC connectivity
+	ctx := context.Background()
+	if err := rpcClient.Health(ctx); err != nil {
+		if *datastore == "" {
+			logger.WithError(err).Fatal("RPC health check failed")
+		}
+		logger.WithError(err).Warn("RPC health check failed, backfilling from datastore only")
+	}
+
+	network, err := rpcClient.GetNetwork(ctx)
+	if err != nil {
+		logger.WithError(err).Warn("Failed to get network info")
+	} else {
+		logger.WithFields(logrus.Fields{
+			"passphrase": network.Passphrase,
+			"protocol":   network.ProtocolVersion,
+		}).Info("Connected to Stellar network")
+	}
+
+	// Ledgers older than the RPC retention window are read from HISTORY_DIR when set
+	var history source.Source
+	if dir := getEnv("HISTORY_DIR", ""); dir != "" {
+		if history, err = source.NewDir(dir); err != nil {
+			logger.WithError(err).Fatal("Failed to open history source")
+		}
+	}
+
+	config := poller.PollerConfig{
+		BatchSize:      1000,
+		MaxConcurrency: 10,
+		Mode:           mode,
+		CursorName:     *cursorName,
+		HistorySource:  history,
+	}
+	if store != nil {
+		config.Archive = store
+	}
+
+	// Backfill reads full ledgers from the datastore without touching the RPC
+	if *datastore != "" {
+		if !isBackfillMode {
+			logger.Fatal("-datastore requires backfill mode (-start-ledger)")
+		}
+		store, err := source.NewDatastore(*datastore, source.DatastoreSchema{})
+		if err != nil {
+			logger.WithError(err).Fatal("Failed to open datastore")
+		}
+		config.LedgerSource = store
+		config.NetworkPassphrase = store.NetworkPassphrase()
+	}
+
+	// Create poller
+	p := poller.NewWithConfig(rpcClient, database.DB, logger, config)
+
+	verifier := verify.New(database.DB, rpcClient, logger)
+
+	// Subcommands run once and exit
+	switch flag.Arg(0) {
+	case "verify":
+		if err := verify.Command(ctx, verifier, p, flag.Args()[1:], os.Stdout); err != nil {
+			logger.WithError(err).Error("Verify failed")
+			os.Exit(1)
+		}
+		return
+	case "":
+	default:
+		logger.Fatalf("Unknown subcommand %q", flag.Arg(0))
+	}
+
+	// Start health/metrics HTTP server
+	go startHTTPServer(p, verifier, database.DB, logger)
+
+	// Setup graceful shutdown
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	sigCh := make(chan os.Signal, 1)
+	signal.Notify(sigCh, syscall.SIGINT, syscall.SIGTERM)
+
+	// Start appropriate mode
+	errCh := make(chan error, 1)
+	if isBackfillMode {
+		// Backfill mode: process historical ledgers
+		go func() {
+			config := poller.BackfillConfig{
+				StartLedger: uint32(*startLedger),
+				EndLedger:   uint32(*endLedger),
+				BatchSize:   uint32(*batchSize),
+				RateLimit:   uint32(*rateLimit),
+				Workers:     *workers,
+				Job:         *job,
+				MaxRetries:  *maxRetries,
+			}
+			if err := p.Backfill(ctx, config); err != nil {
+				errCh <- err
+			} else {
+				logger.Info("Backfill completed successfully")
+				cancel() // Exit after successful backfill
+			}
+		}()
+	} else {
+		// Live polling mode: continuous processing
+		go func() {
+			if err := p.Start(ctx); err != nil {
+				errCh <- err
+			}
+		}()
+
+		// Periodic gap detection and repair (VERIFY_INTERVAL, e.g. "1h"; empty = disabled)
+		if interval := getEnv("VERIFY_INTERVAL", ""); interval != "" {
+			every, err := time.ParseDuration(interval)
+			if err != nil {
+				logger.WithError(err).Fatal("Invalid VERIFY_INTERVAL")
+			}
+			// Repairs run on their own poller so they never share state with live polling
+			repairer := poller.NewWithConfig(rpcClient, database.DB, logger, poller.PollerConfig{Mode: mode})
+			go verifier.Run(ctx, every, verify.Config{Repair: getEnv("VERIFY_REPAIR", "true") == "true"}, repairer)
+		}
+	}
+
+	// Wait for shutdown signal or error
+	select {
+	case <-sigCh:
+		logger.Info("Received shutdown signal")
+		cancel()
+		time.Sleep(2 * time.Second) // Grace period
+	case err := <-errCh:
+		if err != nil {
+			logger.WithError(err).Error("Indexer error")
+		}
+		cancel()
+	case <-ctx.Done():
+		// Context cancelled (e.g., backfill completed)
+		logger.Info("Context cancelled")
+	}
+
+	logger.Info("Indexer stopped")
+}
+
+// startHTTPServer starts health and metrics HTTP server
+func startHTTPServer(p *poller.Poller, verifier *verify.Verifier, database *gorm.DB, logger *logrus.Logger) {
+	api.RegisterCursorRoutes(http.DefaultServeMux, database)
+	api.RegisterVerifyRoutes(http.DefaultServeMux, verifier)
+	api.RegisterHealthRoutes(http.DefaultServeMux, p)
+	api.RegisterContractDataRoutes(http.DefaultServeMux, database)
+	api.RegisterTokenRoutes(http.DefaultServeMux, database)
+
+	http.HandleFunc("/stats", func(w http.ResponseWriter, r *http.Request) {
+		stats, err := p.GetStats()
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprintf(w, `{
+			"lastLedger": %v,
+			"totalEvents": %v,
+			"totalTransactions": %v
+		}`, stats["lastLedger"], stats["totalEvents"], stats["totalTransactions"])
+	})
+
+	logger.Info("HTTP server listening on :8080")
+	if err := http.ListenAndServe(":8080", nil); err != nil {
+		logger.WithError(err).Error("HTTP server error")
+	}
+}
+
+func getEnv(key, defaultValue string) string {
+	if value := os.Getenv(key); value != "" {
+		return value
+	}
+	return defaultValue
+}
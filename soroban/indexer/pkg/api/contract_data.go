@@ -0,0 +1,83 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// ContractDataResponse is the JSON form of a contract storage entry version
+type ContractDataResponse struct {
+	ContractID string          `json:"contractId"`
+	Key        json.RawMessage `json:"key,omitempty"`
+	KeyXdr     string          `json:"keyXdr"`
+	Val        json.RawMessage `json:"val,omitempty"`
+	ValXdr     string          `json:"valXdr,omitempty"`
+	Durability string          `json:"durability"`
+	Ledger     uint32          `json:"ledger"` // Ledger the version was written in
+	TxHash     string          `json:"txHash,omitempty"`
+	OpIndex    int32           `json:"opIndex"`
+	ChangeType string          `json:"changeType"`
+}
+
+// RegisterContractDataRoutes adds the contract storage endpoints to mux:
+//
+//	GET /contracts/{id}/storage?key=<xdr>&durability=<d>&ledger=<n>
+//	    value of a storage entry at the end of ledger n (default: latest); key is the base64
+//	    XDR of the ScVal key, durability persistent (default) or temporary
+//
+// A removed entry is returned with changeType "removed"; one never written is a 404
+func RegisterContractDataRoutes(mux *http.ServeMux, db *gorm.DB) {
+	mux.HandleFunc("GET /contracts/{id}/storage", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		key := query.Get("key")
+		if key == "" {
+			http.Error(w, "key query parameter is required", http.StatusBadRequest)
+			return
+		}
+		durability := query.Get("durability")
+		switch durability {
+		case "":
+			durability = "persistent"
+		case "persistent", "temporary":
+		default:
+			http.Error(w, "durability must be persistent or temporary", http.StatusBadRequest)
+			return
+		}
+		ledger, err := uint32Param(query.Get("ledger"))
+		if err != nil {
+			http.Error(w, "ledger must be a ledger sequence", http.StatusBadRequest)
+			return
+		}
+		if ledger == 0 {
+			ledger = ^uint32(0)
+		}
+
+		version, err := models.GetContractDataAt(db, r.PathValue("id"), key, durability, ledger)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if version == nil {
+			http.Error(w, "storage entry not found", http.StatusNotFound)
+			return
+		}
+
+		writeJSON(w, ContractDataResponse{
+			ContractID: version.ContractID,
+			Key:        json.RawMessage(version.Key),
+			KeyXdr:     version.KeyXdr,
+			Val:        json.RawMessage(version.Val),
+			ValXdr:     version.ValXdr,
+			Durability: version.Durability,
+			Ledger:     version.Ledger,
+			TxHash:     version.TxHash,
+			OpIndex:    version.OpIndex,
+			ChangeType: version.ChangeType,
+		})
+	})
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+func TestContractStorageAt(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.ContractDataHistory{}))
+
+	version := func(ledger uint32, txIndex int32, changeType, val string) *models.ContractDataHistory {
+		row := &models.ContractDataHistory{
+			Ledger: ledger, TxIndex: txIndex, TxHash: "hash", ChangeType: changeType,
+			ContractID: "CA", KeyXdr: "AAAADwAAAAdDb3VudGVyAA==", Key: models.JSONB(`"Counter"`), Durability: "persistent",
+		}
+		if val != "" {
+			row.Val = models.JSONB(`{"count":` + val + `}`)
+			row.ValXdr = "xdr-" + val
+		}
+		return row
+	}
+	require.NoError(t, models.InsertContractDataHistory(db, []*models.ContractDataHistory{
+		version(100, 1, models.ContractDataCreated, "1"),
+		version(105, 1, models.ContractDataUpdated, "2"),
+		version(105, 3, models.ContractDataUpdated, "3"),
+		version(110, models.EvictionTxIndex, models.ContractDataRemoved, ""),
+	}))
+	// A temporary entry with the same key is a different entry
+	temporary := version(106, 1, models.ContractDataCreated, "9")
+	temporary.KeyHash, temporary.Durability = "temporary-hash", "temporary"
+	require.NoError(t, models.InsertContractDataHistory(db, []*models.ContractDataHistory{temporary}))
+
+	mux := http.NewServeMux()
+	RegisterContractDataRoutes(mux, db)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	storageURL := func(query string) string {
+		return server.URL + "/contracts/CA/storage?key=" + url.QueryEscape("AAAADwAAAAdDb3VudGVyAA==") + query
+	}
+
+	var resp ContractDataResponse
+	require.Equal(t, http.StatusOK, getJSON(t, storageURL("&ledger=104"), &resp))
+	assert.JSONEq(t, `{"count":1}`, string(resp.Val))
+	assert.Equal(t, uint32(100), resp.Ledger)
+
+	// The last change of the ledger wins
+	resp = ContractDataResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, storageURL("&ledger=105"), &resp))
+	assert.JSONEq(t, `{"count":3}`, string(resp.Val))
+
+	resp = ContractDataResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, storageURL(""), &resp))
+	assert.Equal(t, models.ContractDataRemoved, resp.ChangeType)
+	assert.Empty(t, resp.Val)
+
+	resp = ContractDataResponse{}
+	require.Equal(t, http.StatusOK, getJSON(t, storageURL("&durability=temporary"), &resp))
+	assert.JSONEq(t, `{"count":9}`, string(resp.Val))
+	assert.Equal(t, "temporary", resp.Durability)
+	assert.Equal(t, http.StatusNotFound, getJSON(t, storageURL("&durability=temporary&ledger=105"), &resp))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, storageURL("&durability=instance"), &resp))
+
+	assert.Equal(t, http.StatusNotFound, getJSON(t, storageURL("&ledger=99"), &resp))
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server.URL+"/contracts/CA/storage", &resp))
+}
@@ -0,0 +1,149 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"strconv"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// CursorResponse is the JSON form of a named cursor
+type CursorResponse struct {
+	Name        string    `json:"name"`
+	LastLedger  uint32    `json:"lastLedger"`
+	PagingToken string    `json:"pagingToken,omitempty"`
+	UpdatedAt   time.Time `json:"updatedAt"`
+}
+
+// ShardResponse is the JSON form of a backfill shard checkpoint
+type ShardResponse struct {
+	ID          string `json:"id"`
+	StartLedger uint32 `json:"startLedger"`
+	EndLedger   uint32 `json:"endLedger"`
+	Checkpoint  uint32 `json:"checkpoint"`
+	Status      string `json:"status"`
+}
+
+// RetryResponse is the JSON form of a queued backfill batch
+type RetryResponse struct {
+	StartLedger uint32 `json:"startLedger"`
+	EndLedger   uint32 `json:"endLedger"`
+	Attempts    int    `json:"attempts"`
+	LastError   string `json:"lastError"`
+}
+
+// CursorDetailResponse is a cursor together with the backfill job state stored under its name
+type CursorDetailResponse struct {
+	CursorResponse
+	Shards  []ShardResponse `json:"shards,omitempty"`
+	Retries []RetryResponse `json:"retries,omitempty"`
+}
+
+// RegisterCursorRoutes adds the cursor endpoints to mux:
+//
+//	GET  /cursors                           list every named cursor
+//	GET  /cursors/{name}                    inspect a cursor and its backfill shards/retries
+//	POST /cursors/{name}/reset?ledger=<n>   move a cursor to ledger n (0 deletes it)
+func RegisterCursorRoutes(mux *http.ServeMux, db *gorm.DB) {
+	mux.HandleFunc("GET /cursors", func(w http.ResponseWriter, r *http.Request) {
+		cursors, err := models.ListCursors(db)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		resp := make([]CursorResponse, len(cursors))
+		for i, cursor := range cursors {
+			resp[i] = cursorResponse(cursor)
+		}
+		writeJSON(w, resp)
+	})
+
+	mux.HandleFunc("GET /cursors/{name}", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		cursor, err := models.GetCursorState(db, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		shards, err := models.GetBackfillShards(db, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		retries, err := models.GetBackfillRetries(db, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		if cursor.UpdatedAt.IsZero() && len(shards) == 0 {
+			http.Error(w, "cursor not found", http.StatusNotFound)
+			return
+		}
+
+		resp := CursorDetailResponse{CursorResponse: cursorResponse(*cursor)}
+		for _, shard := range shards {
+			resp.Shards = append(resp.Shards, ShardResponse{
+				ID:          shard.ID,
+				StartLedger: shard.StartLedger,
+				EndLedger:   shard.EndLedger,
+				Checkpoint:  shard.Checkpoint,
+				Status:      shard.Status,
+			})
+		}
+		for _, retry := range retries {
+			resp.Retries = append(resp.Retries, RetryResponse{
+				StartLedger: retry.StartLedger,
+				EndLedger:   retry.EndLedger,
+				Attempts:    retry.Attempts,
+				LastError:   retry.LastError,
+			})
+		}
+		writeJSON(w, resp)
+	})
+
+	mux.HandleFunc("POST /cursors/{name}/reset", func(w http.ResponseWriter, r *http.Request) {
+		name := r.PathValue("name")
+
+		ledger, err := strconv.ParseUint(r.URL.Query().Get("ledger"), 10, 32)
+		if err != nil {
+			http.Error(w, "ledger query parameter must be a ledger sequence", http.StatusBadRequest)
+			return
+		}
+
+		if err := models.ResetCursor(db, name, uint32(ledger)); err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+
+		cursor, err := models.GetCursorState(db, name)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, cursorResponse(*cursor))
+	})
+}
+
+func cursorResponse(cursor models.Cursor) CursorResponse {
+	return CursorResponse{
+		Name:        cursor.Name,
+		LastLedger:  cursor.LastLedger,
+		PagingToken: cursor.PagingToken,
+		UpdatedAt:   cursor.UpdatedAt,
+	}
+}
+
+// writeJSON writes v as a JSON response body
+func writeJSON(w http.ResponseWriter, v interface{}) {
+	w.Header().Set("Content-Type", "application/json")
+	if err := json.NewEncoder(w).Encode(v); err != nil {
+		http.Error(w, err.Error(), http.StatusInternalServerError)
+	}
+}
@@ -0,0 +1,102 @@
+package api
+
+import (
+	"encoding/json"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+func setupTestServer(t *testing.T) (*gorm.DB, *httptest.Server) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.Cursor{}, &models.BackfillShard{}, &models.BackfillRetry{}))
+
+	mux := http.NewServeMux()
+	RegisterCursorRoutes(mux, db)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	return db, server
+}
+
+func getJSON(t *testing.T, url string, v interface{}) int {
+	resp, err := http.Get(url)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+
+	if resp.StatusCode == http.StatusOK {
+		require.NoError(t, json.NewDecoder(resp.Body).Decode(v))
+	}
+	return resp.StatusCode
+}
+
+func TestListCursors(t *testing.T) {
+	db, server := setupTestServer(t)
+	require.NoError(t, models.UpdateCursorWithToken(db, models.LiveCursor, 5000, "token"))
+	require.NoError(t, models.UpdateCursor(db, "backfill-100-200", 150))
+
+	var cursors []CursorResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/cursors", &cursors))
+	require.Len(t, cursors, 2)
+	assert.Equal(t, "backfill-100-200", cursors[0].Name)
+	assert.Equal(t, uint32(150), cursors[0].LastLedger)
+	assert.Equal(t, models.LiveCursor, cursors[1].Name)
+	assert.Equal(t, "token", cursors[1].PagingToken)
+}
+
+func TestInspectCursor(t *testing.T) {
+	db, server := setupTestServer(t)
+	require.NoError(t, models.CreateBackfillShards(db, "job", []models.BackfillShard{
+		{StartLedger: 100, EndLedger: 149},
+		{StartLedger: 150, EndLedger: 200},
+	}))
+	require.NoError(t, models.UpdateShardCheckpoint(db, "job:100-149", 149, models.ShardStatusCompleted))
+	require.NoError(t, models.EnqueueBackfillRetry(db, "job", 120, 129, errors.New("timeout")))
+	require.NoError(t, models.UpdateCursor(db, "job", 119))
+
+	var detail CursorDetailResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/cursors/job", &detail))
+	assert.Equal(t, uint32(119), detail.LastLedger)
+	require.Len(t, detail.Shards, 2)
+	assert.Equal(t, models.ShardStatusCompleted, detail.Shards[0].Status)
+	assert.Equal(t, uint32(149), detail.Shards[1].Checkpoint)
+	require.Len(t, detail.Retries, 1)
+	assert.Equal(t, "timeout", detail.Retries[0].LastError)
+
+	assert.Equal(t, http.StatusNotFound, getJSON(t, server.URL+"/cursors/missing", &detail))
+}
+
+func TestResetCursor(t *testing.T) {
+	db, server := setupTestServer(t)
+	require.NoError(t, models.UpdateCursorWithToken(db, models.LiveCursor, 5000, "token"))
+
+	resp, err := http.Post(server.URL+"/cursors/live/reset?ledger=4000", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+
+	state, err := models.GetCursorState(db, models.LiveCursor)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(4000), state.LastLedger)
+	assert.Empty(t, state.PagingToken)
+
+	resp, err = http.Post(server.URL+"/cursors/live/reset?ledger=abc", "", nil)
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, resp.StatusCode)
+
+	// GET is not allowed on the reset endpoint
+	resp, err = http.Get(server.URL + "/cursors/live/reset")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusMethodNotAllowed, resp.StatusCode)
+}
@@ -0,0 +1,30 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/blockroma/soroban-indexer/pkg/poller"
+)
+
+// RetentionReporter reports whether live polling is stuck behind the RPC retention window
+type RetentionReporter interface {
+	RetentionAlert() *poller.RetentionAlert
+}
+
+// RegisterHealthRoutes adds the health endpoint to mux:
+//
+//	GET /health   "OK", or 503 with the retention alert while the cursor is behind
+//	              the oldest ledger the RPC retains
+func RegisterHealthRoutes(mux *http.ServeMux, reporter RetentionReporter) {
+	mux.HandleFunc("GET /health", func(w http.ResponseWriter, r *http.Request) {
+		if alert := reporter.RetentionAlert(); alert != nil {
+			w.Header().Set("Content-Type", "application/json")
+			w.WriteHeader(http.StatusServiceUnavailable)
+			json.NewEncoder(w).Encode(alert)
+			return
+		}
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("OK"))
+	})
+}
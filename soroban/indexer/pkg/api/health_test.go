@@ -0,0 +1,45 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blockroma/soroban-indexer/pkg/poller"
+)
+
+type fakeReporter struct {
+	alert *poller.RetentionAlert
+}
+
+func (f *fakeReporter) RetentionAlert() *poller.RetentionAlert {
+	return f.alert
+}
+
+func TestHealth(t *testing.T) {
+	reporter := &fakeReporter{}
+	mux := http.NewServeMux()
+	RegisterHealthRoutes(mux, reporter)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	resp, err := http.Get(server.URL + "/health")
+	require.NoError(t, err)
+	resp.Body.Close()
+	assert.Equal(t, http.StatusOK, resp.StatusCode)
+
+	reporter.alert = &poller.RetentionAlert{Cursor: "live", NextLedger: 101, OldestLedger: 110}
+	resp, err = http.Get(server.URL + "/health")
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	assert.Equal(t, http.StatusServiceUnavailable, resp.StatusCode)
+
+	var alert poller.RetentionAlert
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&alert))
+	assert.Equal(t, uint32(101), alert.NextLedger)
+	assert.Equal(t, uint32(110), alert.OldestLedger)
+}
@@ -0,0 +1,77 @@
+package api
+
+import (
+	"net/http"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// BalanceResponse is the JSON form of a point-in-time token balance
+type BalanceResponse struct {
+	ContractID string     `json:"contractId"`
+	Address    string     `json:"address"`
+	Balance    string     `json:"balance"`
+	Ledger     uint32     `json:"ledger,omitempty"`
+	Time       *time.Time `json:"time,omitempty"`
+}
+
+// ReconcileResponse lists the holders whose event-derived and stored balances disagree
+type ReconcileResponse struct {
+	ContractID string                        `json:"contractId"`
+	OK         bool                          `json:"ok"`
+	Mismatches []models.TokenBalanceMismatch `json:"mismatches"`
+}
+
+// RegisterTokenRoutes adds the token balance endpoints to mux:
+//
+//	GET /tokens/{id}/balances/{address}?ledger=<n>   balance at the end of ledger n
+//	GET /tokens/{id}/balances/{address}?time=<t>     balance at RFC 3339 time t (default: now)
+//	GET /tokens/{id}/reconcile                       holders whose event sum and stored balance differ
+func RegisterTokenRoutes(mux *http.ServeMux, db *gorm.DB) {
+	mux.HandleFunc("GET /tokens/{id}/balances/{address}", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+		resp := BalanceResponse{ContractID: r.PathValue("id"), Address: r.PathValue("address")}
+
+		ledger, err := uint32Param(query.Get("ledger"))
+		if err != nil {
+			http.Error(w, "ledger must be a ledger sequence", http.StatusBadRequest)
+			return
+		}
+
+		if ledger != 0 {
+			resp.Ledger = ledger
+			resp.Balance, err = models.GetTokenBalanceAt(db, resp.ContractID, resp.Address, ledger)
+		} else {
+			at := time.Now().UTC()
+			if value := query.Get("time"); value != "" {
+				if at, err = time.Parse(time.RFC3339, value); err != nil {
+					http.Error(w, "time must be an RFC 3339 timestamp", http.StatusBadRequest)
+					return
+				}
+			}
+			resp.Time = &at
+			resp.Balance, err = models.GetTokenBalanceAtTime(db, resp.ContractID, resp.Address, at)
+		}
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, resp)
+	})
+
+	mux.HandleFunc("GET /tokens/{id}/reconcile", func(w http.ResponseWriter, r *http.Request) {
+		contractID := r.PathValue("id")
+		mismatches, err := models.ReconcileTokenBalances(db, contractID)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		if mismatches == nil {
+			mismatches = []models.TokenBalanceMismatch{}
+		}
+		writeJSON(w, ReconcileResponse{ContractID: contractID, OK: len(mismatches) == 0, Mismatches: mismatches})
+	})
+}
@@ -0,0 +1,57 @@
+package api
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+)
+
+func TestTokenBalances(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&models.TokenBalanceChange{}, &models.TokenBalance{}))
+
+	closedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	stored := &models.TokenBalanceChange{
+		ID: "s1", ContractID: "CA", Address: "GA", Ledger: 100, ClosedAt: closedAt,
+		Source: models.BalanceSourceStorage, Balance: &util.Int128{},
+	}
+	stored.Balance.SetInt64(500)
+	minted := &models.TokenBalanceChange{ID: "e1", ContractID: "CA", Address: "GA", Ledger: 100, ClosedAt: closedAt, Source: models.BalanceSourceEvent}
+	minted.Delta.SetInt64(400)
+	_, err = models.InsertTokenBalanceChanges(db, []*models.TokenBalanceChange{stored, minted})
+	require.NoError(t, err)
+	current := &models.TokenBalance{ContractID: "CA", Address: "GA"}
+	current.Balance.SetInt64(500)
+	require.NoError(t, models.UpsertTokenBalance(db, current))
+
+	mux := http.NewServeMux()
+	RegisterTokenRoutes(mux, db)
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	var balance BalanceResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/tokens/CA/balances/GA?ledger=99", &balance))
+	assert.Equal(t, "0", balance.Balance)
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/tokens/CA/balances/GA?ledger=100", &balance))
+	assert.Equal(t, "500", balance.Balance)
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/tokens/CA/balances/GA?time=2024-01-01T00:00:00Z", &balance))
+	assert.Equal(t, "500", balance.Balance)
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/tokens/CA/balances/GA", &balance))
+	assert.Equal(t, "500", balance.Balance)
+	assert.Equal(t, http.StatusBadRequest, getJSON(t, server.URL+"/tokens/CA/balances/GA?time=yesterday", &balance))
+
+	var reconcile ReconcileResponse
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/tokens/CA/reconcile", &reconcile))
+	assert.False(t, reconcile.OK)
+	assert.Equal(t, []models.TokenBalanceMismatch{{Address: "GA", EventBalance: "400", StorageBalance: "500"}}, reconcile.Mismatches)
+}
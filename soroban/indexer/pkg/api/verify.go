@@ -0,0 +1,62 @@
+package api
+
+import (
+	"net/http"
+	"strconv"
+
+	"github.com/blockroma/soroban-indexer/pkg/verify"
+)
+
+// RegisterVerifyRoutes adds the verification endpoints to mux:
+//
+//	GET  /verify   report of the most recent verification run
+//	POST /verify   run a verification now; optional query parameters:
+//	               startLedger, endLedger, sample, repair=true
+func RegisterVerifyRoutes(mux *http.ServeMux, v *verify.Verifier) {
+	mux.HandleFunc("GET /verify", func(w http.ResponseWriter, r *http.Request) {
+		report := v.LastReport()
+		if report == nil {
+			http.Error(w, "no verification has run yet", http.StatusNotFound)
+			return
+		}
+		writeJSON(w, report)
+	})
+
+	mux.HandleFunc("POST /verify", func(w http.ResponseWriter, r *http.Request) {
+		query := r.URL.Query()
+
+		var config verify.Config
+		var err error
+		if config.StartLedger, err = uint32Param(query.Get("startLedger")); err != nil {
+			http.Error(w, "startLedger must be a ledger sequence", http.StatusBadRequest)
+			return
+		}
+		if config.EndLedger, err = uint32Param(query.Get("endLedger")); err != nil {
+			http.Error(w, "endLedger must be a ledger sequence", http.StatusBadRequest)
+			return
+		}
+		if sample := query.Get("sample"); sample != "" {
+			if config.SampleSize, err = strconv.Atoi(sample); err != nil {
+				http.Error(w, "sample must be a number", http.StatusBadRequest)
+				return
+			}
+		}
+		config.Repair = query.Get("repair") == "true"
+
+		report, err := v.Verify(r.Context(), config)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, report)
+	})
+}
+
+// uint32Param parses an optional numeric query parameter (empty = 0)
+func uint32Param(value string) (uint32, error) {
+	if value == "" {
+		return 0, nil
+	}
+	n, err := strconv.ParseUint(value, 10, 32)
+	return uint32(n), err
+}
@@ -0,0 +1,54 @@
+package api
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/verify"
+)
+
+func TestVerifyRoutes(t *testing.T) {
+	db, _ := setupTestServer(t)
+	require.NoError(t, db.AutoMigrate(&models.Event{}, &models.Transaction{}, &models.Ledger{}, &models.LedgerCoverage{}))
+	require.NoError(t, models.RecordLedgerCoverage(db, models.LiveCursor, 100, 110))
+	require.NoError(t, models.RecordLedgerCoverage(db, models.LiveCursor, 115, 120))
+
+	// An unreachable RPC only marks the sampled ledgers as unchecked
+	rpc := httptest.NewServer(http.NotFoundHandler())
+	rpc.Close()
+
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	mux := http.NewServeMux()
+	RegisterVerifyRoutes(mux, verify.New(db, client.NewClient(rpc.URL), logger))
+	server := httptest.NewServer(mux)
+	t.Cleanup(server.Close)
+
+	var report verify.Report
+	assert.Equal(t, http.StatusNotFound, getJSON(t, server.URL+"/verify", &report))
+
+	resp, err := http.Post(server.URL+"/verify?sample=1&repair=true", "", nil)
+	require.NoError(t, err)
+	defer resp.Body.Close()
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.NoError(t, json.NewDecoder(resp.Body).Decode(&report))
+	assert.Equal(t, []models.LedgerRange{{Start: 111, End: 114}}, report.CoverageGaps)
+	assert.Equal(t, []string{"repair-111-114"}, report.Repairs)
+
+	var last verify.Report
+	require.Equal(t, http.StatusOK, getJSON(t, server.URL+"/verify", &last))
+	assert.Equal(t, report.CoverageGaps, last.CoverageGaps)
+
+	bad, err := http.Post(server.URL+"/verify?startLedger=abc", "", nil)
+	require.NoError(t, err)
+	bad.Body.Close()
+	assert.Equal(t, http.StatusBadRequest, bad.StatusCode)
+}
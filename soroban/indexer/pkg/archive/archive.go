@@ -0,0 +1,217 @@
+// Package archive keeps the raw RPC payloads behind every stored row, so derived tables can be
+// rebuilt after a parser fix without going back to the RPC
+package archive
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/klauspost/compress/zstd"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+)
+
+// ledgersPerIndexDir bounds the number of index files per directory
+const ledgersPerIndexDir = 10000
+
+// Record holds raw RPC payloads of a single ledger
+// A ledger ingested from LedgerCloseMeta has it set; in events mode the transactions and events
+// of a ledger usually arrive in separate records
+type Record struct {
+	Ledger            uint32               `json:"ledger"`
+	NetworkPassphrase string               `json:"networkPassphrase"`
+	LedgerCloseMeta   string               `json:"ledgerCloseMeta,omitempty"` // Base64 XDR
+	Transactions      []client.Transaction `json:"transactions,omitempty"`
+	Events            []client.Event       `json:"events,omitempty"`
+}
+
+// Sink receives the records of every ingested batch
+type Sink interface {
+	Put(record *Record) error
+}
+
+// Store is a content-addressed filesystem archive:
+//
+//	<root>/objects/<2 hex>/<sha256>.json.zst   zstd compressed JSON record
+//	<root>/index/<ledger / 10000>/<ledger>    hashes of the ledger's records, one per line
+//
+// Records are addressed by the hash of their content, so storing the same payload twice (e.g.
+// when a batch is retried) adds nothing
+type Store struct {
+	root    string
+	encoder *zstd.Encoder
+	decoder *zstd.Decoder
+
+	mu sync.Mutex // Serializes index updates
+}
+
+// Open opens (creating if needed) the archive at root
+func Open(root string) (*Store, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, fmt.Errorf("create archive: %w", err)
+	}
+	encoder, err := zstd.NewWriter(nil)
+	if err != nil {
+		return nil, err
+	}
+	decoder, err := zstd.NewReader(nil)
+	if err != nil {
+		return nil, err
+	}
+	return &Store{root: root, encoder: encoder, decoder: decoder}, nil
+}
+
+// Put stores a record and adds it to its ledger's index
+func (s *Store) Put(record *Record) error {
+	data, err := json.Marshal(record)
+	if err != nil {
+		return fmt.Errorf("encode record: %w", err)
+	}
+	sum := sha256.Sum256(data)
+	hash := hex.EncodeToString(sum[:])
+
+	path := s.objectPath(hash)
+	if _, err := os.Stat(path); errors.Is(err, fs.ErrNotExist) {
+		if err := writeFile(path, s.encoder.EncodeAll(data, nil)); err != nil {
+			return fmt.Errorf("write record for ledger %d: %w", record.Ledger, err)
+		}
+	} else if err != nil {
+		return err
+	}
+
+	return s.addToIndex(record.Ledger, hash)
+}
+
+// Get returns every record stored for ledger, in the order they were first stored
+func (s *Store) Get(ledger uint32) ([]*Record, error) {
+	hashes, err := s.indexEntries(ledger)
+	if err != nil {
+		return nil, err
+	}
+
+	records := make([]*Record, 0, len(hashes))
+	for _, hash := range hashes {
+		compressed, err := os.ReadFile(s.objectPath(hash))
+		if err != nil {
+			return nil, fmt.Errorf("read record %s: %w", hash, err)
+		}
+		data, err := s.decoder.DecodeAll(compressed, nil)
+		if err != nil {
+			return nil, fmt.Errorf("decompress record %s: %w", hash, err)
+		}
+		var record Record
+		if err := json.Unmarshal(data, &record); err != nil {
+			return nil, fmt.Errorf("decode record %s: %w", hash, err)
+		}
+		records = append(records, &record)
+	}
+	return records, nil
+}
+
+// Ledgers returns every archived ledger in [start, end] in ascending order (end 0 = no limit)
+func (s *Store) Ledgers(start, end uint32) ([]uint32, error) {
+	dirs, err := os.ReadDir(filepath.Join(s.root, "index"))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	var ledgers []uint32
+	for _, dir := range dirs {
+		bucket, err := strconv.ParseUint(dir.Name(), 10, 32)
+		if err != nil || !dir.IsDir() {
+			continue
+		}
+		// Skip buckets entirely outside the range
+		first := uint32(bucket) * ledgersPerIndexDir
+		if first+ledgersPerIndexDir-1 < start || (end != 0 && first > end) {
+			continue
+		}
+
+		entries, err := os.ReadDir(filepath.Join(s.root, "index", dir.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range entries {
+			ledger, err := strconv.ParseUint(entry.Name(), 10, 32)
+			if err != nil {
+				continue
+			}
+			if uint32(ledger) >= start && (end == 0 || uint32(ledger) <= end) {
+				ledgers = append(ledgers, uint32(ledger))
+			}
+		}
+	}
+
+	sort.Slice(ledgers, func(i, j int) bool { return ledgers[i] < ledgers[j] })
+	return ledgers, nil
+}
+
+func (s *Store) addToIndex(ledger uint32, hash string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	hashes, err := s.indexEntries(ledger)
+	if err != nil {
+		return err
+	}
+	for _, existing := range hashes {
+		if existing == hash {
+			return nil
+		}
+	}
+
+	return writeFile(s.indexPath(ledger), []byte(strings.Join(append(hashes, hash), "\n")+"\n"))
+}
+
+func (s *Store) indexEntries(ledger uint32) ([]string, error) {
+	data, err := os.ReadFile(s.indexPath(ledger))
+	if errors.Is(err, fs.ErrNotExist) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read index for ledger %d: %w", ledger, err)
+	}
+	return strings.Fields(string(data)), nil
+}
+
+func (s *Store) objectPath(hash string) string {
+	return filepath.Join(s.root, "objects", hash[:2], hash+".json.zst")
+}
+
+func (s *Store) indexPath(ledger uint32) string {
+	return filepath.Join(s.root, "index", strconv.FormatUint(uint64(ledger/ledgersPerIndexDir), 10), strconv.FormatUint(uint64(ledger), 10))
+}
+
+// writeFile writes data through a temporary file so readers never see a partial file
+func writeFile(path string, data []byte) error {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return err
+	}
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		os.Remove(tmp.Name())
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		os.Remove(tmp.Name())
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
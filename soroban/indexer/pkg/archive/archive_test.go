@@ -0,0 +1,74 @@
+package archive
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+)
+
+func countObjects(t *testing.T, root string) int {
+	count := 0
+	err := filepath.Walk(filepath.Join(root, "objects"), func(path string, info os.FileInfo, err error) error {
+		if err == nil && !info.IsDir() {
+			count++
+		}
+		return err
+	})
+	require.NoError(t, err)
+	return count
+}
+
+func TestStore_PutGet(t *testing.T) {
+	root := t.TempDir()
+	store, err := Open(root)
+	require.NoError(t, err)
+
+	txs := &Record{Ledger: 100, NetworkPassphrase: "test", Transactions: []client.Transaction{{Hash: "abc", Ledger: 100, EnvelopeXdr: "AAAA"}}}
+	events := &Record{Ledger: 100, NetworkPassphrase: "test", Events: []client.Event{{ID: "e1", Ledger: 100, TxHash: "abc"}}}
+	require.NoError(t, store.Put(txs))
+	require.NoError(t, store.Put(events))
+
+	// Storing the same payload again (e.g. a retried batch) adds nothing
+	require.NoError(t, store.Put(txs))
+	assert.Equal(t, 2, countObjects(t, root))
+
+	records, err := store.Get(100)
+	require.NoError(t, err)
+	require.Len(t, records, 2)
+	assert.Equal(t, txs, records[0])
+	assert.Equal(t, events, records[1])
+
+	records, err = store.Get(101)
+	require.NoError(t, err)
+	assert.Empty(t, records)
+}
+
+func TestStore_Ledgers(t *testing.T) {
+	store, err := Open(t.TempDir())
+	require.NoError(t, err)
+
+	ledgers, err := store.Ledgers(0, 0)
+	require.NoError(t, err)
+	assert.Empty(t, ledgers)
+
+	for _, ledger := range []uint32{25000, 9999, 10000, 10001, 5} {
+		require.NoError(t, store.Put(&Record{Ledger: ledger}))
+	}
+
+	ledgers, err = store.Ledgers(0, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{5, 9999, 10000, 10001, 25000}, ledgers)
+
+	ledgers, err = store.Ledgers(9999, 10000)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{9999, 10000}, ledgers)
+
+	ledgers, err = store.Ledgers(10001, 0)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{10001, 25000}, ledgers)
+}
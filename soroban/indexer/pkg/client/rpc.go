@@ -137,8 +137,9 @@ type Event struct {
 }
 
 // GetEventsRequest parameters for getEvents
+// StartLedger and Pagination.Cursor are mutually exclusive - the RPC rejects requests that set both
 type GetEventsRequest struct {
-	StartLedger uint32                 `json:"startLedger"`
+	StartLedger uint32                 `json:"startLedger,omitempty"`
 	Filters     []EventFilter          `json:"filters,omitempty"`
 	Pagination  *EventPaginationParams `json:"pagination,omitempty"`
 }
@@ -155,9 +156,11 @@ type EventPaginationParams struct {
 }
 
 // GetEventsResponse response from getEvents
+// Cursor points just past the last scanned event and is returned even when Events is empty
 type GetEventsResponse struct {
 	Events       []Event `json:"events"`
 	LatestLedger uint32  `json:"latestLedger"`
+	Cursor       string  `json:"cursor,omitempty"`
 }
 
 // GetEvents fetches events from the RPC
@@ -171,6 +174,48 @@ func (c *Client) GetEvents(ctx context.Context, req GetEventsRequest) (*GetEvent
 	return &result, nil
 }
 
+// LedgerInfo represents a single ledger returned by getLedgers
+type LedgerInfo struct {
+	Hash            string `json:"hash"`
+	Sequence        uint32 `json:"sequence"`
+	LedgerCloseTime int64  `json:"ledgerCloseTime,string"`
+	HeaderXdr       string `json:"headerXdr"`   // base64 LedgerHeaderHistoryEntry
+	MetadataXdr     string `json:"metadataXdr"` // base64 LedgerCloseMeta
+}
+
+// GetLedgersRequest parameters for getLedgers
+// StartLedger and Pagination.Cursor are mutually exclusive
+type GetLedgersRequest struct {
+	StartLedger uint32                  `json:"startLedger,omitempty"`
+	Pagination  *LedgerPaginationParams `json:"pagination,omitempty"`
+}
+
+type LedgerPaginationParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  uint   `json:"limit,omitempty"`
+}
+
+// GetLedgersResponse response from getLedgers
+type GetLedgersResponse struct {
+	Ledgers               []LedgerInfo `json:"ledgers"`
+	LatestLedger          uint32       `json:"latestLedger"`
+	LatestLedgerCloseTime int64        `json:"latestLedgerCloseTime"`
+	OldestLedger          uint32       `json:"oldestLedger"`
+	OldestLedgerCloseTime int64        `json:"oldestLedgerCloseTime"`
+	Cursor                string       `json:"cursor,omitempty"`
+}
+
+// GetLedgers fetches ledgers with their full LedgerCloseMeta
+func (c *Client) GetLedgers(ctx context.Context, req GetLedgersRequest) (*GetLedgersResponse, error) {
+	var result GetLedgersResponse
+
+	if err := c.call(ctx, "getLedgers", req, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // Transaction represents a transaction from RPC
 type Transaction struct {
 	Hash             string `json:"hash"`
@@ -199,6 +244,76 @@ func (c *Client) GetTransaction(ctx context.Context, hash string) (*Transaction,
 	return &result, nil
 }
 
+// TransactionInfo represents a single transaction returned by getTransactions
+type TransactionInfo struct {
+	Status           string `json:"status"`
+	TxHash           string `json:"txHash"`
+	ApplicationOrder int32  `json:"applicationOrder"`
+	FeeBump          bool   `json:"feeBump"`
+	EnvelopeXdr      string `json:"envelopeXdr"`
+	ResultXdr        string `json:"resultXdr"`
+	ResultMetaXdr    string `json:"resultMetaXdr"`
+	Ledger           uint32 `json:"ledger"`
+	CreatedAt        int64  `json:"createdAt"` // Ledger close time (unix seconds)
+}
+
+// Transaction converts the getTransactions entry to the getTransaction shape
+func (t TransactionInfo) Transaction() Transaction {
+	return Transaction{
+		Hash:             t.TxHash,
+		Status:           t.Status,
+		Ledger:           t.Ledger,
+		ApplicationOrder: t.ApplicationOrder,
+		LedgerCloseTime:  t.CreatedAt,
+		EnvelopeXdr:      t.EnvelopeXdr,
+		ResultXdr:        t.ResultXdr,
+		ResultMetaXdr:    t.ResultMetaXdr,
+	}
+}
+
+// GetTransactionsRequest parameters for getTransactions
+// StartLedger and Pagination.Cursor are mutually exclusive
+type GetTransactionsRequest struct {
+	StartLedger uint32                       `json:"startLedger,omitempty"`
+	Pagination  *TransactionPaginationParams `json:"pagination,omitempty"`
+}
+
+type TransactionPaginationParams struct {
+	Cursor string `json:"cursor,omitempty"`
+	Limit  uint   `json:"limit,omitempty"`
+}
+
+// GetTransactionsResponse response from getTransactions
+type GetTransactionsResponse struct {
+	Transactions               []TransactionInfo `json:"transactions"`
+	LatestLedger               uint32            `json:"latestLedger"`
+	LatestLedgerCloseTimestamp int64             `json:"latestLedgerCloseTimestamp"`
+	OldestLedger               uint32            `json:"oldestLedger"`
+	OldestLedgerCloseTimestamp int64             `json:"oldestLedgerCloseTimestamp"`
+	Cursor                     string            `json:"cursor,omitempty"`
+}
+
+// GetTransactions fetches every transaction (successful and failed) in ledger order
+// Pass startLedger for the first page and the returned cursor (with startLedger 0) for the next ones
+func (c *Client) GetTransactions(ctx context.Context, startLedger uint32, cursor string, limit uint) (*GetTransactionsResponse, error) {
+	req := GetTransactionsRequest{
+		Pagination: &TransactionPaginationParams{
+			Cursor: cursor,
+			Limit:  limit,
+		},
+	}
+	if cursor == "" {
+		req.StartLedger = startLedger
+	}
+
+	var result GetTransactionsResponse
+	if err := c.call(ctx, "getTransactions", req, &result); err != nil {
+		return nil, err
+	}
+
+	return &result, nil
+}
+
 // GetTransactions fetches transactions from events (helper method)
 func (c *Client) GetTransactionsFromEvents(ctx context.Context, events []Event) (map[string]*Transaction, error) {
 	txs := make(map[string]*Transaction)
@@ -237,13 +352,27 @@ func (c *Client) GetNetwork(ctx context.Context) (*NetworkInfo, error) {
 	return &result, nil
 }
 
-// Health checks RPC health
-func (c *Client) Health(ctx context.Context) error {
-	var result struct {
-		Status string `json:"status"`
-	}
+// HealthInfo is the getHealth response, including the ledger window the RPC retains
+type HealthInfo struct {
+	Status                string `json:"status"`
+	LatestLedger          uint32 `json:"latestLedger"`
+	OldestLedger          uint32 `json:"oldestLedger"`
+	LedgerRetentionWindow uint32 `json:"ledgerRetentionWindow"`
+}
 
+// GetHealth fetches the RPC health and retention window
+func (c *Client) GetHealth(ctx context.Context) (*HealthInfo, error) {
+	var result HealthInfo
 	if err := c.call(ctx, "getHealth", nil, &result); err != nil {
+		return nil, err
+	}
+	return &result, nil
+}
+
+// Health checks RPC health
+func (c *Client) Health(ctx context.Context) error {
+	result, err := c.GetHealth(ctx)
+	if err != nil {
 		return err
 	}
 
@@ -122,6 +122,169 @@ func TestClient_GetEvents(t *testing.T) {
 	}
 }
 
+func TestClient_GetEventsWithCursor(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int                    `json:"id"`
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		// startLedger must be omitted when paginating with a cursor
+		if _, ok := req.Params["startLedger"]; ok {
+			t.Errorf("startLedger should be omitted when cursor is set, got %v", req.Params["startLedger"])
+		}
+		pagination, _ := req.Params["pagination"].(map[string]interface{})
+		if pagination["cursor"] != "0000053021371011072-0000000001" {
+			t.Errorf("pagination.cursor = %v, want 0000053021371011072-0000000001", pagination["cursor"])
+		}
+
+		resp := jsonRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  json.RawMessage(`{"events": [], "latestLedger": 12346, "cursor": "0000053025666039808-4294967295"}`),
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	eventsResp, err := client.GetEvents(context.Background(), GetEventsRequest{
+		Pagination: &EventPaginationParams{
+			Cursor: "0000053021371011072-0000000001",
+			Limit:  100,
+		},
+	})
+	if err != nil {
+		t.Fatalf("GetEvents() error = %v", err)
+	}
+
+	if eventsResp.Cursor != "0000053025666039808-4294967295" {
+		t.Errorf("Cursor = %v, want 0000053025666039808-4294967295", eventsResp.Cursor)
+	}
+}
+
+func TestClient_GetLedgers(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int                    `json:"id"`
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		if req.Method != "getLedgers" {
+			t.Errorf("Expected method getLedgers, got %s", req.Method)
+		}
+		if req.Params["startLedger"] != float64(1000) {
+			t.Errorf("startLedger = %v, want 1000", req.Params["startLedger"])
+		}
+
+		resp := jsonRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: json.RawMessage(`{
+				"ledgers": [
+					{"hash": "abc", "sequence": 1000, "ledgerCloseTime": "1704067200", "headerXdr": "AAAA", "metadataXdr": "AAAB"}
+				],
+				"latestLedger": 1010,
+				"latestLedgerCloseTime": 1704067250,
+				"oldestLedger": 900,
+				"oldestLedgerCloseTime": 1704066700,
+				"cursor": "1000"
+			}`),
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	ledgersResp, err := client.GetLedgers(context.Background(), GetLedgersRequest{
+		StartLedger: 1000,
+		Pagination:  &LedgerPaginationParams{Limit: 1},
+	})
+	if err != nil {
+		t.Fatalf("GetLedgers() error = %v", err)
+	}
+
+	if len(ledgersResp.Ledgers) != 1 {
+		t.Fatalf("Expected 1 ledger, got %d", len(ledgersResp.Ledgers))
+	}
+	ledger := ledgersResp.Ledgers[0]
+	if ledger.Sequence != 1000 || ledger.LedgerCloseTime != 1704067200 || ledger.MetadataXdr != "AAAB" {
+		t.Errorf("Ledger = %+v, want sequence 1000 closed at 1704067200", ledger)
+	}
+	if ledgersResp.OldestLedger != 900 || ledgersResp.Cursor != "1000" {
+		t.Errorf("Response = %+v, want oldestLedger 900 and cursor 1000", ledgersResp)
+	}
+}
+
+func TestClient_GetTransactions(t *testing.T) {
+	var params []map[string]interface{}
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int                    `json:"id"`
+			Method string                 `json:"method"`
+			Params map[string]interface{} `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+		if req.Method != "getTransactions" {
+			t.Errorf("Expected method getTransactions, got %s", req.Method)
+		}
+		params = append(params, req.Params)
+
+		resp := jsonRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result: json.RawMessage(`{
+				"transactions": [
+					{"status": "FAILED", "txHash": "abc123", "applicationOrder": 2, "feeBump": false,
+					 "envelopeXdr": "AAAA", "resultXdr": "AAAB", "resultMetaXdr": "AAAC", "ledger": 1000, "createdAt": 1704067200}
+				],
+				"latestLedger": 1010,
+				"oldestLedger": 900,
+				"cursor": "4294967296"
+			}`),
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	txResp, err := client.GetTransactions(context.Background(), 1000, "", 50)
+	if err != nil {
+		t.Fatalf("GetTransactions() error = %v", err)
+	}
+	if _, err := client.GetTransactions(context.Background(), 1000, txResp.Cursor, 50); err != nil {
+		t.Fatalf("GetTransactions() with cursor error = %v", err)
+	}
+
+	if params[0]["startLedger"] != float64(1000) {
+		t.Errorf("First request startLedger = %v, want 1000", params[0]["startLedger"])
+	}
+	// startLedger must be omitted when paginating with a cursor
+	if _, ok := params[1]["startLedger"]; ok {
+		t.Errorf("startLedger should be omitted when cursor is set, got %v", params[1]["startLedger"])
+	}
+
+	if len(txResp.Transactions) != 1 {
+		t.Fatalf("Expected 1 transaction, got %d", len(txResp.Transactions))
+	}
+	tx := txResp.Transactions[0].Transaction()
+	if tx.Hash != "abc123" || tx.Status != "FAILED" || tx.Ledger != 1000 || tx.LedgerCloseTime != 1704067200 || tx.ApplicationOrder != 2 {
+		t.Errorf("Transaction = %+v, want failed abc123 in ledger 1000", tx)
+	}
+	if tx.ResultMetaXdr != "AAAC" {
+		t.Errorf("ResultMetaXdr = %s, want AAAC", tx.ResultMetaXdr)
+	}
+}
+
 func TestClient_GetTransaction(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req jsonRPCRequest
@@ -265,6 +428,36 @@ func TestClient_Health(t *testing.T) {
 	}
 }
 
+func TestClient_GetHealth(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req jsonRPCRequest
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Fatalf("Failed to decode request: %v", err)
+		}
+
+		if req.Method != "getHealth" {
+			t.Errorf("Method = %v, want getHealth", req.Method)
+		}
+
+		json.NewEncoder(w).Encode(jsonRPCResponse{
+			JSONRPC: "2.0",
+			ID:      req.ID,
+			Result:  json.RawMessage(`{"status": "healthy", "latestLedger": 50000, "oldestLedger": 32000, "ledgerRetentionWindow": 17280}`),
+		})
+	}))
+	defer server.Close()
+
+	client := NewClient(server.URL)
+	health, err := client.GetHealth(context.Background())
+	if err != nil {
+		t.Fatalf("GetHealth() error = %v", err)
+	}
+
+	if health.LatestLedger != 50000 || health.OldestLedger != 32000 || health.LedgerRetentionWindow != 17280 {
+		t.Errorf("GetHealth() = %+v", health)
+	}
+}
+
 func TestClient_GetLedgerEntries(t *testing.T) {
 	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		var req jsonRPCRequest
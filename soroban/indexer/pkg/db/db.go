@@ -88,8 +88,17 @@ func ConnectWithConfig(dsn string, poolConfig ConnectionPoolConfig) (*DB, error)
 		&models.TokenMetadata{},
 		&models.TokenOperation{},
 		&models.TokenBalance{},
+		&models.TokenBalanceChange{},
+		&models.TokenSupply{},
+		&models.TokenAllowance{},
+		&models.DiagnosticEvent{},
 		&models.ContractDataEntry{},
+		&models.ContractDataHistory{},
 		&models.ContractCode{},
+		&models.Ledger{},
+		&models.LedgerCoverage{},
+		&models.BackfillShard{},
+		&models.BackfillRetry{},
 	); err != nil {
 		return nil, fmt.Errorf("auto migrate: %w", err)
 	}
@@ -151,6 +160,134 @@ func (db *DB) PoolStats() (map[string]interface{}, error) {
 
 // runCustomMigrations handles custom database migrations that AutoMigrate can't handle
 func runCustomMigrations(db *gorm.DB) error {
+	if err := migrateNamedCursors(db); err != nil {
+		return fmt.Errorf("migrate named cursors: %w", err)
+	}
+	if err := migrateEventsLastModifiedLedger(db); err != nil {
+		return err
+	}
+	if err := migrateNumericBalances(db); err != nil {
+		return err
+	}
+	if err := migrateTransactionFees(db); err != nil {
+		return err
+	}
+	return migrateMuxedIDs(db)
+}
+
+// migrateTransactionFees widens transactions.fee and fee_charged from integer to bigint, which
+// Soroban fees (and fee bump fees) can overflow
+func migrateTransactionFees(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" || !db.Migrator().HasTable("transactions") {
+		return nil
+	}
+
+	var dataType string
+	if err := db.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_name = 'transactions' AND column_name = 'fee'`).Scan(&dataType).Error; err != nil {
+		return fmt.Errorf("inspect transactions.fee: %w", err)
+	}
+	if dataType != "integer" {
+		return nil
+	}
+
+	logrus.Info("Converting transactions.fee and fee_charged to bigint")
+
+	return db.Exec("ALTER TABLE transactions ALTER COLUMN fee TYPE bigint, ALTER COLUMN fee_charged TYPE bigint").Error
+}
+
+// migrateMuxedIDs converts the muxed account ID columns from bigint to numeric, so that IDs of 2^63
+// and above fit
+func migrateMuxedIDs(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" {
+		return nil
+	}
+
+	for table, column := range map[string]string{
+		"transactions": "muxed_account_id",
+		"operations":   "source_muxed_id",
+	} {
+		if !db.Migrator().HasTable(table) {
+			continue
+		}
+
+		var dataType string
+		if err := db.Raw(`SELECT data_type FROM information_schema.columns
+			WHERE table_name = ? AND column_name = ?`, table, column).Scan(&dataType).Error; err != nil {
+			return fmt.Errorf("inspect %s.%s: %w", table, column, err)
+		}
+		if dataType != "bigint" {
+			continue
+		}
+
+		logrus.Infof("Converting %s.%s to numeric", table, column)
+
+		if err := db.Exec(fmt.Sprintf("ALTER TABLE %s ALTER COLUMN %s TYPE numeric", table, column)).Error; err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// migrateNumericBalances converts token_balances.balance from text to NUMERIC
+// Rows whose balance is not an integer are dropped; the next write of their storage entry (or a
+// reindex of token_balances) restores them
+func migrateNumericBalances(db *gorm.DB) error {
+	if db.Dialector.Name() != "postgres" || !db.Migrator().HasTable("token_balances") {
+		return nil
+	}
+
+	var dataType string
+	if err := db.Raw(`SELECT data_type FROM information_schema.columns
+		WHERE table_name = 'token_balances' AND column_name = 'balance'`).Scan(&dataType).Error; err != nil {
+		return fmt.Errorf("inspect token_balances.balance: %w", err)
+	}
+	if dataType != "text" && dataType != "character varying" {
+		return nil
+	}
+
+	logrus.Info("Converting token_balances.balance to numeric")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Exec(`DELETE FROM token_balances WHERE balance IS NULL OR balance !~ '^-?[0-9]+$'`).Error; err != nil {
+			return err
+		}
+		return tx.Exec("ALTER TABLE token_balances ALTER COLUMN balance TYPE numeric USING balance::numeric").Error
+	})
+}
+
+// migrateNamedCursors converts the legacy single-row cursor table (keyed by id = 1) to cursors
+// keyed by name; the stored position becomes the live cursor
+func migrateNamedCursors(db *gorm.DB) error {
+	if !db.Migrator().HasTable("indexer_cursor") || db.Migrator().HasColumn(&models.Cursor{}, "name") {
+		return nil
+	}
+
+	logrus.Info("Migrating indexer_cursor to named cursors")
+
+	return db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Migrator().RenameTable("indexer_cursor", "indexer_cursor_legacy"); err != nil {
+			return err
+		}
+		if err := tx.AutoMigrate(&models.Cursor{}); err != nil {
+			return err
+		}
+		// Tables created before paging tokens were stored have no paging_token column
+		pagingToken := "''"
+		if tx.Migrator().HasColumn("indexer_cursor_legacy", "paging_token") {
+			pagingToken = "paging_token"
+		}
+		if err := tx.Exec(`INSERT INTO indexer_cursor (name, last_ledger, paging_token, updated_at)
+			SELECT ?, last_ledger, `+pagingToken+`, updated_at FROM indexer_cursor_legacy WHERE id = 1`,
+			models.LiveCursor).Error; err != nil {
+			return err
+		}
+		return tx.Migrator().DropTable("indexer_cursor_legacy")
+	})
+}
+
+// migrateEventsLastModifiedLedger backfills the last_modified_ledger_seq column on existing events
+func migrateEventsLastModifiedLedger(db *gorm.DB) error {
 	// Check if events table exists
 	if !db.Migrator().HasTable("events") {
 		// Table doesn't exist yet, AutoMigrate will create it with all columns
@@ -263,6 +400,10 @@ func createIndexes(db *gorm.DB) error {
 			name:  "idx_transactions_ledger",
 			query: "CREATE INDEX IF NOT EXISTS idx_transactions_ledger ON transactions (ledger)",
 		},
+		{
+			name:  "idx_transactions_result_code",
+			query: "CREATE INDEX IF NOT EXISTS idx_transactions_result_code ON transactions (result_code)",
+		},
 		{
 			name:  "idx_operations_tx_hash",
 			query: "CREATE INDEX IF NOT EXISTS idx_operations_tx_hash ON operations (tx_hash)",
@@ -283,6 +424,14 @@ func createIndexes(db *gorm.DB) error {
 			name:  "idx_token_operations_to",
 			query: "CREATE INDEX IF NOT EXISTS idx_token_operations_to ON token_operations (\"to\")",
 		},
+		{
+			name:  "idx_diagnostic_events_topic_gin",
+			query: "CREATE INDEX IF NOT EXISTS idx_diagnostic_events_topic_gin ON diagnostic_events USING gin (topic)",
+		},
+		{
+			name:  "idx_token_balances_holders",
+			query: "CREATE INDEX IF NOT EXISTS idx_token_balances_holders ON token_balances (contract_id, balance DESC)",
+		},
 	}
 
 	for _, idx := range indexes {
@@ -301,7 +301,7 @@ func TestDB_WithTransaction(t *testing.T) {
 	// Test successful transaction
 	err = db.WithTransaction(func(tx *gorm.DB) error {
 		return tx.Create(&models.Cursor{
-			ID:         1,
+			Name:       models.LiveCursor,
 			LastLedger: 12345,
 		}).Error
 	})
@@ -330,3 +330,70 @@ func TestDB_Close(t *testing.T) {
 		t.Errorf("Close() error = %v", err)
 	}
 }
+
+// TestRunCustomMigrations_LegacyCursor tests that the single-row cursor becomes the live named cursor
+func TestRunCustomMigrations_LegacyCursor(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Baseline schema: no paging token column
+	if err := db.Exec(`CREATE TABLE indexer_cursor (
+		id integer PRIMARY KEY,
+		last_ledger integer NOT NULL,
+		updated_at datetime
+	)`).Error; err != nil {
+		t.Fatalf("Failed to create legacy cursor table: %v", err)
+	}
+	if err := db.Exec("INSERT INTO indexer_cursor (id, last_ledger) VALUES (1, 12345)").Error; err != nil {
+		t.Fatalf("Failed to insert legacy cursor: %v", err)
+	}
+
+	if err := runCustomMigrations(db); err != nil {
+		t.Fatalf("runCustomMigrations() error = %v", err)
+	}
+
+	state, err := models.GetCursorState(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 12345 || state.PagingToken != "" {
+		t.Errorf("Live cursor = %+v, want ledger 12345 without token", state)
+	}
+	if db.Migrator().HasTable("indexer_cursor_legacy") {
+		t.Error("Legacy cursor table should be dropped")
+	}
+
+	// Running again is a no-op
+	if err := runCustomMigrations(db); err != nil {
+		t.Fatalf("runCustomMigrations() second run error = %v", err)
+	}
+}
+
+// TestRunCustomMigrations_LegacyCursorWithToken tests that a paging token stored by the
+// single-row cursor is kept
+func TestRunCustomMigrations_LegacyCursorWithToken(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := db.Exec(`CREATE TABLE indexer_cursor (
+		id integer PRIMARY KEY,
+		last_ledger integer NOT NULL,
+		paging_token text,
+		updated_at datetime
+	)`).Error; err != nil {
+		t.Fatalf("Failed to create legacy cursor table: %v", err)
+	}
+	if err := db.Exec("INSERT INTO indexer_cursor (id, last_ledger, paging_token) VALUES (1, 12345, 'token')").Error; err != nil {
+		t.Fatalf("Failed to insert legacy cursor: %v", err)
+	}
+
+	if err := runCustomMigrations(db); err != nil {
+		t.Fatalf("runCustomMigrations() error = %v", err)
+	}
+
+	state, err := models.GetCursorState(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 12345 || state.PagingToken != "token" {
+		t.Errorf("Live cursor = %+v, want ledger 12345 with token", state)
+	}
+}
@@ -0,0 +1,140 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Backfill shard statuses
+const (
+	ShardStatusPending   = "pending"
+	ShardStatusRunning   = "running"
+	ShardStatusCompleted = "completed"
+)
+
+// BackfillShard is one contiguous ledger range of a backfill job together with its checkpoint
+// A restarted job resumes each shard from Checkpoint + 1
+type BackfillShard struct {
+	ID          string    `gorm:"column:id;primaryKey"` // "<job>:<start>-<end>"
+	Job         string    `gorm:"column:job;index;not null"`
+	StartLedger uint32    `gorm:"column:start_ledger;not null"`
+	EndLedger   uint32    `gorm:"column:end_ledger;not null"`
+	Checkpoint  uint32    `gorm:"column:checkpoint;not null"` // Last completed ledger (StartLedger - 1 = nothing done)
+	Status      string    `gorm:"column:status;not null"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+}
+
+// TableName returns the table name for BackfillShard
+func (BackfillShard) TableName() string {
+	return "backfill_shards"
+}
+
+// BackfillRetry is a failed backfill batch waiting to be retried
+type BackfillRetry struct {
+	ID          uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	Job         string    `gorm:"column:job;index;not null"`
+	StartLedger uint32    `gorm:"column:start_ledger;not null"`
+	EndLedger   uint32    `gorm:"column:end_ledger;not null"`
+	Attempts    int       `gorm:"column:attempts;not null"`
+	LastError   string    `gorm:"column:last_error"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+}
+
+// TableName returns the table name for BackfillRetry
+func (BackfillRetry) TableName() string {
+	return "backfill_retries"
+}
+
+// CreateBackfillShards stores the shards of a new job, each starting with an empty checkpoint
+func CreateBackfillShards(db *gorm.DB, job string, shards []BackfillShard) error {
+	if len(shards) == 0 {
+		return nil
+	}
+
+	for i := range shards {
+		shards[i].Job = job
+		if shards[i].ID == "" {
+			shards[i].ID = fmt.Sprintf("%s:%d-%d", job, shards[i].StartLedger, shards[i].EndLedger)
+		}
+		if shards[i].Status == "" {
+			shards[i].Status = ShardStatusPending
+		}
+		if shards[i].Checkpoint == 0 {
+			shards[i].Checkpoint = shards[i].StartLedger - 1
+		}
+	}
+
+	// A shard another run already created keeps its checkpoint
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&shards).Error
+}
+
+// GetBackfillShards returns every shard of a job ordered by start ledger
+func GetBackfillShards(db *gorm.DB, job string) ([]BackfillShard, error) {
+	var shards []BackfillShard
+	err := db.Where("job = ?", job).Order("start_ledger ASC").Find(&shards).Error
+	return shards, err
+}
+
+// UpdateShardCheckpoint records the last completed ledger of a shard
+func UpdateShardCheckpoint(db *gorm.DB, id string, ledger uint32, status string) error {
+	return db.Model(&BackfillShard{}).Where("id = ?", id).Updates(map[string]interface{}{
+		"checkpoint": ledger,
+		"status":     status,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// EnqueueBackfillRetry records a failed batch so it can be retried instead of leaving a gap
+func EnqueueBackfillRetry(db *gorm.DB, job string, startLedger, endLedger uint32, cause error) error {
+	retry := &BackfillRetry{
+		Job:         job,
+		StartLedger: startLedger,
+		EndLedger:   endLedger,
+		Attempts:    1,
+	}
+	if cause != nil {
+		retry.LastError = cause.Error()
+	}
+	return db.Create(retry).Error
+}
+
+// GetBackfillRetries returns the queued retries of a job, oldest range first
+func GetBackfillRetries(db *gorm.DB, job string) ([]BackfillRetry, error) {
+	var retries []BackfillRetry
+	err := db.Where("job = ?", job).Order("start_ledger ASC").Find(&retries).Error
+	return retries, err
+}
+
+// RecordBackfillRetryFailure bumps the attempt count of a retry that failed again
+func RecordBackfillRetryFailure(db *gorm.DB, retry *BackfillRetry, cause error) error {
+	retry.Attempts++
+	retry.LastError = cause.Error()
+	return db.Model(retry).Updates(map[string]interface{}{
+		"attempts":   retry.Attempts,
+		"last_error": retry.LastError,
+		"updated_at": time.Now(),
+	}).Error
+}
+
+// DeleteBackfillRetry removes a retry once its batch has been processed
+func DeleteBackfillRetry(db *gorm.DB, id uint) error {
+	return db.Delete(&BackfillRetry{}, id).Error
+}
+
+// GetPendingBackfillJobs returns the jobs whose name starts with prefix that still have
+// unfinished shards or queued retries
+func GetPendingBackfillJobs(db *gorm.DB, prefix string) ([]string, error) {
+	var jobs []string
+	err := db.Raw(`
+		SELECT job FROM backfill_shards WHERE job LIKE ? AND status <> ?
+		UNION
+		SELECT job FROM backfill_retries WHERE job LIKE ?
+		ORDER BY job
+	`, prefix+"%", ShardStatusCompleted, prefix+"%").Scan(&jobs).Error
+	return jobs, err
+}
@@ -0,0 +1,97 @@
+package models
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupBackfillTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&BackfillShard{}, &BackfillRetry{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestBackfillTableNames(t *testing.T) {
+	assert.Equal(t, "backfill_shards", BackfillShard{}.TableName())
+	assert.Equal(t, "backfill_retries", BackfillRetry{}.TableName())
+}
+
+func TestCreateBackfillShards(t *testing.T) {
+	db := setupBackfillTestDB(t)
+
+	require.NoError(t, CreateBackfillShards(db, "job", []BackfillShard{
+		{StartLedger: 106, EndLedger: 110},
+		{StartLedger: 101, EndLedger: 105},
+	}))
+	require.NoError(t, UpdateShardCheckpoint(db, "job:101-105", 103, ShardStatusRunning))
+
+	// Creating the same shards again keeps their progress
+	require.NoError(t, CreateBackfillShards(db, "job", []BackfillShard{
+		{StartLedger: 101, EndLedger: 105},
+	}))
+
+	shards, err := GetBackfillShards(db, "job")
+	require.NoError(t, err)
+	require.Len(t, shards, 2)
+
+	assert.Equal(t, "job:101-105", shards[0].ID)
+	assert.Equal(t, uint32(103), shards[0].Checkpoint)
+	assert.Equal(t, ShardStatusRunning, shards[0].Status)
+
+	assert.Equal(t, "job:106-110", shards[1].ID)
+	assert.Equal(t, uint32(105), shards[1].Checkpoint, "new shard starts before its first ledger")
+	assert.Equal(t, ShardStatusPending, shards[1].Status)
+
+	other, err := GetBackfillShards(db, "other")
+	require.NoError(t, err)
+	assert.Empty(t, other)
+}
+
+func TestBackfillRetries(t *testing.T) {
+	db := setupBackfillTestDB(t)
+
+	require.NoError(t, EnqueueBackfillRetry(db, "job", 105, 106, errors.New("timeout")))
+	require.NoError(t, EnqueueBackfillRetry(db, "job", 101, 102, errors.New("timeout")))
+	require.NoError(t, EnqueueBackfillRetry(db, "other", 1, 2, nil))
+
+	retries, err := GetBackfillRetries(db, "job")
+	require.NoError(t, err)
+	require.Len(t, retries, 2)
+	assert.Equal(t, uint32(101), retries[0].StartLedger)
+	assert.Equal(t, 1, retries[0].Attempts)
+	assert.Equal(t, "timeout", retries[0].LastError)
+
+	require.NoError(t, RecordBackfillRetryFailure(db, &retries[0], errors.New("rate limited")))
+	require.NoError(t, DeleteBackfillRetry(db, retries[1].ID))
+
+	retries, err = GetBackfillRetries(db, "job")
+	require.NoError(t, err)
+	require.Len(t, retries, 1)
+	assert.Equal(t, 2, retries[0].Attempts)
+	assert.Equal(t, "rate limited", retries[0].LastError)
+}
+
+func TestGetPendingBackfillJobs(t *testing.T) {
+	db := setupBackfillTestDB(t)
+
+	require.NoError(t, CreateBackfillShards(db, "repair-100-110", []BackfillShard{{StartLedger: 100, EndLedger: 110}}))
+	require.NoError(t, CreateBackfillShards(db, "repair-200-210", []BackfillShard{{StartLedger: 200, EndLedger: 210}}))
+	require.NoError(t, UpdateShardCheckpoint(db, "repair-200-210:200-210", 210, ShardStatusCompleted))
+	require.NoError(t, CreateBackfillShards(db, "repair-300-310", []BackfillShard{{StartLedger: 300, EndLedger: 310}}))
+	require.NoError(t, UpdateShardCheckpoint(db, "repair-300-310:300-310", 310, ShardStatusCompleted))
+	require.NoError(t, EnqueueBackfillRetry(db, "repair-300-310", 305, 306, errors.New("timeout")))
+	require.NoError(t, CreateBackfillShards(db, "backfill-1-10", []BackfillShard{{StartLedger: 1, EndLedger: 10}}))
+
+	jobs, err := GetPendingBackfillJobs(db, "repair-")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"repair-100-110", "repair-300-310"}, jobs)
+}
@@ -72,12 +72,8 @@ func BatchUpsertTransactions(db *gorm.DB, transactions []*Transaction, config ..
 
 		batch := transactions[i:end]
 		if err := db.Clauses(clause.OnConflict{
-			Columns: []clause.Column{{Name: "id"}},
-			DoUpdates: clause.AssignmentColumns([]string{
-				"status", "ledger", "ledger_created_at", "application_order", "fee_bump",
-				"fee_bump_info", "fee", "fee_charged", "sequence", "source_account",
-				"muxed_account_id", "memo", "preconditions", "signatures", "updated_at",
-			}),
+			Columns:   []clause.Column{{Name: "id"}},
+			DoUpdates: clause.AssignmentColumns(transactionUpdateColumns),
 		}).Create(batch).Error; err != nil {
 			return fmt.Errorf("batch upsert transactions: %w", err)
 		}
@@ -107,8 +103,8 @@ func BatchUpsertOperations(db *gorm.DB, operations []*Operation, config ...Batch
 		if err := db.Clauses(clause.OnConflict{
 			Columns: []clause.Column{{Name: "id"}},
 			DoUpdates: clause.AssignmentColumns([]string{
-				"tx_hash", "operation_index", "operation_type",
-				"source_account", "operation_details", "updated_at",
+				"tx_hash", "operation_index", "operation_type", "source_account",
+				"source_muxed_id", "operation_details", "result_code", "updated_at",
 			}),
 		}).Create(batch).Error; err != nil {
 			return fmt.Errorf("batch upsert operations: %w", err)
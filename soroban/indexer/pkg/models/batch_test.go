@@ -128,8 +128,8 @@ func TestBatchUpsertEvents_CustomBatchSize(t *testing.T) {
 func TestBatchUpsertTransactions(t *testing.T) {
 	db := setupBatchTestDB(t)
 
-	fee := int32(100)
-	feeCharged := int32(100)
+	fee := int64(100)
+	feeCharged := int64(100)
 	ledger1 := uint32(100)
 	ledger2 := uint32(101)
 
@@ -390,7 +390,7 @@ func TestBatchFunctionsWithinTransaction(t *testing.T) {
 		}
 
 		// Test BatchUpsertTransactions
-		fee := int32(100)
+		fee := int64(100)
 		ledger := uint32(100)
 		transactions := []*Transaction{
 			{
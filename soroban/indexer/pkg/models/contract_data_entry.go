@@ -3,6 +3,7 @@ package models
 import (
 	"database/sql/driver"
 	"errors"
+	"strconv"
 	"time"
 
 	"gorm.io/gorm"
@@ -35,6 +36,13 @@ func (j *JSONB) Scan(value interface{}) error {
 	case string:
 		*j = []byte(v)
 		return nil
+	case int64:
+		// SQLite stores scalar JSON numbers with numeric affinity
+		*j = strconv.AppendInt(nil, v, 10)
+		return nil
+	case float64:
+		*j = strconv.AppendFloat(nil, v, 'f', -1, 64)
+		return nil
 	default:
 		return errors.New("failed to scan JSONB value: unexpected type")
 	}
@@ -63,6 +71,8 @@ type ContractDataEntry struct {
 	Flags               uint32    `gorm:"column:flags;type:int"`
 	ValXdr              string    `gorm:"column:val_xdr;type:text"`
 	Val                 JSONB     `gorm:"column:val;type:jsonb"`
+	RemovedLedger       *uint32   `gorm:"column:removed_ledger;type:int"` // Ledger the entry was deleted or evicted in (nil = live)
+	LastModifiedLedger  uint32    `gorm:"column:last_modified_ledger;type:int;not null;default:0"`
 	CreatedAt           time.Time `gorm:"column:created_at"`
 	UpdatedAt           time.Time `gorm:"column:updated_at"`
 }
@@ -71,12 +81,57 @@ func (ContractDataEntry) TableName() string {
 	return "contract_data_entries"
 }
 
-func UpsertContractDataEntry(db *gorm.DB, entry *ContractDataEntry) error {
-	return db.Clauses(clause.OnConflict{
-		Columns: []clause.Column{{Name: "key_hash"}},
-		DoUpdates: clause.AssignmentColumns([]string{
-			"contract_id", "key_xdr", "expiration_ledger_seq",
-			"key", "durability", "flags", "val_xdr", "val", "updated_at",
-		}),
-	}).Create(entry).Error
+// Removed reports whether the entry was deleted or evicted
+func (e *ContractDataEntry) Removed() bool {
+	return e.RemovedLedger != nil
+}
+
+// Expired reports whether the entry's TTL ran out before ledger (archived or deleted state)
+// Entries without a known TTL never expire
+func (e *ContractDataEntry) Expired(ledger uint32) bool {
+	return e.ExpirationLedgerSeq != 0 && e.ExpirationLedgerSeq < ledger
+}
+
+// UpsertContractDataEntry stores the live value of an entry, clearing any earlier removal
+// An unknown expiration (0) keeps the stored one, since TTLs are only written when they change
+// Writes from a ledger before the stored entry's are ignored; it reports whether entry was applied
+func UpsertContractDataEntry(db *gorm.DB, entry *ContractDataEntry) (bool, error) {
+	columns := []string{
+		"contract_id", "key_xdr", "key", "durability", "flags", "val_xdr", "val", "removed_ledger",
+		"last_modified_ledger", "updated_at",
+	}
+	if entry.ExpirationLedgerSeq != 0 {
+		columns = append(columns, "expiration_ledger_seq")
+	}
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_hash"}},
+		DoUpdates: clause.AssignmentColumns(columns),
+		Where:     notOlderContractData,
+	}).Create(entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// RemoveContractDataEntry tombstones an entry with entry.RemovedLedger, keeping its last value
+// Entries never seen before are stored with just their key
+// Like UpsertContractDataEntry, removals older than the stored entry are ignored
+func RemoveContractDataEntry(db *gorm.DB, entry *ContractDataEntry) (bool, error) {
+	result := db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key_hash"}},
+		DoUpdates: clause.AssignmentColumns([]string{"removed_ledger", "last_modified_ledger", "updated_at"}),
+		Where:     notOlderContractData,
+	}).Create(entry)
+	return result.RowsAffected > 0, result.Error
+}
+
+// notOlderContractData keeps ledgers indexed out of order from overwriting newer entries
+var notOlderContractData = clause.Where{Exprs: []clause.Expression{
+	clause.Expr{SQL: "excluded.last_modified_ledger >= contract_data_entries.last_modified_ledger"},
+}}
+
+// UpdateContractDataExpiration sets the ledger an entry lives until, from its TTL entry
+func UpdateContractDataExpiration(db *gorm.DB, keyHash string, liveUntilLedger uint32) error {
+	return db.Model(&ContractDataEntry{}).Where("key_hash = ?", keyHash).Updates(map[string]interface{}{
+		"expiration_ledger_seq": liveUntilLedger,
+		"updated_at":            time.Now(),
+	}).Error
 }
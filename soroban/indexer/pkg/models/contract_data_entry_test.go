@@ -118,7 +118,7 @@ func TestContractDataEntryJSONBSerialization(t *testing.T) {
 	for _, tt := range tests {
 		t.Run(tt.name, func(t *testing.T) {
 			// Insert entry
-			if err := UpsertContractDataEntry(db, tt.entry); err != nil {
+			if _, err := UpsertContractDataEntry(db, tt.entry); err != nil {
 				t.Errorf("Failed to upsert contract data entry: %v", err)
 				return
 			}
@@ -183,7 +183,7 @@ func TestContractDataEntryUpsert(t *testing.T) {
 		UpdatedAt:  time.Now(),
 	}
 
-	if err := UpsertContractDataEntry(db, entry); err != nil {
+	if _, err := UpsertContractDataEntry(db, entry); err != nil {
 		t.Fatalf("Failed to insert: %v", err)
 	}
 
@@ -204,7 +204,7 @@ func TestContractDataEntryUpsert(t *testing.T) {
 		Durability: "temporary",
 	}
 
-	if err := UpsertContractDataEntry(db, updatedEntry); err != nil {
+	if _, err := UpsertContractDataEntry(db, updatedEntry); err != nil {
 		t.Fatalf("Failed to upsert: %v", err)
 	}
 
@@ -225,6 +225,54 @@ func TestContractDataEntryUpsert(t *testing.T) {
 	}
 }
 
+// TestContractDataEntryUpsertOlderLedger tests that writes and removals from an earlier ledger
+// than the stored entry's are ignored
+func TestContractDataEntryUpsertOlderLedger(t *testing.T) {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to connect to database: %v", err)
+	}
+	if err := db.AutoMigrate(&ContractDataEntry{}); err != nil {
+		t.Fatalf("Failed to migrate: %v", err)
+	}
+
+	write := func(ledger uint32, val string) bool {
+		applied, err := UpsertContractDataEntry(db, &ContractDataEntry{
+			KeyHash: "test_hash", ContractID: "CTEST", Val: JSONB(`"` + val + `"`), LastModifiedLedger: ledger,
+		})
+		if err != nil {
+			t.Fatalf("Failed to upsert: %v", err)
+		}
+		return applied
+	}
+	stored := func() ContractDataEntry {
+		var retrieved ContractDataEntry
+		if err := db.Where("key_hash = ?", "test_hash").First(&retrieved).Error; err != nil {
+			t.Fatalf("Failed to load entry: %v", err)
+		}
+		return retrieved
+	}
+
+	if !write(200, "new") || !write(200, "same ledger") {
+		t.Fatal("Expected writes from the stored ledger to apply")
+	}
+	if write(150, "old") {
+		t.Error("Expected a write from an earlier ledger to be ignored")
+	}
+	if got := stored(); string(got.Val) != `"same ledger"` || got.LastModifiedLedger != 200 {
+		t.Errorf("Entry = %s at %d, want \"same ledger\" at 200", got.Val, got.LastModifiedLedger)
+	}
+
+	removedAt := uint32(180)
+	applied, err := RemoveContractDataEntry(db, &ContractDataEntry{KeyHash: "test_hash", RemovedLedger: &removedAt, LastModifiedLedger: removedAt})
+	if err != nil {
+		t.Fatalf("Failed to remove: %v", err)
+	}
+	if got := stored(); applied || got.Removed() {
+		t.Error("Expected a removal from an earlier ledger to be ignored")
+	}
+}
+
 // TestContractDataEntryNilValues tests handling of nil values
 func TestContractDataEntryNilValues(t *testing.T) {
 	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
@@ -246,7 +294,7 @@ func TestContractDataEntryNilValues(t *testing.T) {
 	}
 
 	// Should insert without error
-	if err := UpsertContractDataEntry(db, entry); err != nil {
+	if _, err := UpsertContractDataEntry(db, entry); err != nil {
 		t.Errorf("Failed to insert entry with nil JSONB: %v", err)
 	}
 
@@ -326,7 +374,7 @@ func TestContractDataEntryComplexStructures(t *testing.T) {
 	}
 
 	// Insert
-	if err := UpsertContractDataEntry(db, entry); err != nil {
+	if _, err := UpsertContractDataEntry(db, entry); err != nil {
 		t.Errorf("Failed to insert complex structure: %v", err)
 		return
 	}
@@ -390,7 +438,7 @@ func BenchmarkContractDataEntryUpsert(b *testing.B) {
 
 	b.ResetTimer()
 	for i := 0; i < b.N; i++ {
-		if err := UpsertContractDataEntry(db, entry); err != nil {
+		if _, err := UpsertContractDataEntry(db, entry); err != nil {
 			b.Fatalf("Upsert failed: %v", err)
 		}
 	}
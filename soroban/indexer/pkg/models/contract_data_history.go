@@ -0,0 +1,73 @@
+package models
+
+import (
+	"math"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Contract data change types
+const (
+	ContractDataCreated  = "created"
+	ContractDataUpdated  = "updated"
+	ContractDataRestored = "restored"
+	ContractDataRemoved  = "removed"
+)
+
+// EvictionTxIndex is the TxIndex of changes made by ledger evictions, which are applied after
+// every transaction of the ledger
+const EvictionTxIndex = math.MaxInt32
+
+// ContractDataHistory is one version of a contract storage entry
+// Rows are append-only: every created, updated, restored or removed value is kept, ordered by
+// (ledger, tx_index, change_index), so storage can be read as of any ledger. The key hash is part
+// of the primary key, so versions of different entries that share a position are all kept
+type ContractDataHistory struct {
+	Ledger      uint32    `gorm:"column:ledger;primaryKey;autoIncrement:false;index:idx_contract_data_history_key,priority:3"`
+	TxIndex     int32     `gorm:"column:tx_index;primaryKey;autoIncrement:false"`     // Application order of the transaction
+	ChangeIndex int32     `gorm:"column:change_index;primaryKey;autoIncrement:false"` // Position among the transaction's contract data changes
+	TxHash      string    `gorm:"column:tx_hash;type:varchar(64)"`                    // Empty for evictions
+	OpIndex     int32     `gorm:"column:op_index"`                                    // -1 for transaction level changes and evictions
+	ChangeType  string    `gorm:"column:change_type;type:varchar(16)"`
+	KeyHash     string    `gorm:"column:key_hash;type:text;primaryKey;index"`
+	ContractID  string    `gorm:"column:contract_id;type:varchar(64);index:idx_contract_data_history_key,priority:1"`
+	KeyXdr      string    `gorm:"column:key_xdr;type:text;index:idx_contract_data_history_key,priority:2"`
+	Key         JSONB     `gorm:"column:key;type:jsonb"`
+	Durability  string    `gorm:"column:durability;type:varchar(64)"`
+	ValXdr      string    `gorm:"column:val_xdr;type:text"` // Empty for removals
+	Val         JSONB     `gorm:"column:val;type:jsonb"`
+	CreatedAt   time.Time `gorm:"column:created_at"`
+}
+
+func (ContractDataHistory) TableName() string {
+	return "contract_data_history"
+}
+
+// InsertContractDataHistory appends versions; versions already stored are left untouched, so
+// reprocessing a ledger doesn't duplicate them
+func InsertContractDataHistory(db *gorm.DB, rows []*ContractDataHistory) error {
+	if len(rows) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(rows).Error
+}
+
+// GetContractDataAt returns the version of a contract's storage entry current at the end of
+// ledger, keyed by the base64 XDR of its ScVal key and its durability (persistent and temporary
+// entries with the same key are different entries)
+// A removal row means the entry didn't exist at that ledger; nil means it was never written
+func GetContractDataAt(db *gorm.DB, contractID, keyXdr, durability string, ledger uint32) (*ContractDataHistory, error) {
+	var row ContractDataHistory
+	err := db.Where("contract_id = ? AND key_xdr = ? AND durability = ? AND ledger <= ?", contractID, keyXdr, durability, ledger).
+		Order("ledger DESC, tx_index DESC, change_index DESC").
+		First(&row).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &row, nil
+}
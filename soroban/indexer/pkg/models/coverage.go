@@ -0,0 +1,142 @@
+package models
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+// LedgerRange is an inclusive range of ledger sequences
+type LedgerRange struct {
+	Start uint32 `json:"start"`
+	End   uint32 `json:"end"`
+}
+
+// LedgerCoverage records a range of ledgers that was fully ingested
+// Ranges ingested back to back by the same source are merged into one row
+type LedgerCoverage struct {
+	ID          uint      `gorm:"column:id;primaryKey;autoIncrement"`
+	StartLedger uint32    `gorm:"column:start_ledger;not null;index"`
+	EndLedger   uint32    `gorm:"column:end_ledger;not null;index"`
+	Source      string    `gorm:"column:source;not null;index"` // Cursor or backfill job that ingested the range
+	CreatedAt   time.Time `gorm:"column:created_at"`
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
+}
+
+// TableName returns the table name for LedgerCoverage
+func (LedgerCoverage) TableName() string {
+	return "ledger_coverage"
+}
+
+// RecordLedgerCoverage marks [startLedger, endLedger] as ingested by source
+// A range that overlaps or touches an existing range of the same source extends it
+func RecordLedgerCoverage(db *gorm.DB, source string, startLedger, endLedger uint32) error {
+	return db.Transaction(func(tx *gorm.DB) error {
+		var existing LedgerCoverage
+		err := tx.Where("source = ? AND start_ledger <= ? AND end_ledger + 1 >= ?", source, uint64(endLedger)+1, startLedger).
+			Order("start_ledger ASC").
+			First(&existing).Error
+		if err == gorm.ErrRecordNotFound {
+			return tx.Create(&LedgerCoverage{StartLedger: startLedger, EndLedger: endLedger, Source: source}).Error
+		}
+		if err != nil {
+			return err
+		}
+
+		if startLedger < existing.StartLedger {
+			existing.StartLedger = startLedger
+		}
+		if endLedger > existing.EndLedger {
+			existing.EndLedger = endLedger
+		}
+		return tx.Model(&existing).Updates(map[string]interface{}{
+			"start_ledger": existing.StartLedger,
+			"end_ledger":   existing.EndLedger,
+			"updated_at":   time.Now(),
+		}).Error
+	})
+}
+
+// GetCoveredRange returns the lowest and highest ingested ledgers (0, 0 when nothing is recorded)
+func GetCoveredRange(db *gorm.DB) (uint32, uint32, error) {
+	var bounds struct {
+		Start *uint32
+		End   *uint32
+	}
+	err := db.Model(&LedgerCoverage{}).Select("MIN(start_ledger) AS start, MAX(end_ledger) AS end").Scan(&bounds).Error
+	if err != nil || bounds.Start == nil || bounds.End == nil {
+		return 0, 0, err
+	}
+	return *bounds.Start, *bounds.End, nil
+}
+
+// FindCoverageGaps returns the ranges inside [startLedger, endLedger] that no coverage row includes
+func FindCoverageGaps(db *gorm.DB, startLedger, endLedger uint32) ([]LedgerRange, error) {
+	var rows []LedgerCoverage
+	err := db.Where("end_ledger >= ? AND start_ledger <= ?", startLedger, endLedger).
+		Order("start_ledger ASC").
+		Find(&rows).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var gaps []LedgerRange
+	next := uint64(startLedger) // First ledger not yet known to be covered
+	for _, row := range rows {
+		if uint64(row.StartLedger) > next {
+			gaps = append(gaps, LedgerRange{Start: uint32(next), End: row.StartLedger - 1})
+		}
+		if uint64(row.EndLedger)+1 > next {
+			next = uint64(row.EndLedger) + 1
+		}
+	}
+	if next <= uint64(endLedger) {
+		gaps = append(gaps, LedgerRange{Start: uint32(next), End: endLedger})
+	}
+
+	return gaps, nil
+}
+
+// FindEventLedgerHoles returns runs of ledgers inside [startLedger, endLedger] that lie between
+// two ledgers with events but have no events themselves
+func FindEventLedgerHoles(db *gorm.DB, startLedger, endLedger uint32) ([]LedgerRange, error) {
+	return findLedgerHoles(db, Event{}.TableName(), startLedger, endLedger)
+}
+
+// FindTransactionLedgerHoles returns runs of ledgers inside [startLedger, endLedger] that lie
+// between two ledgers with transactions but have no transactions themselves
+func FindTransactionLedgerHoles(db *gorm.DB, startLedger, endLedger uint32) ([]LedgerRange, error) {
+	return findLedgerHoles(db, Transaction{}.TableName(), startLedger, endLedger)
+}
+
+// findLedgerHoles finds missing ledger sequences between the distinct ledgers of a table
+func findLedgerHoles(db *gorm.DB, table string, startLedger, endLedger uint32) ([]LedgerRange, error) {
+	query := fmt.Sprintf(`
+		SELECT prev + 1 AS start, ledger - 1 AS "end"
+		FROM (
+			SELECT ledger, LAG(ledger) OVER (ORDER BY ledger) AS prev
+			FROM (SELECT DISTINCT ledger FROM %s WHERE ledger BETWEEN ? AND ?) distinct_ledgers
+		) ordered
+		WHERE ledger - prev > 1
+		ORDER BY ledger ASC
+	`, table)
+
+	var holes []LedgerRange
+	err := db.Raw(query, startLedger, endLedger).Scan(&holes).Error
+	return holes, err
+}
+
+// CountEventsInLedger returns the number of stored events of a ledger
+func CountEventsInLedger(db *gorm.DB, ledger uint32) (int64, error) {
+	var count int64
+	err := db.Model(&Event{}).Where("ledger = ?", ledger).Count(&count).Error
+	return count, err
+}
+
+// CountTransactionsInLedger returns the number of stored transactions of a ledger
+func CountTransactionsInLedger(db *gorm.DB, ledger uint32) (int64, error) {
+	var count int64
+	err := db.Model(&Transaction{}).Where("ledger = ?", ledger).Count(&count).Error
+	return count, err
+}
@@ -0,0 +1,99 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupCoverageTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&LedgerCoverage{}, &Event{}, &Transaction{})
+	require.NoError(t, err)
+
+	return db
+}
+
+func TestRecordLedgerCoverage_MergesAdjacentRanges(t *testing.T) {
+	db := setupCoverageTestDB(t)
+
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 100, 100))
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 101, 105))
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 103, 104)) // Re-ingested
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 110, 112))
+	require.NoError(t, RecordLedgerCoverage(db, "backfill-1-50", 1, 50))
+
+	var rows []LedgerCoverage
+	require.NoError(t, db.Order("start_ledger ASC").Find(&rows).Error)
+	require.Len(t, rows, 3)
+	assert.Equal(t, LedgerRange{Start: 1, End: 50}, LedgerRange{Start: rows[0].StartLedger, End: rows[0].EndLedger})
+	assert.Equal(t, LedgerRange{Start: 100, End: 105}, LedgerRange{Start: rows[1].StartLedger, End: rows[1].EndLedger})
+	assert.Equal(t, LedgerRange{Start: 110, End: 112}, LedgerRange{Start: rows[2].StartLedger, End: rows[2].EndLedger})
+
+	start, end, err := GetCoveredRange(db)
+	require.NoError(t, err)
+	assert.Equal(t, uint32(1), start)
+	assert.Equal(t, uint32(112), end)
+}
+
+func TestFindCoverageGaps(t *testing.T) {
+	db := setupCoverageTestDB(t)
+
+	start, end, err := GetCoveredRange(db)
+	require.NoError(t, err)
+	assert.Zero(t, start)
+	assert.Zero(t, end)
+
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 100, 105))
+	require.NoError(t, RecordLedgerCoverage(db, "job", 103, 108)) // Overlaps another source
+	require.NoError(t, RecordLedgerCoverage(db, LiveCursor, 112, 120))
+
+	gaps, err := FindCoverageGaps(db, 95, 125)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{{95, 99}, {109, 111}, {121, 125}}, gaps)
+
+	gaps, err = FindCoverageGaps(db, 100, 108)
+	require.NoError(t, err)
+	assert.Empty(t, gaps)
+}
+
+func TestFindLedgerHoles(t *testing.T) {
+	db := setupCoverageTestDB(t)
+
+	for i, ledger := range []int32{100, 100, 101, 105, 106, 110} {
+		require.NoError(t, UpsertEvent(db, &Event{ID: string(rune('a' + i)), Ledger: ledger}))
+	}
+	for _, id := range []string{"tx1", "tx2"} {
+		ledger := uint32(200)
+		if id == "tx2" {
+			ledger = 203
+		}
+		require.NoError(t, UpsertTransaction(db, &Transaction{ID: id, Ledger: &ledger}))
+	}
+
+	holes, err := FindEventLedgerHoles(db, 0, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{{102, 104}, {107, 109}}, holes)
+
+	// Holes are only reported between ledgers inside the range
+	holes, err = FindEventLedgerHoles(db, 105, 200)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{{107, 109}}, holes)
+
+	holes, err = FindTransactionLedgerHoles(db, 0, 300)
+	require.NoError(t, err)
+	assert.Equal(t, []LedgerRange{{201, 202}}, holes)
+
+	count, err := CountEventsInLedger(db, 100)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), count)
+
+	count, err = CountTransactionsInLedger(db, 203)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), count)
+}
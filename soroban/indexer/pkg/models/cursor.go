@@ -6,29 +6,73 @@ import (
 	"gorm.io/gorm"
 )
 
+// LiveCursor is the name of the cursor followed by live polling
+const LiveCursor = "live"
+
+// Cursor is the progress of one named ingestion pipeline or backfill job
 type Cursor struct {
-	ID         int       `gorm:"column:id;primaryKey"`
-	LastLedger uint32    `gorm:"column:last_ledger;not null"`
-	UpdatedAt  time.Time `gorm:"column:updated_at"`
+	Name        string    `gorm:"column:name;primaryKey"`
+	LastLedger  uint32    `gorm:"column:last_ledger;not null"`
+	PagingToken string    `gorm:"column:paging_token"` // RPC getEvents cursor to resume from (empty = use LastLedger)
+	UpdatedAt   time.Time `gorm:"column:updated_at"`
 }
 
 func (Cursor) TableName() string {
 	return "indexer_cursor"
 }
 
-func GetCursor(db *gorm.DB) (uint32, error) {
+// GetCursor returns the last processed ledger of the named cursor (0 if it has never been stored)
+func GetCursor(db *gorm.DB, name string) (uint32, error) {
 	var cursor Cursor
-	err := db.First(&cursor, 1).Error
+	err := db.Where("name = ?", name).First(&cursor).Error
 	if err == gorm.ErrRecordNotFound {
 		return 0, nil
 	}
 	return cursor.LastLedger, err
 }
 
-func UpdateCursor(db *gorm.DB, ledger uint32) error {
+// GetCursorState returns the full cursor row, including the paging token
+// Returns an empty cursor if none has been stored yet
+func GetCursorState(db *gorm.DB, name string) (*Cursor, error) {
+	var cursor Cursor
+	err := db.Where("name = ?", name).First(&cursor).Error
+	if err == gorm.ErrRecordNotFound {
+		return &Cursor{Name: name}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &cursor, nil
+}
+
+// ListCursors returns every stored cursor ordered by name
+func ListCursors(db *gorm.DB) ([]Cursor, error) {
+	var cursors []Cursor
+	err := db.Order("name ASC").Find(&cursors).Error
+	return cursors, err
+}
+
+// UpdateCursor stores the last processed ledger and clears the paging token
+func UpdateCursor(db *gorm.DB, name string, ledger uint32) error {
+	return UpdateCursorWithToken(db, name, ledger, "")
+}
+
+// UpdateCursorWithToken stores the last processed ledger together with the RPC paging token
+// that the next getEvents call should resume from
+func UpdateCursorWithToken(db *gorm.DB, name string, ledger uint32, pagingToken string) error {
 	return db.Save(&Cursor{
-		ID:         1,
-		LastLedger: ledger,
-		UpdatedAt:  time.Now(),
+		Name:        name,
+		LastLedger:  ledger,
+		PagingToken: pagingToken,
+		UpdatedAt:   time.Now(),
 	}).Error
 }
+
+// ResetCursor moves the named cursor to ledger, so its pipeline resumes at ledger + 1
+// A ledger of 0 deletes the cursor; live polling then starts again from the latest ledger
+func ResetCursor(db *gorm.DB, name string, ledger uint32) error {
+	if ledger == 0 {
+		return db.Where("name = ?", name).Delete(&Cursor{}).Error
+	}
+	return UpdateCursor(db, name, ledger)
+}
@@ -0,0 +1,60 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Diagnostic event names emitted by the Soroban host
+const (
+	DiagnosticFnCall   = "fn_call"
+	DiagnosticFnReturn = "fn_return"
+	DiagnosticError    = "error"
+	DiagnosticLog      = "log"
+)
+
+// DiagnosticEvent is an event from a Soroban transaction's diagnostic stream: the call trace
+// (fn_call / fn_return), host errors and contract logs. Unlike contract events they are kept for
+// failed transactions, which makes them the only record of why a call failed
+type DiagnosticEvent struct {
+	TxHash                   string    `gorm:"column:tx_hash;primaryKey"`
+	EventIndex               int32     `gorm:"column:event_index;primaryKey"`
+	Ledger                   uint32    `gorm:"column:ledger;index:idx_diagnostic_events_ledger"`
+	EventType                string    `gorm:"column:type"`
+	Name                     string    `gorm:"column:name;index:idx_diagnostic_events_name"` // First topic when it is a symbol, e.g. fn_call
+	ContractID               string    `gorm:"column:contract_id;index:idx_diagnostic_events_contract_id"`
+	Topic                    JSONB     `gorm:"column:topic;type:jsonb"`
+	Value                    JSONB     `gorm:"column:value;type:jsonb"`
+	InSuccessfulContractCall bool      `gorm:"column:in_successful_contract_call"`
+	CreatedAt                time.Time `gorm:"column:created_at"`
+}
+
+func (DiagnosticEvent) TableName() string {
+	return "diagnostic_events"
+}
+
+// InsertDiagnosticEvents stores a transaction's diagnostic events, skipping ones already stored
+func InsertDiagnosticEvents(db *gorm.DB, events []*DiagnosticEvent) error {
+	if len(events) == 0 {
+		return nil
+	}
+	return db.Clauses(clause.OnConflict{DoNothing: true}).Create(&events).Error
+}
+
+// GetDiagnosticEvents returns a transaction's diagnostic events in emission order
+func GetDiagnosticEvents(db *gorm.DB, txHash string) ([]DiagnosticEvent, error) {
+	var events []DiagnosticEvent
+	err := db.Where("tx_hash = ?", txHash).Order("event_index ASC").Find(&events).Error
+	return events, err
+}
+
+// GetFailedCallTrace returns the diagnostic events of a transaction's failed contract calls:
+// the fn_call / fn_return trace and errors that were rolled back
+func GetFailedCallTrace(db *gorm.DB, txHash string) ([]DiagnosticEvent, error) {
+	var events []DiagnosticEvent
+	err := db.Where("tx_hash = ? AND NOT in_successful_contract_call", txHash).
+		Order("event_index ASC").Find(&events).Error
+	return events, err
+}
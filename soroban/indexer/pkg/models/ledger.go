@@ -0,0 +1,97 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+// Ledger represents a closed ledger header together with per-ledger counts
+type Ledger struct {
+	Sequence               uint32    `gorm:"column:sequence;primaryKey"`
+	Hash                   string    `gorm:"column:hash;type:varchar(64);uniqueIndex;not null"`
+	PreviousHash           string    `gorm:"column:previous_hash;type:varchar(64);not null"`
+	ClosedAt               time.Time `gorm:"column:closed_at;index"`
+	ProtocolVersion        uint32    `gorm:"column:protocol_version"`
+	BaseFee                uint32    `gorm:"column:base_fee"`          // Stroops
+	BaseReserve            uint32    `gorm:"column:base_reserve"`      // Stroops
+	TransactionCount       int       `gorm:"column:transaction_count"` // Successful and failed
+	FailedTransactionCount int       `gorm:"column:failed_transaction_count"`
+	OperationCount         int       `gorm:"column:operation_count"` // Operations of every transaction in the ledger
+
+	// Soroban fee settings
+	// SorobanFeeWrite1KB comes from the LedgerCloseMeta extension (nil when absent). SorobanFeeUpgrades
+	// is not the effective fee schedule: it only holds the fee-related network config settings
+	// upgraded in this ledger (nil otherwise), so the settings in force at a ledger are the latest
+	// upgrade of each at or before it
+	SorobanFeeWrite1KB *int64 `gorm:"column:soroban_fee_write_1kb"`
+	SorobanFeeUpgrades JSONB  `gorm:"column:soroban_fee_upgrades;type:jsonb"`
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
+}
+
+// TableName returns the table name for Ledger
+func (Ledger) TableName() string {
+	return "ledgers"
+}
+
+// UpsertLedger inserts or updates a ledger by sequence
+func UpsertLedger(db *gorm.DB, ledger *Ledger) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "sequence"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"hash", "previous_hash", "closed_at", "protocol_version", "base_fee", "base_reserve",
+			"transaction_count", "failed_transaction_count", "operation_count",
+			"soroban_fee_write_1kb", "soroban_fee_upgrades", "updated_at",
+		}),
+	}).Create(ledger).Error
+}
+
+// GetLedger retrieves a ledger by sequence
+func GetLedger(db *gorm.DB, sequence uint32) (*Ledger, error) {
+	var ledger Ledger
+	err := db.Where("sequence = ?", sequence).First(&ledger).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ledger, nil
+}
+
+// GetLedgerByHash retrieves a ledger by its hex-encoded hash
+func GetLedgerByHash(db *gorm.DB, hash string) (*Ledger, error) {
+	var ledger Ledger
+	err := db.Where("hash = ?", hash).First(&ledger).Error
+	if err != nil {
+		return nil, err
+	}
+	return &ledger, nil
+}
+
+// GetLedgers retrieves ledgers, newest first
+func GetLedgers(db *gorm.DB, limit, offset int) ([]Ledger, error) {
+	var ledgers []Ledger
+	err := db.Order("sequence DESC").Limit(limit).Offset(offset).Find(&ledgers).Error
+	return ledgers, err
+}
+
+// GetLedgerRange retrieves ledgers in [start, end], oldest first
+func GetLedgerRange(db *gorm.DB, start, end uint32) ([]Ledger, error) {
+	var ledgers []Ledger
+	err := db.Where("sequence BETWEEN ? AND ?", start, end).Order("sequence ASC").Find(&ledgers).Error
+	return ledgers, err
+}
+
+// FindLedgerChainBreaks returns the sequences in [start, end] whose previous hash does not match
+// the stored hash of the ledger before them
+// Ledgers whose predecessor is not stored are not reported
+func FindLedgerChainBreaks(db *gorm.DB, start, end uint32) ([]uint32, error) {
+	var breaks []uint32
+	err := db.Raw(`
+		SELECT l.sequence FROM ledgers l
+		JOIN ledgers prev ON prev.sequence = l.sequence - 1
+		WHERE l.sequence BETWEEN ? AND ? AND l.previous_hash <> prev.hash
+		ORDER BY l.sequence`, start, end).Scan(&breaks).Error
+	return breaks, err
+}
@@ -0,0 +1,128 @@
+package models
+
+import (
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func setupLedgerTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(&Ledger{})
+	require.NoError(t, err)
+
+	return db
+}
+
+// testLedgerHash returns a deterministic 64-char hex hash for a sequence
+func testLedgerHash(sequence uint32) string {
+	return fmt.Sprintf("%064x", sequence)
+}
+
+// seedLedgerChain stores a correctly linked chain of ledgers [start, end]
+func seedLedgerChain(t *testing.T, db *gorm.DB, start, end uint32) {
+	for seq := start; seq <= end; seq++ {
+		require.NoError(t, UpsertLedger(db, &Ledger{
+			Sequence:     seq,
+			Hash:         testLedgerHash(seq),
+			PreviousHash: testLedgerHash(seq - 1),
+			ClosedAt:     time.Unix(int64(1704067200+5*(seq-start)), 0).UTC(),
+		}))
+	}
+}
+
+func TestLedgerTableName(t *testing.T) {
+	assert.Equal(t, "ledgers", Ledger{}.TableName())
+}
+
+func TestUpsertLedger(t *testing.T) {
+	db := setupLedgerTestDB(t)
+
+	feeWrite := int64(3500)
+	ledger := &Ledger{
+		Sequence:           100,
+		Hash:               testLedgerHash(100),
+		PreviousHash:       testLedgerHash(99),
+		ClosedAt:           time.Unix(1704067200, 0).UTC(),
+		ProtocolVersion:    22,
+		BaseFee:            100,
+		BaseReserve:        5000000,
+		TransactionCount:   3,
+		OperationCount:     4,
+		SorobanFeeWrite1KB: &feeWrite,
+	}
+	require.NoError(t, UpsertLedger(db, ledger))
+
+	// Re-ingesting the same ledger updates it in place
+	ledger.TransactionCount = 5
+	ledger.FailedTransactionCount = 1
+	require.NoError(t, UpsertLedger(db, ledger))
+
+	var count int64
+	db.Model(&Ledger{}).Count(&count)
+	assert.Equal(t, int64(1), count)
+
+	stored, err := GetLedger(db, 100)
+	require.NoError(t, err)
+	assert.Equal(t, 5, stored.TransactionCount)
+	assert.Equal(t, 1, stored.FailedTransactionCount)
+	assert.Equal(t, uint32(22), stored.ProtocolVersion)
+	require.NotNil(t, stored.SorobanFeeWrite1KB)
+	assert.Equal(t, int64(3500), *stored.SorobanFeeWrite1KB)
+
+	byHash, err := GetLedgerByHash(db, testLedgerHash(100))
+	require.NoError(t, err)
+	assert.Equal(t, uint32(100), byHash.Sequence)
+
+	_, err = GetLedger(db, 101)
+	assert.ErrorIs(t, err, gorm.ErrRecordNotFound)
+}
+
+func TestGetLedgers(t *testing.T) {
+	db := setupLedgerTestDB(t)
+	seedLedgerChain(t, db, 100, 104)
+
+	latest, err := GetLedgers(db, 2, 0)
+	require.NoError(t, err)
+	require.Len(t, latest, 2)
+	assert.Equal(t, uint32(104), latest[0].Sequence)
+	assert.Equal(t, uint32(103), latest[1].Sequence)
+
+	rangeLedgers, err := GetLedgerRange(db, 101, 103)
+	require.NoError(t, err)
+	require.Len(t, rangeLedgers, 3)
+	assert.Equal(t, uint32(101), rangeLedgers[0].Sequence)
+	assert.Equal(t, uint32(103), rangeLedgers[2].Sequence)
+}
+
+func TestFindLedgerChainBreaks(t *testing.T) {
+	db := setupLedgerTestDB(t)
+	seedLedgerChain(t, db, 100, 105)
+
+	breaks, err := FindLedgerChainBreaks(db, 100, 105)
+	require.NoError(t, err)
+	assert.Empty(t, breaks)
+
+	// Ledger 103 points at a hash that is not ledger 102's
+	require.NoError(t, UpsertLedger(db, &Ledger{
+		Sequence:     103,
+		Hash:         testLedgerHash(103),
+		PreviousHash: testLedgerHash(999),
+	}))
+
+	breaks, err = FindLedgerChainBreaks(db, 100, 105)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{103}, breaks)
+
+	// Out of range breaks are not reported
+	breaks, err = FindLedgerChainBreaks(db, 104, 105)
+	require.NoError(t, err)
+	assert.Empty(t, breaks)
+}
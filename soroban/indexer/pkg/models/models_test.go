@@ -122,8 +122,8 @@ func TestUpsertTransaction(t *testing.T) {
 	ledger := uint32(12345)
 	appOrder := int32(1)
 	sourceAccount := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
-	fee := int32(100)
-	feeCharged := int32(100)
+	fee := int64(100)
+	feeCharged := int64(100)
 	sequence := int64(987654321)
 	feeBump := false
 	createdAt := int64(1704067200)
@@ -174,11 +174,11 @@ func TestTransactionWithExtendedFields(t *testing.T) {
 	ledger := uint32(12345)
 	appOrder := int32(1)
 	sourceAccount := "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
-	fee := int32(100)
-	feeCharged := int32(100)
+	fee := int64(100)
+	feeCharged := int64(100)
 	sequence := int64(987654321)
 	feeBump := true
-	muxedId := int64(1234567890)
+	muxedId := "1234567890"
 
 	// Extended fields
 	memo := &util.TypeItem{
@@ -257,7 +257,7 @@ func TestCursor(t *testing.T) {
 	db := setupTestDB(t)
 
 	// Initial cursor should be 0
-	cursor, err := GetCursor(db)
+	cursor, err := GetCursor(db, LiveCursor)
 	if err != nil {
 		t.Fatalf("GetCursor() error = %v", err)
 	}
@@ -266,13 +266,13 @@ func TestCursor(t *testing.T) {
 	}
 
 	// Update cursor
-	err = UpdateCursor(db, 12345)
+	err = UpdateCursor(db, LiveCursor, 12345)
 	if err != nil {
 		t.Fatalf("UpdateCursor() error = %v", err)
 	}
 
 	// Verify updated cursor
-	cursor, err = GetCursor(db)
+	cursor, err = GetCursor(db, LiveCursor)
 	if err != nil {
 		t.Fatalf("GetCursor() after update error = %v", err)
 	}
@@ -281,12 +281,12 @@ func TestCursor(t *testing.T) {
 	}
 
 	// Update again
-	err = UpdateCursor(db, 67890)
+	err = UpdateCursor(db, LiveCursor, 67890)
 	if err != nil {
 		t.Fatalf("UpdateCursor() second update error = %v", err)
 	}
 
-	cursor, err = GetCursor(db)
+	cursor, err = GetCursor(db, LiveCursor)
 	if err != nil {
 		t.Fatalf("GetCursor() after second update error = %v", err)
 	}
@@ -295,6 +295,98 @@ func TestCursor(t *testing.T) {
 	}
 }
 
+func TestCursorPagingToken(t *testing.T) {
+	db := setupTestDB(t)
+
+	// Empty state before any update
+	state, err := GetCursorState(db, LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 0 || state.PagingToken != "" {
+		t.Errorf("Initial state = %+v, want zero ledger and empty token", state)
+	}
+
+	if err := UpdateCursorWithToken(db, LiveCursor, 12345, "0000053021371011072-4294967295"); err != nil {
+		t.Fatalf("UpdateCursorWithToken() error = %v", err)
+	}
+
+	state, err = GetCursorState(db, LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() after update error = %v", err)
+	}
+	if state.LastLedger != 12345 {
+		t.Errorf("LastLedger = %v, want 12345", state.LastLedger)
+	}
+	if state.PagingToken != "0000053021371011072-4294967295" {
+		t.Errorf("PagingToken = %v, want 0000053021371011072-4294967295", state.PagingToken)
+	}
+
+	// UpdateCursor without a token clears it
+	if err := UpdateCursor(db, LiveCursor, 12346); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+	state, err = GetCursorState(db, LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() after reset error = %v", err)
+	}
+	if state.PagingToken != "" {
+		t.Errorf("PagingToken = %v, want empty after UpdateCursor", state.PagingToken)
+	}
+}
+
+func TestNamedCursors(t *testing.T) {
+	db := setupTestDB(t)
+
+	if err := UpdateCursorWithToken(db, LiveCursor, 5000, "token"); err != nil {
+		t.Fatalf("UpdateCursorWithToken() error = %v", err)
+	}
+	if err := UpdateCursor(db, "backfill-100-200", 150); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+
+	// Cursors are independent
+	live, err := GetCursor(db, LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursor() error = %v", err)
+	}
+	if live != 5000 {
+		t.Errorf("Live cursor = %v, want 5000", live)
+	}
+
+	cursors, err := ListCursors(db)
+	if err != nil {
+		t.Fatalf("ListCursors() error = %v", err)
+	}
+	if len(cursors) != 2 || cursors[0].Name != "backfill-100-200" || cursors[1].Name != LiveCursor {
+		t.Fatalf("ListCursors() = %+v, want backfill and live cursors ordered by name", cursors)
+	}
+
+	// Reset moves a cursor and clears its paging token
+	if err := ResetCursor(db, LiveCursor, 4000); err != nil {
+		t.Fatalf("ResetCursor() error = %v", err)
+	}
+	state, err := GetCursorState(db, LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 4000 || state.PagingToken != "" {
+		t.Errorf("Live cursor = %+v, want ledger 4000 without token", state)
+	}
+
+	// Reset to 0 removes the cursor
+	if err := ResetCursor(db, "backfill-100-200", 0); err != nil {
+		t.Fatalf("ResetCursor() error = %v", err)
+	}
+	cursors, err = ListCursors(db)
+	if err != nil {
+		t.Fatalf("ListCursors() error = %v", err)
+	}
+	if len(cursors) != 1 || cursors[0].Name != LiveCursor {
+		t.Errorf("ListCursors() = %+v, want only the live cursor", cursors)
+	}
+}
+
 func TestUpsertTokenMetadata(t *testing.T) {
 	db := setupTestDB(t)
 
@@ -376,10 +468,10 @@ func TestUpsertTokenBalance(t *testing.T) {
 	balance := &TokenBalance{
 		ContractID: "contract-789",
 		Address:    "holder-address",
-		Balance:    "5000000000",
 		CreatedAt:  time.Now(),
 		UpdatedAt:  time.Now(),
 	}
+	balance.Balance.SetString("5000000000", 10)
 
 	err := UpsertTokenBalance(db, balance)
 	if err != nil {
@@ -392,8 +484,8 @@ func TestUpsertTokenBalance(t *testing.T) {
 		t.Fatalf("Failed to retrieve token balance: %v", result.Error)
 	}
 
-	if retrieved.Balance != balance.Balance {
-		t.Errorf("Balance = %v, want %v", retrieved.Balance, balance.Balance)
+	if retrieved.Balance.Cmp(&balance.Balance.Int) != 0 {
+		t.Errorf("Balance = %v, want %v", retrieved.Balance.String(), balance.Balance.String())
 	}
 }
 
@@ -412,7 +504,7 @@ func TestUpsertContractDataEntry(t *testing.T) {
 		UpdatedAt:  time.Now(),
 	}
 
-	err := UpsertContractDataEntry(db, entry)
+	_, err := UpsertContractDataEntry(db, entry)
 	if err != nil {
 		t.Fatalf("UpsertContractDataEntry() error = %v", err)
 	}
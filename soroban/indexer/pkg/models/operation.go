@@ -7,12 +7,14 @@ import (
 // Operation represents a Stellar operation within a transaction
 type Operation struct {
 	// Composite primary key: tx_hash + operation_index
-	ID               string `gorm:"primaryKey;type:varchar(128)" json:"id"`
-	TxHash           string `gorm:"index;type:varchar(64);not null" json:"tx_hash"`
-	OperationIndex   int32  `gorm:"not null" json:"operation_index"`
-	SourceAccount    string `gorm:"type:varchar(56)" json:"source_account"`
-	OperationType    string `gorm:"type:varchar(64);not null" json:"operation_type"`
-	OperationDetails []byte `gorm:"type:jsonb" json:"operation_details"` // JSONB for type-specific data
+	ID               string  `gorm:"primaryKey;type:varchar(128)" json:"id"`
+	TxHash           string  `gorm:"index;type:varchar(64);not null" json:"tx_hash"`
+	OperationIndex   int32   `gorm:"not null" json:"operation_index"`
+	SourceAccount    string  `gorm:"type:varchar(56)" json:"source_account"`
+	SourceMuxedID    *string `gorm:"column:source_muxed_id;type:numeric" json:"source_muxed_id,omitempty"` // Set when the source is a muxed (M...) account, as a decimal
+	OperationType    string  `gorm:"type:varchar(64);not null" json:"operation_type"`
+	OperationDetails []byte  `gorm:"type:jsonb" json:"operation_details"`                              // JSONB for type-specific data
+	ResultCode       *string `gorm:"column:result_code;type:varchar(64)" json:"result_code,omitempty"` // e.g. PAYMENT_UNDERFUNDED, INVOKE_HOST_FUNCTION_TRAPPED
 
 	// Timestamps
 	CreatedAt int64 `gorm:"autoCreateTime" json:"created_at"`
@@ -0,0 +1,70 @@
+package models
+
+import (
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+)
+
+// TokenAllowance is the amount a spender may still transfer from an owner's balance, until the
+// expiration ledger; Ledger is the ledger of the approve or spend that set it
+type TokenAllowance struct {
+	ContractID       string      `gorm:"column:contract_id;primaryKey"`
+	Owner            string      `gorm:"column:owner;primaryKey;index:idx_token_allowances_owner"`
+	Spender          string      `gorm:"column:spender;primaryKey;index:idx_token_allowances_spender"`
+	Amount           util.Int128 `gorm:"column:amount;type:numeric"`
+	ExpirationLedger uint32      `gorm:"column:expiration_ledger"`
+	Ledger           uint32      `gorm:"column:ledger"`
+	CreatedAt        time.Time   `gorm:"column:created_at"`
+	UpdatedAt        time.Time   `gorm:"column:updated_at"`
+}
+
+func (TokenAllowance) TableName() string {
+	return "token_allowances"
+}
+
+// Active reports whether the allowance can still be spent at ledger
+func (a *TokenAllowance) Active(ledger uint32) bool {
+	return a.Amount.Sign() > 0 && a.ExpirationLedger >= ledger
+}
+
+// UpsertTokenAllowance stores an allowance unless a later ledger already set it, so ledgers
+// processed out of order don't roll it back
+func UpsertTokenAllowance(db *gorm.DB, allowance *TokenAllowance) error {
+	return db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "contract_id"}, {Name: "owner"}, {Name: "spender"}},
+		DoUpdates: clause.AssignmentColumns([]string{"amount", "expiration_ledger", "ledger", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "token_allowances.ledger <= excluded.ledger"},
+		}},
+	}).Create(allowance).Error
+}
+
+// DeleteTokenAllowance removes an allowance whose storage entry was deleted or evicted at ledger
+func DeleteTokenAllowance(db *gorm.DB, contractID, owner, spender string, ledger uint32) error {
+	return db.Where("contract_id = ? AND owner = ? AND spender = ? AND ledger <= ?", contractID, owner, spender, ledger).
+		Delete(&TokenAllowance{}).Error
+}
+
+// GetActiveAllowances returns the allowances owner granted that can still be spent at ledger,
+// across every token
+func GetActiveAllowances(db *gorm.DB, owner string, ledger uint32) ([]TokenAllowance, error) {
+	return activeAllowances(db.Where("owner = ?", owner), ledger)
+}
+
+// GetActiveAllowancesForSpender returns the allowances granted to spender that can still be spent
+// at ledger, across every token
+func GetActiveAllowancesForSpender(db *gorm.DB, spender string, ledger uint32) ([]TokenAllowance, error) {
+	return activeAllowances(db.Where("spender = ?", spender), ledger)
+}
+
+func activeAllowances(query *gorm.DB, ledger uint32) ([]TokenAllowance, error) {
+	var allowances []TokenAllowance
+	err := query.Where("amount > 0 AND expiration_ledger >= ?", ledger).
+		Order("contract_id ASC, owner ASC, spender ASC").
+		Find(&allowances).Error
+	return allowances, err
+}
@@ -0,0 +1,54 @@
+package models
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func allowance(owner, spender string, amount int64, expiration, ledger uint32) *TokenAllowance {
+	row := &TokenAllowance{ContractID: "CA", Owner: owner, Spender: spender, ExpirationLedger: expiration, Ledger: ledger}
+	row.Amount.SetInt64(amount)
+	return row
+}
+
+func TestTokenAllowances(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&TokenAllowance{}))
+
+	require.NoError(t, UpsertTokenAllowance(db, allowance("GA", "GS", 100, 500, 10)))
+	require.NoError(t, UpsertTokenAllowance(db, allowance("GA", "GT", 0, 500, 10)))  // Revoked
+	require.NoError(t, UpsertTokenAllowance(db, allowance("GA", "GU", 100, 50, 10))) // Expires early
+	require.NoError(t, UpsertTokenAllowance(db, allowance("GB", "GS", 7, 500, 10)))
+	// An older ledger doesn't overwrite a newer allowance
+	require.NoError(t, UpsertTokenAllowance(db, allowance("GA", "GS", 999, 500, 9)))
+
+	allowances, err := GetActiveAllowances(db, "GA", 100)
+	require.NoError(t, err)
+	require.Len(t, allowances, 1)
+	assert.Equal(t, "GS", allowances[0].Spender)
+	assert.Equal(t, "100", allowances[0].Amount.String())
+	assert.True(t, allowances[0].Active(500))
+	assert.False(t, allowances[0].Active(501))
+
+	allowances, err = GetActiveAllowances(db, "GA", 50)
+	require.NoError(t, err)
+	assert.Len(t, allowances, 2)
+
+	allowances, err = GetActiveAllowancesForSpender(db, "GS", 100)
+	require.NoError(t, err)
+	require.Len(t, allowances, 2)
+	assert.Equal(t, "GA", allowances[0].Owner)
+	assert.Equal(t, "GB", allowances[1].Owner)
+
+	// Removals older than the allowance leave it in place
+	require.NoError(t, DeleteTokenAllowance(db, "CA", "GA", "GS", 9))
+	allowances, err = GetActiveAllowances(db, "GA", 100)
+	require.NoError(t, err)
+	assert.Len(t, allowances, 1)
+	require.NoError(t, DeleteTokenAllowance(db, "CA", "GA", "GS", 11))
+	allowances, err = GetActiveAllowances(db, "GA", 100)
+	require.NoError(t, err)
+	assert.Empty(t, allowances)
+}
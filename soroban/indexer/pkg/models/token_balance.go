@@ -5,23 +5,68 @@ import (
 
 	"gorm.io/gorm"
 	"gorm.io/gorm/clause"
+
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
 )
 
 type TokenBalance struct {
-	ContractID string    `gorm:"column:contract_id;primaryKey;not null"`
-	Address    string    `gorm:"column:address;primaryKey;not null"`
-	Balance    string    `gorm:"column:balance"`
-	CreatedAt  time.Time `gorm:"column:created_at"`
-	UpdatedAt  time.Time `gorm:"column:updated_at"`
+	ContractID string      `gorm:"column:contract_id;primaryKey;not null"`
+	Address    string      `gorm:"column:address;primaryKey;not null"`
+	Balance    util.Int128 `gorm:"column:balance;type:numeric"`
+	// Ledger of the storage write the balance was read from
+	LastModifiedLedger uint32    `gorm:"column:last_modified_ledger;type:int;not null;default:0"`
+	CreatedAt          time.Time `gorm:"column:created_at"`
+	UpdatedAt          time.Time `gorm:"column:updated_at"`
 }
 
 func (TokenBalance) TableName() string {
 	return "token_balances"
 }
 
+// UpsertTokenBalance stores a balance unless the stored one was read from a later ledger
 func UpsertTokenBalance(db *gorm.DB, tokenBalance *TokenBalance) error {
 	return db.Clauses(clause.OnConflict{
 		Columns:   []clause.Column{{Name: "contract_id"}, {Name: "address"}},
-		DoUpdates: clause.AssignmentColumns([]string{"balance", "updated_at"}),
+		DoUpdates: clause.AssignmentColumns([]string{"balance", "last_modified_ledger", "updated_at"}),
+		Where: clause.Where{Exprs: []clause.Expression{
+			clause.Expr{SQL: "excluded.last_modified_ledger >= token_balances.last_modified_ledger"},
+		}},
 	}).Create(tokenBalance).Error
 }
+
+// DeleteTokenBalance removes the balance of address once its storage entry is gone in ledger,
+// unless it was written in a later ledger
+func DeleteTokenBalance(db *gorm.DB, contractID, address string, ledger uint32) error {
+	return db.Where("contract_id = ? AND address = ? AND last_modified_ledger <= ?", contractID, address, ledger).
+		Delete(&TokenBalance{}).Error
+}
+
+// GetTopHolders returns the largest positive balances of a token, largest first
+func GetTopHolders(db *gorm.DB, contractID string, limit int) ([]TokenBalance, error) {
+	var balances []TokenBalance
+	err := db.Where("contract_id = ? AND balance > 0", contractID).
+		Order("balance DESC, address ASC").
+		Limit(limit).
+		Find(&balances).Error
+	return balances, err
+}
+
+// CountTokenHolders returns the number of addresses holding a positive balance of a token
+func CountTokenHolders(db *gorm.DB, contractID string) (int64, error) {
+	var count int64
+	err := db.Model(&TokenBalance{}).Where("contract_id = ? AND balance > 0", contractID).Count(&count).Error
+	return count, err
+}
+
+// GetCirculatingSupply returns the sum of all balances of a token held in contract storage
+func GetCirculatingSupply(db *gorm.DB, contractID string) (string, error) {
+	var total util.Int128
+	err := db.Model(&TokenBalance{}).
+		Select("COALESCE(SUM(balance), 0)").
+		Where("contract_id = ?", contractID).
+		Scan(&total).Error
+	if err != nil {
+		return "", err
+	}
+	return total.String(), nil
+}
@@ -0,0 +1,183 @@
+package models
+
+import (
+	"math/big"
+	"sort"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+)
+
+// Token balance change sources
+const (
+	BalanceSourceEvent   = "event"   // Transfer, mint, burn and clawback events
+	BalanceSourceStorage = "storage" // Balance entries in contract storage
+)
+
+// TokenBalanceChange is a change of an address' token balance
+// Event rows carry the delta of a token operation; storage rows carry the balance stored after
+// each write of the balance entry (and its delta from the previous write), ordered by
+// (ledger, tx_index, change_index)
+type TokenBalanceChange struct {
+	ID          string       `gorm:"column:id;primaryKey"`
+	ContractID  string       `gorm:"column:contract_id;not null;index:idx_token_balance_changes_holder,priority:1"`
+	Address     string       `gorm:"column:address;not null;index:idx_token_balance_changes_holder,priority:2"`
+	Ledger      uint32       `gorm:"column:ledger;not null;index:idx_token_balance_changes_holder,priority:3"`
+	TxIndex     int32        `gorm:"column:tx_index"`
+	ChangeIndex int32        `gorm:"column:change_index"`
+	ClosedAt    time.Time    `gorm:"column:closed_at;index"`
+	Source      string       `gorm:"column:source;type:varchar(16);not null"`
+	Operation   string       `gorm:"column:operation;type:varchar(32)"` // Token operation of event rows
+	Delta       util.Int128  `gorm:"column:delta;type:numeric"`
+	Balance     *util.Int128 `gorm:"column:balance;type:numeric"` // Balance after the change, storage rows only
+	CreatedAt   time.Time    `gorm:"column:created_at"`
+}
+
+func (TokenBalanceChange) TableName() string {
+	return "token_balance_changes"
+}
+
+// TokenBalanceMismatch is an address whose event-derived balance disagrees with its stored balance
+type TokenBalanceMismatch struct {
+	Address        string `json:"address"`
+	EventBalance   string `json:"eventBalance"`   // Sum of event deltas
+	StorageBalance string `json:"storageBalance"` // Current balance entry (0 if none)
+}
+
+// InsertTokenBalanceChanges appends balance changes and returns how many were new; changes already
+// stored are left untouched
+func InsertTokenBalanceChanges(db *gorm.DB, changes []*TokenBalanceChange) (int64, error) {
+	if len(changes) == 0 {
+		return 0, nil
+	}
+	result := db.Clauses(clause.OnConflict{DoNothing: true}).Create(changes)
+	return result.RowsAffected, result.Error
+}
+
+// GetPreviousStorageBalance returns the stored balance of address before the given position
+// (0 if its balance entry was never written before)
+func GetPreviousStorageBalance(db *gorm.DB, contractID, address string, ledger uint32, txIndex, changeIndex int32) (*big.Int, error) {
+	return latestStorageBalance(db.Where(
+		"(ledger < ? OR (ledger = ? AND (tx_index < ? OR (tx_index = ? AND change_index < ?))))",
+		ledger, ledger, txIndex, txIndex, changeIndex,
+	), contractID, address)
+}
+
+// SetDeltaFromPreviousStorage sets the delta of a storage change from the storage change stored
+// before it for the same holder, for changes whose prior state isn't known
+func SetDeltaFromPreviousStorage(db *gorm.DB, change *TokenBalanceChange) error {
+	previous, err := GetPreviousStorageBalance(db, change.ContractID, change.Address, change.Ledger, change.TxIndex, change.ChangeIndex)
+	if err != nil {
+		return err
+	}
+	change.Delta.Sub(&change.Balance.Int, previous)
+	return nil
+}
+
+// GetTokenBalanceAt returns the balance of address at the end of ledger, from its balance entry
+func GetTokenBalanceAt(db *gorm.DB, contractID, address string, ledger uint32) (string, error) {
+	balance, err := latestStorageBalance(db.Where("ledger <= ?", ledger), contractID, address)
+	if err != nil {
+		return "", err
+	}
+	return balance.String(), nil
+}
+
+// GetTokenBalanceAtTime returns the balance of address as of the last ledger closed at or before at
+func GetTokenBalanceAtTime(db *gorm.DB, contractID, address string, at time.Time) (string, error) {
+	balance, err := latestStorageBalance(db.Where("closed_at <= ?", at), contractID, address)
+	if err != nil {
+		return "", err
+	}
+	return balance.String(), nil
+}
+
+// latestStorageBalance returns the balance of the last storage change matching query
+func latestStorageBalance(query *gorm.DB, contractID, address string) (*big.Int, error) {
+	var change TokenBalanceChange
+	err := query.Where("contract_id = ? AND address = ? AND source = ?", contractID, address, BalanceSourceStorage).
+		Order("ledger DESC, tx_index DESC, change_index DESC").
+		First(&change).Error
+	if err == gorm.ErrRecordNotFound {
+		return new(big.Int), nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	if change.Balance == nil {
+		return new(big.Int), nil
+	}
+	return &change.Balance.Int, nil
+}
+
+// ReconcileTokenBalances compares, for every holder of a token, the sum of its event deltas with
+// its current stored balance and returns the addresses where they differ, ordered by address
+// Only tokens indexed since their deployment can reconcile: earlier events are not summed
+func ReconcileTokenBalances(db *gorm.DB, contractID string) ([]TokenBalanceMismatch, error) {
+	var sums []struct {
+		Address string
+		Total   util.Int128
+	}
+	err := db.Model(&TokenBalanceChange{}).
+		Select("address, SUM(delta) AS total").
+		Where("contract_id = ? AND source = ?", contractID, BalanceSourceEvent).
+		Group("address").
+		Scan(&sums).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var balances []TokenBalance
+	if err := db.Where("contract_id = ?", contractID).Find(&balances).Error; err != nil {
+		return nil, err
+	}
+
+	eventBalances := make(map[string]*big.Int, len(sums))
+	for i := range sums {
+		eventBalances[sums[i].Address] = &sums[i].Total.Int
+	}
+	storageBalances := make(map[string]*big.Int, len(balances))
+	for i := range balances {
+		storageBalances[balances[i].Address] = &balances[i].Balance.Int
+	}
+
+	addresses := make(map[string]bool, len(eventBalances)+len(storageBalances))
+	for address := range eventBalances {
+		addresses[address] = true
+	}
+	for address := range storageBalances {
+		addresses[address] = true
+	}
+
+	var mismatches []TokenBalanceMismatch
+	for _, address := range sortedAddresses(addresses) {
+		eventBalance, storageBalance := orZero(eventBalances[address]), orZero(storageBalances[address])
+		if eventBalance.Cmp(storageBalance) != 0 {
+			mismatches = append(mismatches, TokenBalanceMismatch{
+				Address:        address,
+				EventBalance:   eventBalance.String(),
+				StorageBalance: storageBalance.String(),
+			})
+		}
+	}
+	return mismatches, nil
+}
+
+func orZero(n *big.Int) *big.Int {
+	if n == nil {
+		return new(big.Int)
+	}
+	return n
+}
+
+func sortedAddresses(set map[string]bool) []string {
+	addresses := make([]string, 0, len(set))
+	for address := range set {
+		addresses = append(addresses, address)
+	}
+	sort.Strings(addresses)
+	return addresses
+}
@@ -0,0 +1,105 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+)
+
+func setupBalanceTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(&TokenBalanceChange{}, &TokenBalance{}))
+	return db
+}
+
+var balanceTestStart = time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+
+func eventChange(id, address string, ledger uint32, delta int64) *TokenBalanceChange {
+	change := &TokenBalanceChange{
+		ID: id, ContractID: "CA", Address: address, Ledger: ledger,
+		ClosedAt: balanceTestStart.Add(time.Duration(ledger) * 5 * time.Second), Source: BalanceSourceEvent,
+	}
+	change.Delta.SetInt64(delta)
+	return change
+}
+
+func storageChange(id, address string, ledger uint32, txIndex int32, balance int64) *TokenBalanceChange {
+	change := eventChange(id, address, ledger, 0)
+	change.Source = BalanceSourceStorage
+	change.TxIndex = txIndex
+	change.Balance = &util.Int128{}
+	change.Balance.SetInt64(balance)
+	return change
+}
+
+func tokenBalance(contractID, address string, balance int64) *TokenBalance {
+	row := &TokenBalance{ContractID: contractID, Address: address}
+	row.Balance.SetInt64(balance)
+	return row
+}
+
+func TestTokenBalanceAt(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	inserted, err := InsertTokenBalanceChanges(db, []*TokenBalanceChange{
+		storageChange("s1", "GA", 100, 1, 50),
+		storageChange("s2", "GA", 105, 1, 80),
+		storageChange("s3", "GA", 105, 2, 70),
+		storageChange("s4", "GA", 110, EvictionTxIndex, 0),
+		storageChange("s5", "GB", 105, 1, 999),
+	})
+	require.NoError(t, err)
+	assert.Equal(t, int64(5), inserted)
+	// Stored changes are not replaced
+	inserted, err = InsertTokenBalanceChanges(db, []*TokenBalanceChange{storageChange("s1", "GA", 100, 1, 1)})
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), inserted)
+
+	for ledger, want := range map[uint32]string{99: "0", 100: "50", 104: "50", 105: "70", 109: "70", 110: "0"} {
+		balance, err := GetTokenBalanceAt(db, "CA", "GA", ledger)
+		require.NoError(t, err)
+		assert.Equal(t, want, balance, "ledger %d", ledger)
+	}
+
+	balance, err := GetTokenBalanceAtTime(db, "CA", "GA", balanceTestStart.Add(106*5*time.Second))
+	require.NoError(t, err)
+	assert.Equal(t, "70", balance)
+
+	previous, err := GetPreviousStorageBalance(db, "CA", "GA", 105, 2, 0)
+	require.NoError(t, err)
+	assert.Equal(t, big.NewInt(80), previous)
+}
+
+func TestReconcileTokenBalances(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	_, err := InsertTokenBalanceChanges(db, []*TokenBalanceChange{
+		eventChange("e1", "GA", 100, 100), // mint
+		eventChange("e2", "GA", 101, -30), // transfer
+		eventChange("e3", "GB", 101, 30),
+		eventChange("e4", "GC", 102, 5), // Storage never written
+		storageChange("s1", "GA", 101, 1, 999),
+	})
+	require.NoError(t, err)
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GA", 70)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GB", 31)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GD", 1)))
+
+	mismatches, err := ReconcileTokenBalances(db, "CA")
+	require.NoError(t, err)
+	assert.Equal(t, []TokenBalanceMismatch{
+		{Address: "GB", EventBalance: "30", StorageBalance: "31"},
+		{Address: "GC", EventBalance: "5", StorageBalance: "0"},
+		{Address: "GD", EventBalance: "0", StorageBalance: "1"},
+	}, mismatches)
+
+	mismatches, err = ReconcileTokenBalances(db, "CB")
+	require.NoError(t, err)
+	assert.Empty(t, mismatches)
+}
@@ -14,6 +14,9 @@ type TokenMetadata struct {
 	Decimal      uint32    `gorm:"column:decimal"`
 	Name         string    `gorm:"column:name"`
 	Symbol       string    `gorm:"column:symbol"`
+	StellarAsset bool      `gorm:"column:stellar_asset;not null;default:false"`        // Stellar Asset Contract of a classic asset
+	AssetCode    string    `gorm:"column:asset_code;index:idx_token_metadata_asset"`   // Classic asset code ("native" for lumens)
+	AssetIssuer  string    `gorm:"column:asset_issuer;index:idx_token_metadata_asset"` // Classic asset issuer ("" for lumens)
 	CreatedAt    time.Time `gorm:"column:created_at"`
 	UpdatedAt    time.Time `gorm:"column:updated_at"`
 }
@@ -24,11 +27,38 @@ func (TokenMetadata) TableName() string {
 
 func UpsertTokenMetadata(db *gorm.DB, metadata *TokenMetadata) error {
 	return db.Clauses(clause.OnConflict{
-		Columns:   []clause.Column{{Name: "contract_id"}},
-		DoUpdates: clause.AssignmentColumns([]string{"admin_address", "decimal", "name", "symbol", "updated_at"}),
+		Columns: []clause.Column{{Name: "contract_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"admin_address", "decimal", "name", "symbol", "stellar_asset", "asset_code", "asset_issuer", "updated_at",
+		}),
 	}).Create(metadata).Error
 }
 
+// UpsertStellarAsset records a contract as the Stellar Asset Contract of a classic asset, keeping
+// the admin already known for it
+func UpsertStellarAsset(db *gorm.DB, metadata *TokenMetadata) error {
+	return db.Clauses(clause.OnConflict{
+		Columns: []clause.Column{{Name: "contract_id"}},
+		DoUpdates: clause.AssignmentColumns([]string{
+			"decimal", "name", "symbol", "stellar_asset", "asset_code", "asset_issuer", "updated_at",
+		}),
+	}).Create(metadata).Error
+}
+
+// GetStellarAssetContract returns the Stellar Asset Contract of a classic asset (nil if none was
+// seen); use code "native" and an empty issuer for lumens
+func GetStellarAssetContract(db *gorm.DB, code, issuer string) (*TokenMetadata, error) {
+	var metadata TokenMetadata
+	err := db.Where("stellar_asset = ? AND asset_code = ? AND asset_issuer = ?", true, code, issuer).First(&metadata).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &metadata, nil
+}
+
 func NewTokenMetadata(data []byte) (*TokenMetadata, error) {
 	var tm TokenMetadata
 	err := json.Unmarshal(data, &tm)
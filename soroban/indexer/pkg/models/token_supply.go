@@ -0,0 +1,87 @@
+package models
+
+import (
+	"math/big"
+	"time"
+
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+)
+
+// TokenSupply is the total supply of a token at the end of a ledger that minted, burned or clawed
+// back some of it; the supply at any other ledger is the one of the last row at or before it
+type TokenSupply struct {
+	ContractID string      `gorm:"column:contract_id;primaryKey"`
+	Ledger     uint32      `gorm:"column:ledger;primaryKey;autoIncrement:false"`
+	Supply     util.Int128 `gorm:"column:supply;type:numeric"`
+	Minted     util.Int128 `gorm:"column:minted;type:numeric"` // Minted during the ledger
+	Burned     util.Int128 `gorm:"column:burned;type:numeric"` // Burned or clawed back during the ledger
+	UpdatedAt  time.Time   `gorm:"column:updated_at"`
+}
+
+func (TokenSupply) TableName() string {
+	return "token_supply"
+}
+
+// ApplyTokenSupplyChange adds delta (negative for burns and clawbacks) to the supply of a token
+// at ledger and at every later ledger already recorded, so ledgers may be processed out of order
+func ApplyTokenSupplyChange(db *gorm.DB, contractID string, ledger uint32, delta *big.Int) error {
+	var row TokenSupply
+	err := db.Where("contract_id = ? AND ledger = ?", contractID, ledger).First(&row).Error
+	if err == gorm.ErrRecordNotFound {
+		row = TokenSupply{ContractID: contractID, Ledger: ledger}
+		// Nothing precedes ledger 0 (and ledger-1 would wrap around to the latest supply)
+		if ledger > 0 {
+			previous, err := GetTokenSupplyAt(db, contractID, ledger-1)
+			if err != nil {
+				return err
+			}
+			if previous != nil {
+				row.Supply.Set(&previous.Supply.Int)
+			}
+		}
+	} else if err != nil {
+		return err
+	}
+
+	row.Supply.Add(&row.Supply.Int, delta)
+	if delta.Sign() >= 0 {
+		row.Minted.Add(&row.Minted.Int, delta)
+	} else {
+		row.Burned.Sub(&row.Burned.Int, delta)
+	}
+	row.UpdatedAt = time.Now()
+	if err := db.Save(&row).Error; err != nil {
+		return err
+	}
+
+	var amount util.Int128
+	amount.Set(delta)
+	return db.Model(&TokenSupply{}).
+		Where("contract_id = ? AND ledger > ?", contractID, ledger).
+		Update("supply", gorm.Expr("supply + ?", amount)).Error
+}
+
+// GetTokenSupply returns the current supply of a token (nil if it never minted or burned)
+func GetTokenSupply(db *gorm.DB, contractID string) (*TokenSupply, error) {
+	return latestTokenSupply(db.Where("contract_id = ?", contractID))
+}
+
+// GetTokenSupplyAt returns the supply of a token at the end of ledger (nil if it had not minted
+// or burned yet)
+func GetTokenSupplyAt(db *gorm.DB, contractID string, ledger uint32) (*TokenSupply, error) {
+	return latestTokenSupply(db.Where("contract_id = ? AND ledger <= ?", contractID, ledger))
+}
+
+func latestTokenSupply(query *gorm.DB) (*TokenSupply, error) {
+	var supply TokenSupply
+	err := query.Order("ledger DESC").First(&supply).Error
+	if err == gorm.ErrRecordNotFound {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &supply, nil
+}
@@ -0,0 +1,91 @@
+package models
+
+import (
+	"math/big"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestApplyTokenSupplyChange(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&TokenSupply{}))
+
+	supply, err := GetTokenSupply(db, "CA")
+	require.NoError(t, err)
+	assert.Nil(t, supply)
+
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 100, big.NewInt(1000)))
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 105, big.NewInt(-300)))
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 105, big.NewInt(20)))
+	// Ledgers processed out of order shift every later supply
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 102, big.NewInt(5)))
+	require.NoError(t, ApplyTokenSupplyChange(db, "CB", 101, big.NewInt(7)))
+
+	for ledger, want := range map[uint32]string{100: "1000", 101: "1000", 102: "1005", 104: "1005", 105: "725", 200: "725"} {
+		supply, err := GetTokenSupplyAt(db, "CA", ledger)
+		require.NoError(t, err)
+		require.NotNil(t, supply, "ledger %d", ledger)
+		assert.Equal(t, want, supply.Supply.String(), "ledger %d", ledger)
+	}
+	supply, err = GetTokenSupplyAt(db, "CA", 99)
+	require.NoError(t, err)
+	assert.Nil(t, supply)
+
+	supply, err = GetTokenSupply(db, "CA")
+	require.NoError(t, err)
+	require.NotNil(t, supply)
+	assert.Equal(t, uint32(105), supply.Ledger)
+	assert.Equal(t, "725", supply.Supply.String())
+	assert.Equal(t, "20", supply.Minted.String())
+	assert.Equal(t, "300", supply.Burned.String())
+}
+
+func TestApplyTokenSupplyChange_LedgerZero(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	require.NoError(t, db.AutoMigrate(&TokenSupply{}))
+
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 100, big.NewInt(1000)))
+	// Nothing precedes ledger 0, so it doesn't start from the latest supply
+	require.NoError(t, ApplyTokenSupplyChange(db, "CA", 0, big.NewInt(5)))
+
+	supply, err := GetTokenSupplyAt(db, "CA", 0)
+	require.NoError(t, err)
+	require.NotNil(t, supply)
+	assert.Equal(t, "5", supply.Supply.String())
+
+	supply, err = GetTokenSupplyAt(db, "CA", 100)
+	require.NoError(t, err)
+	require.NotNil(t, supply)
+	assert.Equal(t, "1005", supply.Supply.String())
+}
+
+func TestTokenHolders(t *testing.T) {
+	db := setupBalanceTestDB(t)
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GA", 70)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GB", 300)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GC", 0)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CA", "GD", 70)))
+	require.NoError(t, UpsertTokenBalance(db, tokenBalance("CB", "GA", 5)))
+
+	// Balances compare as numbers, not strings
+	holders, err := GetTopHolders(db, "CA", 2)
+	require.NoError(t, err)
+	require.Len(t, holders, 2)
+	assert.Equal(t, "GB", holders[0].Address)
+	assert.Equal(t, "300", holders[0].Balance.String())
+	assert.Equal(t, "GA", holders[1].Address)
+
+	count, err := CountTokenHolders(db, "CA")
+	require.NoError(t, err)
+	assert.Equal(t, int64(3), count)
+
+	circulating, err := GetCirculatingSupply(db, "CA")
+	require.NoError(t, err)
+	assert.Equal(t, "440", circulating)
+
+	circulating, err = GetCirculatingSupply(db, "CX")
+	require.NoError(t, err)
+	assert.Equal(t, "0", circulating)
+}
@@ -17,30 +17,56 @@ type Transaction struct {
 	ApplicationOrder *int32              `gorm:"column:application_order"`
 	FeeBump          *bool               `gorm:"column:fee_bump"`
 	FeeBumpInfo      *util.FeeBumpInfo   `gorm:"column:fee_bump_info;type:jsonb"`
-	Fee              *int32              `gorm:"column:fee"`
-	FeeCharged       *int32              `gorm:"column:fee_charged"`
+	Fee              *int64              `gorm:"column:fee"`
+	FeeCharged       *int64              `gorm:"column:fee_charged"`
 	Sequence         *int64              `gorm:"column:sequence"`
 	SourceAccount    *string             `gorm:"column:source_account"`
-	MuxedAccountId   *int64              `gorm:"column:muxed_account_id"`
+	MuxedAccountId   *string             `gorm:"column:muxed_account_id;type:numeric"` // Unsigned 64-bit, as a decimal
 	Memo             *util.TypeItem      `gorm:"column:memo;type:jsonb"`
 	Preconditions    *util.Preconditions `gorm:"column:preconditions;type:jsonb"`
 	Signatures       *util.Signatures    `gorm:"column:signatures;type:jsonb"`
-	CreatedAt        time.Time           `gorm:"column:created_at"`
-	UpdatedAt        time.Time           `gorm:"column:updated_at"`
+	ResultCode       *string             `gorm:"column:result_code"`             // e.g. txSUCCESS, txFAILED, txBAD_SEQ
+	InnerResultCode  *string             `gorm:"column:inner_result_code"`       // Inner transaction's, for fee bumps
+	ReturnValue      JSONB               `gorm:"column:return_value;type:jsonb"` // Decoded Soroban return value
+	ReturnValueXdr   *string             `gorm:"column:return_value_xdr"`
+
+	// Soroban resources declared in SorobanTransactionData
+	ResourceFee   *int64  `gorm:"column:resource_fee"`
+	Instructions  *uint32 `gorm:"column:instructions"`
+	ReadBytes     *uint32 `gorm:"column:read_bytes"`
+	WriteBytes    *uint32 `gorm:"column:write_bytes"`
+	ReadOnlyKeys  JSONB   `gorm:"column:read_only_keys;type:jsonb"`  // Base64 LedgerKey XDR
+	ReadWriteKeys JSONB   `gorm:"column:read_write_keys;type:jsonb"` // Base64 LedgerKey XDR
+
+	// Soroban resource fees charged, from the meta extension
+	NonRefundableResourceFee *int64 `gorm:"column:non_refundable_resource_fee"`
+	RefundableResourceFee    *int64 `gorm:"column:refundable_resource_fee"` // Includes the rent fee
+	RentFee                  *int64 `gorm:"column:rent_fee"`
+	FeeRefunded              *int64 `gorm:"column:fee_refunded"`
+
+	CreatedAt time.Time `gorm:"column:created_at"`
+	UpdatedAt time.Time `gorm:"column:updated_at"`
 }
 
 func (Transaction) TableName() string {
 	return "transactions"
 }
 
+// transactionUpdateColumns are the columns a transaction upsert overwrites
+var transactionUpdateColumns = []string{
+	"status", "ledger", "ledger_created_at", "application_order", "fee_bump",
+	"fee_bump_info", "fee", "fee_charged", "sequence", "source_account",
+	"muxed_account_id", "memo", "preconditions", "signatures", "result_code",
+	"inner_result_code", "return_value", "return_value_xdr", "resource_fee",
+	"instructions", "read_bytes", "write_bytes", "read_only_keys", "read_write_keys",
+	"non_refundable_resource_fee", "refundable_resource_fee", "rent_fee", "fee_refunded",
+	"updated_at",
+}
+
 func UpsertTransaction(db *gorm.DB, tx *Transaction) error {
 	return db.Clauses(clause.OnConflict{
-		Columns: []clause.Column{{Name: "id"}},
-		DoUpdates: clause.AssignmentColumns([]string{
-			"status", "ledger", "ledger_created_at", "application_order", "fee_bump",
-			"fee_bump_info", "fee", "fee_charged", "sequence", "source_account",
-			"muxed_account_id", "memo", "preconditions", "signatures", "updated_at",
-		}),
+		Columns:   []clause.Column{{Name: "id"}},
+		DoUpdates: clause.AssignmentColumns(transactionUpdateColumns),
 	}).Create(tx).Error
 }
 
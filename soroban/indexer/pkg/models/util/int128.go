@@ -23,6 +23,10 @@ func (i *Int128) Scan(value interface{}) error {
 		strValue = string(t)
 	case string:
 		strValue = t
+	case int64:
+		// SQLite returns small numeric values as integers
+		i.Int.SetInt64(t)
+		return nil
 	default:
 		return fmt.Errorf("unsupported type for Int128: %T", value)
 	}
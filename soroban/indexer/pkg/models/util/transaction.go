@@ -97,11 +97,15 @@ func (p *Preconditions) Scan(value interface{}) error {
 	return json.Unmarshal(bytes, p)
 }
 
-// FeeBumpInfo represents fee bump transaction information
+// FeeBumpInfo represents fee bump transaction information: the outer fee and fee source, and the
+// inner transaction's hash, fee and signatures
 type FeeBumpInfo struct {
-	Fee            int32   `json:"fee"`
-	SourceAccount  *string `json:"source_account,omitempty"`
-	MuxedAccountId *int64  `json:"muxed_account_id,omitempty"`
+	Fee             int64       `json:"fee"`
+	SourceAccount   *string     `json:"source_account,omitempty"`
+	MuxedAccountId  *string     `json:"muxed_account_id,omitempty"`
+	InnerHash       string      `json:"inner_hash,omitempty"`
+	InnerFee        int64       `json:"inner_fee,omitempty"`
+	InnerSignatures *Signatures `json:"inner_signatures,omitempty"`
 }
 
 // Value implements driver.Valuer for FeeBumpInfo
@@ -13,9 +13,45 @@ import (
 	"github.com/blockroma/soroban-indexer/pkg/models"
 )
 
+// ContractDataChange is a contract data entry written or removed in a ledger
+// Removed entries only carry their key and RemovedLedger
+type ContractDataChange struct {
+	Type    string // models.ContractDataCreated, ...
+	OpIndex int32  // Operation that made the change (-1 for transaction level changes and evictions)
+	Entry   *models.ContractDataEntry
+	// Entry as it was before the change, from the state change preceding it in the meta
+	// Nil for created and restored entries and for evictions, which carry no prior state
+	Previous *models.ContractDataEntry
+}
+
+// ContractDataChanges holds the contract storage changes of a transaction, in apply order
+type ContractDataChanges struct {
+	Changes []ContractDataChange
+	TTLs    map[string]uint32 // Ledger each entry lives until, by key hash, for TTLs set or extended
+}
+
 // ExtractContractDataFromMeta extracts contract data entries from transaction metadata
 // This is the passive indexing approach - it extracts contract storage changes from successful transactions
+// Only entries that were written are returned; see ExtractContractDataChanges for removals
 func ExtractContractDataFromMeta(txHash string, metaXdr string) ([]*models.ContractDataEntry, error) {
+	changes, err := ExtractContractDataChanges(0, metaXdr)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []*models.ContractDataEntry
+	for _, change := range changes.Changes {
+		if change.Type != models.ContractDataRemoved {
+			entries = append(entries, change.Entry)
+		}
+	}
+	return entries, nil
+}
+
+// ExtractContractDataChanges extracts the contract storage changes of a transaction applied in
+// ledger: written entries, removed entries (tombstoned with ledger) and TTL updates
+// Written entries get ExpirationLedgerSeq from a TTL changed in the same transaction
+func ExtractContractDataChanges(ledger uint32, metaXdr string) (*ContractDataChanges, error) {
 	// Decode metadata XDR
 	data, err := base64.StdEncoding.DecodeString(metaXdr)
 	if err != nil {
@@ -27,74 +63,125 @@ func ExtractContractDataFromMeta(txHash string, metaXdr string) ([]*models.Contr
 		return nil, fmt.Errorf("unmarshal transaction meta: %w", err)
 	}
 
-	var entries []*models.ContractDataEntry
+	result := &ContractDataChanges{TTLs: make(map[string]uint32)}
 
-	// Check if this is a Soroban transaction (V3 or V4)
-	isSoroban := meta.V4 != nil || meta.V3 != nil
-	if !isSoroban {
-		// Not a Soroban transaction (classic Stellar)
-		return entries, nil
-	}
-
-	// Get the changes from either V3 or V4
+	// Operation changes are applied before the transaction level changes after them
+	var groups []xdr.LedgerEntryChanges
 	var txChangesAfter xdr.LedgerEntryChanges
-	var operations []interface{} // Can be []OperationMeta or []OperationMetaV2
-
 	if meta.V4 != nil {
-		txChangesAfter = meta.V4.TxChangesAfter
 		for _, op := range meta.V4.Operations {
-			operations = append(operations, op)
+			groups = append(groups, op.Changes)
 		}
+		txChangesAfter = meta.V4.TxChangesAfter
 	} else if meta.V3 != nil {
-		txChangesAfter = meta.V3.TxChangesAfter
 		for _, op := range meta.V3.Operations {
-			operations = append(operations, op)
+			groups = append(groups, op.Changes)
 		}
+		txChangesAfter = meta.V3.TxChangesAfter
+	} else {
+		// Not a Soroban transaction (classic Stellar)
+		return result, nil
 	}
+	groups = append(groups, txChangesAfter)
 
-	// Process ledger entry changes after the transaction
-	// This includes created, updated, and restored contract data entries
-	for _, change := range txChangesAfter {
-		ledgerEntry, ok := getLedgerEntryFromChange(change)
-		if !ok {
-			continue
+	for i, changes := range groups {
+		opIndex := int32(i)
+		if i == len(groups)-1 {
+			opIndex = -1
 		}
 
-		entry, err := extractContractDataFromLedgerEntry(ledgerEntry)
-		if err != nil {
-			continue
-		}
-		if entry != nil {
-			entries = append(entries, entry)
-		}
-	}
+		// Each update or removal is preceded by the state of the entry before it
+		states := make(map[string]*models.ContractDataEntry)
+		for _, change := range changes {
+			if state, ok := change.GetState(); ok {
+				if entry, err := extractContractDataFromLedgerEntry(state); err == nil && entry != nil {
+					states[entry.KeyHash] = entry
+				}
+				continue
+			}
 
-	// Also process operation-level changes
-	for _, op := range operations {
-		var changes xdr.LedgerEntryChanges
-		if v3Op, ok := op.(xdr.OperationMeta); ok {
-			changes = v3Op.Changes
-		} else if v4Op, ok := op.(xdr.OperationMetaV2); ok {
-			changes = v4Op.Changes
-		}
+			if change.Type == xdr.LedgerEntryChangeTypeLedgerEntryRemoved {
+				if removed, ok := change.GetRemoved(); ok {
+					if entry := removedContractData(ledger, removed); entry != nil {
+						result.Changes = append(result.Changes, ContractDataChange{
+							Type: models.ContractDataRemoved, OpIndex: opIndex, Entry: entry, Previous: states[entry.KeyHash],
+						})
+					}
+				}
+				continue
+			}
 
-		for _, change := range changes {
 			ledgerEntry, ok := getLedgerEntryFromChange(change)
 			if !ok {
 				continue
 			}
 
+			if ttl, ok := ledgerEntry.Data.GetTtl(); ok {
+				result.TTLs[hex.EncodeToString(ttl.KeyHash[:])] = uint32(ttl.LiveUntilLedgerSeq)
+				continue
+			}
+
 			entry, err := extractContractDataFromLedgerEntry(ledgerEntry)
-			if err != nil {
+			if err != nil || entry == nil {
 				continue
 			}
-			if entry != nil {
-				entries = append(entries, entry)
+			entry.LastModifiedLedger = ledger
+			written := ContractDataChange{Type: contractDataChangeType(change.Type), OpIndex: opIndex, Entry: entry}
+			if change.Type == xdr.LedgerEntryChangeTypeLedgerEntryUpdated {
+				written.Previous = states[entry.KeyHash]
 			}
+			result.Changes = append(result.Changes, written)
 		}
 	}
 
-	return entries, nil
+	for _, change := range result.Changes {
+		if liveUntil, ok := result.TTLs[change.Entry.KeyHash]; ok && change.Type != models.ContractDataRemoved {
+			change.Entry.ExpirationLedgerSeq = liveUntil
+		}
+	}
+
+	return result, nil
+}
+
+// ContractDataRemovals returns removal changes for the contract data keys among keys
+// Used for the entries a ledger evicted, which don't appear in any transaction's meta
+func ContractDataRemovals(ledger uint32, keys []xdr.LedgerKey) []ContractDataChange {
+	var changes []ContractDataChange
+	for _, key := range keys {
+		if entry := removedContractData(ledger, key); entry != nil {
+			changes = append(changes, ContractDataChange{Type: models.ContractDataRemoved, OpIndex: -1, Entry: entry})
+		}
+	}
+	return changes
+}
+
+// contractDataChangeType names the change that wrote an entry
+func contractDataChangeType(t xdr.LedgerEntryChangeType) string {
+	switch t {
+	case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+		return models.ContractDataCreated
+	case xdr.LedgerEntryChangeTypeLedgerEntryRestored:
+		return models.ContractDataRestored
+	default:
+		return models.ContractDataUpdated
+	}
+}
+
+// removedContractData builds the tombstone of a removed contract data key
+// Returns nil for other ledger entry types and non-contract addresses
+func removedContractData(ledger uint32, key xdr.LedgerKey) *models.ContractDataEntry {
+	contractData, ok := key.GetContractData()
+	if !ok {
+		return nil
+	}
+
+	entry, err := contractDataKeyFields(key, contractData.Contract, contractData.Key, contractData.Durability)
+	if err != nil || entry == nil {
+		return nil
+	}
+	entry.RemovedLedger = &ledger
+	entry.LastModifiedLedger = ledger
+	return entry
 }
 
 // getLedgerEntryFromChange extracts a ledger entry from a ledger entry change
@@ -117,7 +204,7 @@ func getLedgerEntryFromChange(change xdr.LedgerEntryChange) (xdr.LedgerEntry, bo
 		// We want the new state (created/updated), not the old state
 		return xdr.LedgerEntry{}, false
 	case xdr.LedgerEntryChangeTypeLedgerEntryRemoved:
-		// Removed entries are not useful for indexing
+		// Removed changes only carry the key, see removedContractData
 		return xdr.LedgerEntry{}, false
 	}
 	return xdr.LedgerEntry{}, false
@@ -135,13 +222,43 @@ func extractContractDataFromLedgerEntry(entry xdr.LedgerEntry) (*models.Contract
 		return nil, fmt.Errorf("contract data is nil")
 	}
 
+	// Create ledger key hash for unique identification
+	ledgerKey, err := entry.LedgerKey()
+	if err != nil {
+		return nil, fmt.Errorf("get ledger key: %w", err)
+	}
+
+	result, err := contractDataKeyFields(ledgerKey, contractData.Contract, contractData.Key, contractData.Durability)
+	if err != nil || result == nil {
+		return nil, err
+	}
+
+	valInterface := ScValToInterface(contractData.Val)
+	valBytes, err := json.Marshal(valInterface)
+	if err != nil {
+		return nil, fmt.Errorf("marshal val to JSON: %w", err)
+	}
+
+	valXDR, err := xdr.MarshalBase64(contractData.Val)
+	if err != nil {
+		return nil, fmt.Errorf("marshal value xdr: %w", err)
+	}
+
+	result.Val = models.JSONB(valBytes)
+	result.ValXdr = valXDR
+	return result, nil
+}
+
+// contractDataKeyFields fills the key fields of a contract data entry: key hash, contract ID,
+// key and durability. Returns nil when the entry is not owned by a contract
+func contractDataKeyFields(ledgerKey xdr.LedgerKey, contract xdr.ScAddress, key xdr.ScVal, durability xdr.ContractDataDurability) (*models.ContractDataEntry, error) {
 	// Extract contract ID from the contract address
-	if contractData.Contract.Type != xdr.ScAddressTypeScAddressTypeContract {
+	if contract.Type != xdr.ScAddressTypeScAddressTypeContract {
 		// Not a contract address (might be an account address)
 		return nil, nil
 	}
 
-	contractIDHash := contractData.Contract.ContractId
+	contractIDHash := contract.ContractId
 	if contractIDHash == nil {
 		return nil, fmt.Errorf("contract ID is nil")
 	}
@@ -152,26 +269,13 @@ func extractContractDataFromLedgerEntry(entry xdr.LedgerEntry) (*models.Contract
 		return nil, fmt.Errorf("encode contract ID: %w", err)
 	}
 
-	// Convert key and value to interface{} first
-	keyInterface := ScValToInterface(contractData.Key)
-	valInterface := ScValToInterface(contractData.Val)
-
 	// Marshal to JSON bytes for JSONB storage
-	keyBytes, err := json.Marshal(keyInterface)
+	keyBytes, err := json.Marshal(ScValToInterface(key))
 	if err != nil {
 		return nil, fmt.Errorf("marshal key to JSON: %w", err)
 	}
-	valBytes, err := json.Marshal(valInterface)
-	if err != nil {
-		return nil, fmt.Errorf("marshal val to JSON: %w", err)
-	}
-
-	// Create ledger key hash for unique identification
-	ledgerKey, err := entry.LedgerKey()
-	if err != nil {
-		return nil, fmt.Errorf("get ledger key: %w", err)
-	}
 
+	// The key hash matches the one TTL entries refer to
 	bin, err := ledgerKey.MarshalBinary()
 	if err != nil {
 		return nil, fmt.Errorf("marshal ledger key: %w", err)
@@ -180,21 +284,16 @@ func extractContractDataFromLedgerEntry(entry xdr.LedgerEntry) (*models.Contract
 	keyHash := sha256.Sum256(bin)
 	hexKey := hex.EncodeToString(keyHash[:])
 
-	// Marshal key and value to base64 XDR
-	keyXDR, err := xdr.MarshalBase64(contractData.Key)
+	// Marshal key to base64 XDR
+	keyXDR, err := xdr.MarshalBase64(key)
 	if err != nil {
 		return nil, fmt.Errorf("marshal key xdr: %w", err)
 	}
 
-	valXDR, err := xdr.MarshalBase64(contractData.Val)
-	if err != nil {
-		return nil, fmt.Errorf("marshal value xdr: %w", err)
-	}
-
 	// Determine durability
-	durability := "persistent"
-	if contractData.Durability == xdr.ContractDataDurabilityTemporary {
-		durability = "temporary"
+	durabilityName := "persistent"
+	if durability == xdr.ContractDataDurabilityTemporary {
+		durabilityName = "temporary"
 	}
 
 	return &models.ContractDataEntry{
@@ -202,9 +301,7 @@ func extractContractDataFromLedgerEntry(entry xdr.LedgerEntry) (*models.Contract
 		ContractID: contractID,
 		Key:        models.JSONB(keyBytes),
 		KeyXdr:     keyXDR,
-		Val:        models.JSONB(valBytes),
-		ValXdr:     valXDR,
-		Durability: durability,
+		Durability: durabilityName,
 	}, nil
 }
 
@@ -1,11 +1,14 @@
 package parser
 
 import (
+	"crypto/sha256"
 	"encoding/base64"
 	"testing"
 
 	"github.com/stellar/go/strkey"
 	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
 )
 
 // TestExtractContractDataFromMeta tests basic contract data extraction
@@ -427,3 +430,101 @@ func getMapKeys[V any](m map[string]V) []string {
 	}
 	return keys
 }
+
+// TestExtractContractDataChanges tests removals, change types and TTLs in V4 metadata
+func TestExtractContractDataChanges(t *testing.T) {
+	contractIDBytes := xdr.ContractId{}
+	for i := range contractIDBytes {
+		contractIDBytes[i] = byte(i + 20)
+	}
+	contract := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractIDBytes}
+
+	entry := func(name string, amount uint64) xdr.LedgerEntry {
+		return xdr.LedgerEntry{
+			Data: xdr.LedgerEntryData{
+				Type: xdr.LedgerEntryTypeContractData,
+				ContractData: &xdr.ContractDataEntry{
+					Contract:   contract,
+					Key:        xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: scSymbolPtr(name)},
+					Val:        xdr.ScVal{Type: xdr.ScValTypeScvU128, U128: &xdr.UInt128Parts{Lo: xdr.Uint64(amount)}},
+					Durability: xdr.ContractDataDurabilityTemporary,
+				},
+			},
+		}
+	}
+
+	created := entry("Created", 1)
+	updated := entry("Updated", 2)
+	removed := entry("Removed", 0)
+	removedKey, err := removed.LedgerKey()
+	if err != nil {
+		t.Fatalf("LedgerKey() error = %v", err)
+	}
+
+	// TTL of the created entry, keyed by the hash of its ledger key
+	createdKey, _ := created.LedgerKey()
+	createdBin, _ := createdKey.MarshalBinary()
+	ttl := xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeTtl,
+			Ttl:  &xdr.TtlEntry{KeyHash: xdr.Hash(sha256.Sum256(createdBin)), LiveUntilLedgerSeq: 12345},
+		},
+	}
+
+	transactionMeta := xdr.TransactionMeta{
+		V: 4,
+		V4: &xdr.TransactionMetaV4{
+			Operations: []xdr.OperationMetaV2{{
+				Changes: xdr.LedgerEntryChanges{
+					{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &created},
+					{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &ttl},
+					{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &removedKey},
+				},
+			}},
+			TxChangesAfter: xdr.LedgerEntryChanges{
+				{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &updated},
+			},
+		},
+	}
+
+	metaXdrBytes, _ := transactionMeta.MarshalBinary()
+	metaXdr := base64.StdEncoding.EncodeToString(metaXdrBytes)
+
+	changes, err := ExtractContractDataChanges(900, metaXdr)
+	if err != nil {
+		t.Fatalf("ExtractContractDataChanges() error = %v", err)
+	}
+
+	if len(changes.Changes) != 3 {
+		t.Fatalf("Expected 3 changes, got %d", len(changes.Changes))
+	}
+	wantTypes := []string{models.ContractDataCreated, models.ContractDataRemoved, models.ContractDataUpdated}
+	wantOps := []int32{0, 0, -1}
+	for i, change := range changes.Changes {
+		if change.Type != wantTypes[i] || change.OpIndex != wantOps[i] {
+			t.Errorf("Change %d = %v in op %d, want %v in op %d", i, change.Type, change.OpIndex, wantTypes[i], wantOps[i])
+		}
+	}
+
+	createdEntry := changes.Changes[0].Entry
+	if createdEntry.ExpirationLedgerSeq != 12345 || changes.TTLs[createdEntry.KeyHash] != 12345 {
+		t.Errorf("ExpirationLedgerSeq = %d, want 12345 from the TTL", createdEntry.ExpirationLedgerSeq)
+	}
+	if createdEntry.Durability != "temporary" || createdEntry.Removed() {
+		t.Errorf("Created entry = %+v", createdEntry)
+	}
+
+	tombstone := changes.Changes[1].Entry
+	if tombstone.RemovedLedger == nil || *tombstone.RemovedLedger != 900 {
+		t.Errorf("RemovedLedger = %v, want 900", tombstone.RemovedLedger)
+	}
+	if tombstone.KeyXdr == "" || len(tombstone.Val) != 0 {
+		t.Errorf("Removed entry should carry its key only: %+v", tombstone)
+	}
+
+	// The written entries only
+	entries, err := ExtractContractDataFromMeta("test-tx-hash", metaXdr)
+	if err != nil || len(entries) != 2 {
+		t.Errorf("ExtractContractDataFromMeta() = %d entries, %v; want 2", len(entries), err)
+	}
+}
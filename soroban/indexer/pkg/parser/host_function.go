@@ -0,0 +1,145 @@
+package parser
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+
+	"github.com/stellar/go/xdr"
+)
+
+// hostFunctionDetails decodes an InvokeHostFunction operation into details: the contract, function
+// and arguments it invokes, the contract it creates or the WASM it uploads, and its authorization
+// entries
+func hostFunctionDetails(op xdr.InvokeHostFunctionOp, details map[string]interface{}) {
+	fn := op.HostFunction
+	details["host_function"] = fn.Type.String()
+
+	switch fn.Type {
+	case xdr.HostFunctionTypeHostFunctionTypeInvokeContract:
+		if fn.InvokeContract != nil {
+			for k, v := range invokeContractDetails(*fn.InvokeContract) {
+				details[k] = v
+			}
+		}
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContract:
+		if fn.CreateContract != nil {
+			for k, v := range createContractDetails(fn.CreateContract.ContractIdPreimage, fn.CreateContract.Executable, nil) {
+				details[k] = v
+			}
+		}
+	case xdr.HostFunctionTypeHostFunctionTypeCreateContractV2:
+		if fn.CreateContractV2 != nil {
+			args := fn.CreateContractV2
+			for k, v := range createContractDetails(args.ContractIdPreimage, args.Executable, args.ConstructorArgs) {
+				details[k] = v
+			}
+		}
+	case xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm:
+		if fn.Wasm != nil {
+			hash := sha256.Sum256(*fn.Wasm)
+			details["wasm_hash"] = hex.EncodeToString(hash[:])
+			details["wasm_size"] = len(*fn.Wasm)
+		}
+	}
+
+	auth := make([]map[string]interface{}, 0, len(op.Auth))
+	for _, entry := range op.Auth {
+		auth = append(auth, authorizationEntryDetails(entry))
+	}
+	details["auth"] = auth
+}
+
+// invokeContractDetails returns the contract, function symbol and decoded arguments of a call
+func invokeContractDetails(args xdr.InvokeContractArgs) map[string]interface{} {
+	contractID, _ := args.ContractAddress.String()
+	return map[string]interface{}{
+		"contract_id": contractID,
+		"function":    string(args.FunctionName),
+		"args":        scValsToInterface(args.Args),
+	}
+}
+
+// createContractDetails returns the deployer (or wrapped asset), salt, executable and constructor
+// arguments of a contract creation
+func createContractDetails(preimage xdr.ContractIdPreimage, executable xdr.ContractExecutable, constructorArgs []xdr.ScVal) map[string]interface{} {
+	details := map[string]interface{}{
+		"executable": contractExecutableDetails(executable),
+	}
+
+	switch preimage.Type {
+	case xdr.ContractIdPreimageTypeContractIdPreimageFromAddress:
+		if preimage.FromAddress != nil {
+			deployer, _ := preimage.FromAddress.Address.String()
+			details["deployer"] = deployer
+			details["salt"] = hex.EncodeToString(preimage.FromAddress.Salt[:])
+		}
+	case xdr.ContractIdPreimageTypeContractIdPreimageFromAsset:
+		if preimage.FromAsset != nil {
+			details["asset"] = assetToMap(*preimage.FromAsset)
+		}
+	}
+
+	if constructorArgs != nil {
+		details["constructor_args"] = scValsToInterface(constructorArgs)
+	}
+	return details
+}
+
+// contractExecutableDetails returns the type of a contract executable and, for Wasm contracts, the
+// hex wasm_hash of its code
+func contractExecutableDetails(executable xdr.ContractExecutable) map[string]interface{} {
+	result := map[string]interface{}{"type": executable.Type.String()}
+	if executable.WasmHash != nil {
+		result["wasm_hash"] = hex.EncodeToString(executable.WasmHash[:])
+	}
+	return result
+}
+
+// authorizationEntryDetails returns the credentials of an authorization entry (for address
+// credentials: the signer, nonce and signature expiration) and the invocation tree it authorizes
+func authorizationEntryDetails(entry xdr.SorobanAuthorizationEntry) map[string]interface{} {
+	result := map[string]interface{}{
+		"credentials_type": entry.Credentials.Type.String(),
+		"root_invocation":  authorizedInvocationDetails(entry.RootInvocation),
+	}
+
+	if creds := entry.Credentials.Address; creds != nil {
+		address, _ := creds.Address.String()
+		result["address"] = address
+		result["nonce"] = int64(creds.Nonce)
+		result["signature_expiration_ledger"] = uint32(creds.SignatureExpirationLedger)
+	}
+	return result
+}
+
+func authorizedInvocationDetails(invocation xdr.SorobanAuthorizedInvocation) map[string]interface{} {
+	fn := invocation.Function
+	var result map[string]interface{}
+	switch {
+	case fn.ContractFn != nil:
+		result = invokeContractDetails(*fn.ContractFn)
+	case fn.CreateContractHostFn != nil:
+		result = createContractDetails(fn.CreateContractHostFn.ContractIdPreimage, fn.CreateContractHostFn.Executable, nil)
+	case fn.CreateContractV2HostFn != nil:
+		args := fn.CreateContractV2HostFn
+		result = createContractDetails(args.ContractIdPreimage, args.Executable, args.ConstructorArgs)
+	default:
+		result = map[string]interface{}{}
+	}
+	result["type"] = fn.Type.String()
+
+	subInvocations := make([]map[string]interface{}, 0, len(invocation.SubInvocations))
+	for _, sub := range invocation.SubInvocations {
+		subInvocations = append(subInvocations, authorizedInvocationDetails(sub))
+	}
+	result["sub_invocations"] = subInvocations
+	return result
+}
+
+func scValsToInterface(vals []xdr.ScVal) []interface{} {
+	result := make([]interface{}, len(vals))
+	for i, val := range vals {
+		result[i] = ScValToInterface(val)
+	}
+	return result
+}
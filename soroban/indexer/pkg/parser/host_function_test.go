@@ -0,0 +1,180 @@
+package parser
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"testing"
+
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+)
+
+func testContractAddress(t *testing.T, seed byte) (xdr.ScAddress, string) {
+	var id xdr.ContractId
+	for i := range id {
+		id[i] = seed
+	}
+	address, err := strkey.Encode(strkey.VersionByteContract, id[:])
+	if err != nil {
+		t.Fatalf("Encode() error = %v", err)
+	}
+	return xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &id}, address
+}
+
+func TestParseOperationDetails_InvokeContract(t *testing.T) {
+	contract, contractID := testContractAddress(t, 1)
+	token, tokenID := testContractAddress(t, 2)
+	signer := xdr.MustAddress(testIssuer)
+	signerAddress := xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &signer}
+	amount := xdr.Uint32(42)
+
+	swap := xdr.InvokeContractArgs{
+		ContractAddress: contract,
+		FunctionName:    "swap",
+		Args: []xdr.ScVal{
+			{Type: xdr.ScValTypeScvAddress, Address: &signerAddress},
+			{Type: xdr.ScValTypeScvU32, U32: &amount},
+		},
+	}
+	transfer := xdr.InvokeContractArgs{ContractAddress: token, FunctionName: "transfer"}
+
+	body := xdr.OperationBody{
+		Type: xdr.OperationTypeInvokeHostFunction,
+		InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+			HostFunction: xdr.HostFunction{Type: xdr.HostFunctionTypeHostFunctionTypeInvokeContract, InvokeContract: &swap},
+			Auth: []xdr.SorobanAuthorizationEntry{
+				{
+					Credentials: xdr.SorobanCredentials{
+						Type: xdr.SorobanCredentialsTypeSorobanCredentialsAddress,
+						Address: &xdr.SorobanAddressCredentials{
+							Address:                   signerAddress,
+							Nonce:                     -7,
+							SignatureExpirationLedger: 1000,
+							Signature:                 xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+						},
+					},
+					RootInvocation: xdr.SorobanAuthorizedInvocation{
+						Function: xdr.SorobanAuthorizedFunction{Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn, ContractFn: &swap},
+						SubInvocations: []xdr.SorobanAuthorizedInvocation{{
+							Function: xdr.SorobanAuthorizedFunction{Type: xdr.SorobanAuthorizedFunctionTypeSorobanAuthorizedFunctionTypeContractFn, ContractFn: &transfer},
+						}},
+					},
+				},
+				{Credentials: xdr.SorobanCredentials{Type: xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount}},
+			},
+		},
+	}
+
+	details, err := parseOperationDetails(body)
+	if err != nil {
+		t.Fatalf("parseOperationDetails() error = %v", err)
+	}
+
+	// Decode through JSON, the way the details are stored
+	detailsJSON, err := json.Marshal(details)
+	if err != nil {
+		t.Fatalf("Marshal() error = %v", err)
+	}
+	var stored struct {
+		HostFunction string        `json:"host_function"`
+		ContractID   string        `json:"contract_id"`
+		Function     string        `json:"function"`
+		Args         []interface{} `json:"args"`
+		Auth         []struct {
+			CredentialsType           string `json:"credentials_type"`
+			Address                   string `json:"address"`
+			Nonce                     int64  `json:"nonce"`
+			SignatureExpirationLedger uint32 `json:"signature_expiration_ledger"`
+			RootInvocation            struct {
+				ContractID     string `json:"contract_id"`
+				Function       string `json:"function"`
+				SubInvocations []struct {
+					ContractID string `json:"contract_id"`
+					Function   string `json:"function"`
+				} `json:"sub_invocations"`
+			} `json:"root_invocation"`
+		} `json:"auth"`
+	}
+	if err := json.Unmarshal(detailsJSON, &stored); err != nil {
+		t.Fatalf("Unmarshal() error = %v", err)
+	}
+
+	if stored.HostFunction != xdr.HostFunctionTypeHostFunctionTypeInvokeContract.String() {
+		t.Errorf("host_function = %v", stored.HostFunction)
+	}
+	if stored.ContractID != contractID || stored.Function != "swap" {
+		t.Errorf("Invocation = %s.%s, want %s.swap", stored.ContractID, stored.Function, contractID)
+	}
+	if len(stored.Args) != 2 || stored.Args[0] != testIssuer || stored.Args[1] != float64(42) {
+		t.Errorf("args = %v, want [%s 42]", stored.Args, testIssuer)
+	}
+
+	if len(stored.Auth) != 2 {
+		t.Fatalf("auth entries = %d, want 2", len(stored.Auth))
+	}
+	auth := stored.Auth[0]
+	if auth.CredentialsType != xdr.SorobanCredentialsTypeSorobanCredentialsAddress.String() || auth.Address != testIssuer {
+		t.Errorf("credentials = %s %s, want address credentials of %s", auth.CredentialsType, auth.Address, testIssuer)
+	}
+	if auth.Nonce != -7 || auth.SignatureExpirationLedger != 1000 {
+		t.Errorf("nonce/expiration = %d/%d, want -7/1000", auth.Nonce, auth.SignatureExpirationLedger)
+	}
+	if auth.RootInvocation.Function != "swap" || len(auth.RootInvocation.SubInvocations) != 1 {
+		t.Fatalf("root_invocation = %+v, want swap with one sub-invocation", auth.RootInvocation)
+	}
+	if sub := auth.RootInvocation.SubInvocations[0]; sub.ContractID != tokenID || sub.Function != "transfer" {
+		t.Errorf("sub_invocation = %+v, want %s.transfer", sub, tokenID)
+	}
+	if stored.Auth[1].CredentialsType != xdr.SorobanCredentialsTypeSorobanCredentialsSourceAccount.String() || stored.Auth[1].Address != "" {
+		t.Errorf("source account credentials = %+v", stored.Auth[1])
+	}
+}
+
+func TestParseOperationDetails_CreateContract(t *testing.T) {
+	deployer := xdr.MustAddress(testIssuer)
+	wasmHash := xdr.Hash{0xab}
+	salt := xdr.Uint256{0x01, 0x02}
+	admin := xdr.ScSymbol("admin")
+
+	body := xdr.OperationBody{
+		Type: xdr.OperationTypeInvokeHostFunction,
+		InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+			HostFunction: xdr.HostFunction{
+				Type: xdr.HostFunctionTypeHostFunctionTypeCreateContractV2,
+				CreateContractV2: &xdr.CreateContractArgsV2{
+					ContractIdPreimage: xdr.ContractIdPreimage{
+						Type: xdr.ContractIdPreimageTypeContractIdPreimageFromAddress,
+						FromAddress: &xdr.ContractIdPreimageFromAddress{
+							Address: xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &deployer},
+							Salt:    salt,
+						},
+					},
+					Executable:      xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash},
+					ConstructorArgs: []xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &admin}},
+				},
+			},
+		},
+	}
+
+	details, err := parseOperationDetails(body)
+	if err != nil {
+		t.Fatalf("parseOperationDetails() error = %v", err)
+	}
+
+	if details["deployer"] != testIssuer {
+		t.Errorf("deployer = %v, want %s", details["deployer"], testIssuer)
+	}
+	if details["salt"] != hex.EncodeToString(salt[:]) {
+		t.Errorf("salt = %v", details["salt"])
+	}
+	executable, _ := details["executable"].(map[string]interface{})
+	if executable["type"] != xdr.ContractExecutableTypeContractExecutableWasm.String() || executable["wasm_hash"] != hex.EncodeToString(wasmHash[:]) {
+		t.Errorf("executable = %v", executable)
+	}
+	if args, _ := details["constructor_args"].([]interface{}); len(args) != 1 || args[0] != admin {
+		t.Errorf("constructor_args = %v, want [admin]", details["constructor_args"])
+	}
+	if auth, _ := details["auth"].([]map[string]interface{}); auth == nil || len(auth) != 0 {
+		t.Errorf("auth = %v, want an empty list", details["auth"])
+	}
+}
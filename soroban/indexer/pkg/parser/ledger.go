@@ -0,0 +1,115 @@
+package parser
+
+import (
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// ParseLedger extracts the ledger header, transaction/operation counts and any Soroban fee
+// settings upgraded in the ledger from a LedgerCloseMeta
+func ParseLedger(lcm xdr.LedgerCloseMeta) (*models.Ledger, error) {
+	header := lcm.LedgerHeaderHistoryEntry().Header
+	hash := lcm.LedgerHash()
+	previousHash := lcm.PreviousLedgerHash()
+
+	ledger := &models.Ledger{
+		Sequence:         lcm.LedgerSequence(),
+		Hash:             hex.EncodeToString(hash[:]),
+		PreviousHash:     hex.EncodeToString(previousHash[:]),
+		ClosedAt:         lcm.ClosedAt(),
+		ProtocolVersion:  uint32(header.LedgerVersion),
+		BaseFee:          uint32(header.BaseFee),
+		BaseReserve:      uint32(header.BaseReserve),
+		TransactionCount: lcm.CountTransactions(),
+	}
+
+	for _, envelope := range lcm.TransactionEnvelopes() {
+		ledger.OperationCount += len(envelope.Operations())
+	}
+
+	for i := 0; i < ledger.TransactionCount; i++ {
+		if !lcm.TransactionResultPair(i).Result.Successful() {
+			ledger.FailedTransactionCount++
+		}
+	}
+
+	if feeWrite1KB, ok := sorobanFeeWrite1KB(lcm); ok {
+		ledger.SorobanFeeWrite1KB = &feeWrite1KB
+	}
+
+	upgrades := sorobanFeeUpgrades(lcm.UpgradesProcessing())
+	if len(upgrades) > 0 {
+		encoded, err := json.Marshal(upgrades)
+		if err != nil {
+			return nil, fmt.Errorf("marshal soroban fee upgrades: %w", err)
+		}
+		ledger.SorobanFeeUpgrades = models.JSONB(encoded)
+	}
+
+	return ledger, nil
+}
+
+// sorobanFeeWrite1KB returns the write fee per 1KB reported in the meta extension
+func sorobanFeeWrite1KB(lcm xdr.LedgerCloseMeta) (int64, bool) {
+	var ext xdr.LedgerCloseMetaExt
+	switch lcm.V {
+	case 1:
+		ext = lcm.MustV1().Ext
+	case 2:
+		ext = lcm.MustV2().Ext
+	default:
+		return 0, false
+	}
+	if ext.V != 1 || ext.V1 == nil {
+		return 0, false
+	}
+	return int64(ext.V1.SorobanFeeWrite1Kb), true
+}
+
+// sorobanFeeUpgrades collects the fee-related config settings written by network upgrades
+func sorobanFeeUpgrades(upgrades []xdr.UpgradeEntryMeta) map[string]int64 {
+	settings := make(map[string]int64)
+
+	for _, upgrade := range upgrades {
+		for _, change := range upgrade.Changes {
+			var entry *xdr.LedgerEntry
+			switch change.Type {
+			case xdr.LedgerEntryChangeTypeLedgerEntryCreated:
+				entry = change.Created
+			case xdr.LedgerEntryChangeTypeLedgerEntryUpdated:
+				entry = change.Updated
+			default:
+				continue
+			}
+			if entry == nil || entry.Data.Type != xdr.LedgerEntryTypeConfigSetting {
+				continue
+			}
+
+			setting := entry.Data.MustConfigSetting()
+			switch setting.ConfigSettingId {
+			case xdr.ConfigSettingIdConfigSettingContractComputeV0:
+				settings["fee_rate_per_instructions_increment"] = int64(setting.ContractCompute.FeeRatePerInstructionsIncrement)
+			case xdr.ConfigSettingIdConfigSettingContractLedgerCostV0:
+				cost := setting.ContractLedgerCost
+				settings["fee_disk_read_ledger_entry"] = int64(cost.FeeDiskReadLedgerEntry)
+				settings["fee_write_ledger_entry"] = int64(cost.FeeWriteLedgerEntry)
+				settings["fee_disk_read_1kb"] = int64(cost.FeeDiskRead1Kb)
+				settings["rent_fee_1kb_soroban_state_size_low"] = int64(cost.RentFee1KbSorobanStateSizeLow)
+				settings["rent_fee_1kb_soroban_state_size_high"] = int64(cost.RentFee1KbSorobanStateSizeHigh)
+			case xdr.ConfigSettingIdConfigSettingContractHistoricalDataV0:
+				settings["fee_historical_1kb"] = int64(setting.ContractHistoricalData.FeeHistorical1Kb)
+			case xdr.ConfigSettingIdConfigSettingContractEventsV0:
+				settings["fee_contract_events_1kb"] = int64(setting.ContractEvents.FeeContractEvents1Kb)
+			case xdr.ConfigSettingIdConfigSettingContractBandwidthV0:
+				settings["fee_tx_size_1kb"] = int64(setting.ContractBandwidth.FeeTxSize1Kb)
+			}
+		}
+	}
+
+	return settings
+}
@@ -0,0 +1,125 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// createTestLedgerMeta builds a V1 LedgerCloseMeta with one failed two-operation transaction
+// and a network upgrade that changes the contract events fee
+func createTestLedgerMeta() xdr.LedgerCloseMeta {
+	source := xdr.MustAddress("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H")
+	envelope := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: source.ToMuxedAccount(),
+				Fee:           200,
+				SeqNum:        1,
+				Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+				Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+				Operations: []xdr.Operation{
+					{Body: xdr.OperationBody{Type: xdr.OperationTypeInflation}},
+					{Body: xdr.OperationBody{Type: xdr.OperationTypeInflation}},
+				},
+			},
+		},
+	}
+
+	configEntry := xdr.LedgerEntry{
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeConfigSetting,
+			ConfigSetting: &xdr.ConfigSettingEntry{
+				ConfigSettingId: xdr.ConfigSettingIdConfigSettingContractEventsV0,
+				ContractEvents:  &xdr.ConfigSettingContractEventsV0{TxMaxContractEventsSizeBytes: 8198, FeeContractEvents1Kb: 10000},
+			},
+		},
+	}
+
+	var hash, previousHash xdr.Hash
+	hash[0] = 0xab
+	previousHash[0] = 0xcd
+
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			Ext: xdr.LedgerCloseMetaExt{V: 1, V1: &xdr.LedgerCloseMetaExtV1{SorobanFeeWrite1Kb: 3500}},
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Hash: hash,
+				Header: xdr.LedgerHeader{
+					LedgerVersion:      22,
+					PreviousLedgerHash: previousHash,
+					ScpValue:           xdr.StellarValue{CloseTime: 1704067200},
+					LedgerSeq:          100,
+					BaseFee:            100,
+					BaseReserve:        5000000,
+				},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &xdr.TransactionSetV1{
+					Phases: []xdr.TransactionPhase{{
+						V: 0,
+						V0Components: &[]xdr.TxSetComponent{{
+							Type:                  xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+							TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{Txs: []xdr.TransactionEnvelope{envelope}},
+						}},
+					}},
+				},
+			},
+			TxProcessing: []xdr.TransactionResultMeta{{
+				Result: xdr.TransactionResultPair{
+					Result: xdr.TransactionResult{
+						FeeCharged: 200,
+						Result:     xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxFailed, Results: &[]xdr.OperationResult{}},
+					},
+				},
+				TxApplyProcessing: xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}},
+			}},
+			UpgradesProcessing: []xdr.UpgradeEntryMeta{{
+				Upgrade: xdr.LedgerUpgrade{Type: xdr.LedgerUpgradeTypeLedgerUpgradeConfig, NewConfig: &xdr.ConfigUpgradeSetKey{}},
+				Changes: xdr.LedgerEntryChanges{
+					{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &configEntry},
+				},
+			}},
+		},
+	}
+}
+
+func TestParseLedger(t *testing.T) {
+	ledger, err := ParseLedger(createTestLedgerMeta())
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(100), ledger.Sequence)
+	assert.Equal(t, "ab00000000000000000000000000000000000000000000000000000000000000", ledger.Hash)
+	assert.Equal(t, "cd00000000000000000000000000000000000000000000000000000000000000", ledger.PreviousHash)
+	assert.Equal(t, int64(1704067200), ledger.ClosedAt.Unix())
+	assert.Equal(t, uint32(22), ledger.ProtocolVersion)
+	assert.Equal(t, uint32(100), ledger.BaseFee)
+	assert.Equal(t, uint32(5000000), ledger.BaseReserve)
+	assert.Equal(t, 1, ledger.TransactionCount)
+	assert.Equal(t, 1, ledger.FailedTransactionCount)
+	assert.Equal(t, 2, ledger.OperationCount)
+
+	require.NotNil(t, ledger.SorobanFeeWrite1KB)
+	assert.Equal(t, int64(3500), *ledger.SorobanFeeWrite1KB)
+
+	var upgrades map[string]int64
+	require.NoError(t, json.Unmarshal(ledger.SorobanFeeUpgrades, &upgrades))
+	assert.Equal(t, map[string]int64{"fee_contract_events_1kb": 10000}, upgrades)
+}
+
+func TestParseLedger_NoUpgrades(t *testing.T) {
+	lcm := createTestLedgerMeta()
+	lcm.V1.UpgradesProcessing = nil
+	lcm.V1.Ext = xdr.LedgerCloseMetaExt{V: 0}
+
+	ledger, err := ParseLedger(lcm)
+	require.NoError(t, err)
+	assert.Nil(t, ledger.SorobanFeeWrite1KB)
+	assert.Nil(t, ledger.SorobanFeeUpgrades)
+}
@@ -75,12 +75,12 @@ func parseOperation(txHash string, index int, op xdr.Operation, txSourceAccount
 	opID := fmt.Sprintf("%s-%d", txHash, index)
 
 	// Get operation source account (use transaction source if not specified)
-	var sourceAccount string
+	// Muxed sources are stored as their base account and ID
+	source := txSourceAccount
 	if op.SourceAccount != nil {
-		sourceAccount = op.SourceAccount.Address()
-	} else {
-		sourceAccount = txSourceAccount.Address()
+		source = *op.SourceAccount
 	}
+	sourceAccount, muxedID := muxedAccount(source)
 
 	// Get operation type
 	opType := op.Body.Type.String()
@@ -101,6 +101,7 @@ func parseOperation(txHash string, index int, op xdr.Operation, txSourceAccount
 		TxHash:           txHash,
 		OperationIndex:   int32(index),
 		SourceAccount:    sourceAccount,
+		SourceMuxedID:    muxedID,
 		OperationType:    opType,
 		OperationDetails: detailsJSON,
 	}, nil
@@ -328,8 +329,7 @@ func parseOperationDetails(body xdr.OperationBody) (map[string]interface{}, erro
 
 	case xdr.OperationTypeInvokeHostFunction:
 		if op, ok := body.GetInvokeHostFunctionOp(); ok {
-			details["host_function"] = op.HostFunction.Type.String()
-			// Don't include full function details - too complex
+			hostFunctionDetails(op, details)
 		}
 
 	case xdr.OperationTypeExtendFootprintTtl:
@@ -347,3 +347,43 @@ func TestPriceToMap(t *testing.T) {
 		t.Errorf("priceToMap() d = %v, want 2", result["d"])
 	}
 }
+
+func TestParseOperations_MuxedSource(t *testing.T) {
+	txSource, err := xdr.MuxedAccountFromAccountId("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", 7)
+	if err != nil {
+		t.Fatalf("MuxedAccountFromAccountId() error = %v", err)
+	}
+	opSource := xdr.MustAddress("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF")
+	opSourceMuxed := opSource.ToMuxedAccount()
+	bump := xdr.Operation{Body: xdr.OperationBody{Type: xdr.OperationTypeBumpSequence, BumpSequenceOp: &xdr.BumpSequenceOp{BumpTo: 1}}}
+	withSource := bump
+	withSource.SourceAccount = &opSourceMuxed
+
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{Tx: xdr.Transaction{
+			SourceAccount: txSource,
+			Fee:           100,
+			Operations:    []xdr.Operation{bump, withSource},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	operations, err := ParseOperations("muxed-tx", envelope)
+	if err != nil {
+		t.Fatalf("ParseOperations() error = %v", err)
+	}
+	if len(operations) != 2 {
+		t.Fatalf("ParseOperations() returned %d operations, want 2", len(operations))
+	}
+
+	// The muxed transaction source is stored as its base account and ID
+	if op := operations[0]; op.SourceAccount != "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H" || op.SourceMuxedID == nil || *op.SourceMuxedID != "7" {
+		t.Errorf("Operation 0 source = %s (%v), want base account with ID 7", op.SourceAccount, op.SourceMuxedID)
+	}
+	if op := operations[1]; op.SourceAccount != opSource.Address() || op.SourceMuxedID != nil {
+		t.Errorf("Operation 1 source = %s (%v), want %s", op.SourceAccount, op.SourceMuxedID, opSource.Address())
+	}
+}
@@ -1,10 +1,13 @@
 package parser
 
 import (
+	"bytes"
 	"encoding/base64"
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
+	"math/big"
+	"strconv"
 	"strings"
 	"time"
 
@@ -103,11 +106,13 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 	}
 
 	sourceAccount := ""
-	fee := int32(0)
+	var muxedAccountID *string
+	fee := int64(0)
 	sequence := int64(0)
 	var memo *util.TypeItem
 	var preconditions *util.Preconditions
 	var signatures *util.Signatures
+	var feeBumpInfo *util.FeeBumpInfo
 
 	// Extract details from envelope
 	switch envelope.Type {
@@ -115,7 +120,7 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 		if v0, ok := envelope.GetV0(); ok {
 			// V0 uses Ed25519 public key, convert to address
 			sourceAccount, _ = strkey.Encode(strkey.VersionByteAccountID, v0.Tx.SourceAccountEd25519[:])
-			fee = int32(v0.Tx.Fee)
+			fee = int64(v0.Tx.Fee)
 			sequence = int64(v0.Tx.SeqNum)
 
 			// Parse memo
@@ -129,8 +134,8 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 		}
 	case xdr.EnvelopeTypeEnvelopeTypeTx:
 		if v1, ok := envelope.GetV1(); ok {
-			sourceAccount = v1.Tx.SourceAccount.ToAccountId().Address()
-			fee = int32(v1.Tx.Fee)
+			sourceAccount, muxedAccountID = muxedAccount(v1.Tx.SourceAccount)
+			fee = int64(v1.Tx.Fee)
 			sequence = int64(v1.Tx.SeqNum)
 
 			// Parse memo
@@ -144,15 +149,25 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 		}
 	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
 		if fb, ok := envelope.GetFeeBump(); ok {
-			sourceAccount = fb.Tx.FeeSource.ToAccountId().Address()
-			fee = int32(fb.Tx.Fee)
+			// The outer fee and fee source go in the fee bump info; the transaction itself is the
+			// inner one, so its source account is the inner source
+			fee = int64(fb.Tx.Fee)
+			feeSource, feeSourceID := muxedAccount(fb.Tx.FeeSource)
+			feeBumpInfo = &util.FeeBumpInfo{
+				Fee:            fee,
+				SourceAccount:  &feeSource,
+				MuxedAccountId: feeSourceID,
+			}
 
 			// Fee bump doesn't have sequence, memo, or preconditions
 			// Get from inner tx
 			if innerV1, ok := fb.Tx.InnerTx.GetV1(); ok {
+				sourceAccount, muxedAccountID = muxedAccount(innerV1.Tx.SourceAccount)
 				sequence = int64(innerV1.Tx.SeqNum)
 				memo = parseMemo(innerV1.Tx.Memo)
 				preconditions = parsePreconditionsV1(innerV1.Tx.Cond)
+				feeBumpInfo.InnerFee = int64(innerV1.Tx.Fee)
+				feeBumpInfo.InnerSignatures = parseSignatures(innerV1.Signatures)
 			}
 
 			// Parse signatures from fee bump envelope
@@ -162,22 +177,31 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 
 	// Decode result to get fee charged
 	result, err := decodeResult(tx.ResultXdr)
-	feeCharged := int32(0)
+	feeCharged := int64(0)
 	if err == nil {
-		feeCharged = int32(result.FeeCharged)
+		feeCharged = int64(result.FeeCharged)
+
+		// The inner transaction's hash is only in its result
+		if pair, ok := result.Result.GetInnerResultPair(); ok && feeBumpInfo != nil {
+			feeBumpInfo.InnerHash = hex.EncodeToString(pair.TransactionHash[:])
+		}
 	}
+	feeBump := feeBumpInfo != nil
 
 	ledger := tx.Ledger
 	appOrder := tx.ApplicationOrder
 	ledgerCreatedAt := tx.LedgerCloseTime
 
-	return &models.Transaction{
+	transaction := &models.Transaction{
 		ID:               txHash,  // Use the provided hash parameter, not tx.Hash
 		Status:           tx.Status,
 		Ledger:           &ledger,
 		LedgerCreatedAt:  &ledgerCreatedAt,
 		ApplicationOrder: &appOrder,
+		FeeBump:          &feeBump,
+		FeeBumpInfo:      feeBumpInfo,
 		SourceAccount:    &sourceAccount,
+		MuxedAccountId:   muxedAccountID,
 		Fee:              &fee,
 		FeeCharged:       &feeCharged,
 		Sequence:         &sequence,
@@ -186,7 +210,30 @@ func ParseTransactionWithHash(tx client.Transaction, txHash string) (*models.Tra
 		Signatures:       signatures,
 		CreatedAt:        time.Unix(tx.LedgerCloseTime, 0),
 		UpdatedAt:        time.Now(),
-	}, nil
+	}
+	if result != nil {
+		parseResultCodes(transaction, result)
+	}
+	parseSorobanResources(transaction, sorobanData(envelope))
+	parseSorobanMeta(transaction, tx.ResultMetaXdr)
+
+	return transaction, nil
+}
+
+// muxedAccount returns the base account of a (possibly muxed) account and its muxed ID, which is
+// nil for plain G... accounts. The ID is an unsigned 64-bit integer, so it is kept as a decimal
+// string rather than wrapping IDs of 2^63 and above
+func muxedAccount(account xdr.MuxedAccount) (string, *string) {
+	address := account.ToAccountId().Address()
+	if account.Type != xdr.CryptoKeyTypeKeyTypeMuxedEd25519 {
+		return address, nil
+	}
+	id, err := account.GetId()
+	if err != nil {
+		return address, nil
+	}
+	muxedID := strconv.FormatUint(uint64(id), 10)
+	return address, &muxedID
 }
 
 // parseScVal decodes base64 XDR ScVal to Go interface
@@ -223,27 +270,30 @@ func ScValToInterface(val xdr.ScVal) interface{} {
 		return val.MustU64()
 	case xdr.ScValTypeScvI64:
 		return val.MustI64()
+	case xdr.ScValTypeScvTimepoint:
+		return uint64(val.MustTimepoint())
+	case xdr.ScValTypeScvDuration:
+		return uint64(val.MustDuration())
 	case xdr.ScValTypeScvU128:
-		parts := val.MustU128()
-		// Convert to big integer string for consistency with soroban-rpc-indexer
-		hi := uint64(parts.Hi)
-		lo := uint64(parts.Lo)
-		result := (hi << 64) | lo
-		return fmt.Sprintf("%d", result)
+		// Big integers are encoded as decimal strings for consistency with soroban-rpc-indexer
+		return Uint128ToBigInt(val.MustU128()).String()
 	case xdr.ScValTypeScvI128:
-		parts := val.MustI128()
-		// Convert to big integer string for consistency with soroban-rpc-indexer
-		hi := int64(parts.Hi)
-		lo := uint64(parts.Lo)
-		// Note: This is simplified; proper int128 handling needs big.Int for large values
-		if hi >= 0 {
-			result := (uint64(hi) << 64) | lo
-			return fmt.Sprintf("%d", result)
-		} else {
-			// For negative numbers, use two's complement
-			result := (uint64(hi) << 64) | lo
-			return fmt.Sprintf("%d", int64(result))
+		return Int128ToBigInt(val.MustI128()).String()
+	case xdr.ScValTypeScvU256:
+		return Uint256ToBigInt(val.MustU256()).String()
+	case xdr.ScValTypeScvI256:
+		return Int256ToBigInt(val.MustI256()).String()
+	case xdr.ScValTypeScvError:
+		scErr := val.MustError()
+		result := map[string]interface{}{
+			"type": scErr.Type.String(),
+		}
+		if scErr.ContractCode != nil {
+			result["code"] = uint32(*scErr.ContractCode)
+		} else if scErr.Code != nil {
+			result["code"] = scErr.Code.String()
 		}
+		return result
 	case xdr.ScValTypeScvBytes:
 		return val.MustBytes()
 	case xdr.ScValTypeScvString:
@@ -313,6 +363,52 @@ func ScValToInterface(val xdr.ScVal) interface{} {
 	}
 }
 
+// Uint128ToBigInt converts unsigned 128-bit parts to an exact big.Int
+func Uint128ToBigInt(parts xdr.UInt128Parts) *big.Int {
+	return joinUint64Words(uint64(parts.Hi), uint64(parts.Lo))
+}
+
+// Int128ToBigInt converts signed 128-bit parts (two's complement) to an exact big.Int
+func Int128ToBigInt(parts xdr.Int128Parts) *big.Int {
+	result := joinUint64Words(uint64(parts.Hi), uint64(parts.Lo))
+	if parts.Hi < 0 {
+		result.Sub(result, new(big.Int).Lsh(big.NewInt(1), 128))
+	}
+	return result
+}
+
+// Uint256ToBigInt converts unsigned 256-bit parts to an exact big.Int
+func Uint256ToBigInt(parts xdr.UInt256Parts) *big.Int {
+	return joinUint64Words(uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo))
+}
+
+// Int256ToBigInt converts signed 256-bit parts (two's complement) to an exact big.Int
+func Int256ToBigInt(parts xdr.Int256Parts) *big.Int {
+	result := joinUint64Words(uint64(parts.HiHi), uint64(parts.HiLo), uint64(parts.LoHi), uint64(parts.LoLo))
+	if parts.HiHi < 0 {
+		result.Sub(result, new(big.Int).Lsh(big.NewInt(1), 256))
+	}
+	return result
+}
+
+// joinUint64Words concatenates big-endian 64-bit words into a non-negative big.Int
+func joinUint64Words(words ...uint64) *big.Int {
+	result := new(big.Int)
+	for _, w := range words {
+		result.Lsh(result, 64)
+		result.Or(result, new(big.Int).SetUint64(w))
+	}
+	return result
+}
+
+// DecodeJSON unmarshals stored JSONB into v, keeping numbers as json.Number
+// so that 64-bit integers survive the round trip without float64 rounding
+func DecodeJSON(data []byte, v interface{}) error {
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	decoder.UseNumber()
+	return decoder.Decode(v)
+}
+
 // decodeEnvelope decodes base64 XDR transaction envelope
 func decodeEnvelope(xdrStr string) (*xdr.TransactionEnvelope, error) {
 	data, err := base64.StdEncoding.DecodeString(xdrStr)
@@ -1,6 +1,8 @@
 package parser
 
 import (
+	"encoding/hex"
+	"math"
 	"testing"
 
 	"github.com/stellar/go/xdr"
@@ -95,11 +97,122 @@ func TestScValToInterface(t *testing.T) {
 	}
 }
 
+func TestScValToInterface_BigIntegers(t *testing.T) {
+	maxU64 := xdr.Uint64(^uint64(0))
+	tp := xdr.TimePoint(1700000000)
+	dur := xdr.Duration(3600)
+	contractCode := xdr.Uint32(7)
+	budgetCode := xdr.ScErrorCodeScecExceededLimit
+
+	tests := []struct {
+		name     string
+		scVal    xdr.ScVal
+		expected interface{}
+	}{
+		{
+			name:     "u128 above 2^64",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvU128, U128: &xdr.UInt128Parts{Hi: 1, Lo: 5}},
+			expected: "18446744073709551621",
+		},
+		{
+			name:     "u128 max",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvU128, U128: &xdr.UInt128Parts{Hi: maxU64, Lo: maxU64}},
+			expected: "340282366920938463463374607431768211455",
+		},
+		{
+			name:     "i128 positive above 2^64",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 2, Lo: 0}},
+			expected: "36893488147419103232",
+		},
+		{
+			name:     "i128 minus one",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: -1, Lo: maxU64}},
+			expected: "-1",
+		},
+		{
+			name:     "i128 min",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: xdr.Int64(-1 << 63), Lo: 0}},
+			expected: "-170141183460469231731687303715884105728",
+		},
+		{
+			name:     "u256",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvU256, U256: &xdr.UInt256Parts{HiHi: 1, HiLo: 0, LoHi: 0, LoLo: 1}},
+			expected: "6277101735386680763835789423207666416102355444464034512897",
+		},
+		{
+			name:     "i256 minus two",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvI256, I256: &xdr.Int256Parts{HiHi: -1, HiLo: maxU64, LoHi: maxU64, LoLo: maxU64 - 1}},
+			expected: "-2",
+		},
+		{
+			name:     "timepoint",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvTimepoint, Timepoint: &tp},
+			expected: uint64(1700000000),
+		},
+		{
+			name:     "duration",
+			scVal:    xdr.ScVal{Type: xdr.ScValTypeScvDuration, Duration: &dur},
+			expected: uint64(3600),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := ScValToInterface(tt.scVal)
+			if result != tt.expected {
+				t.Errorf("ScValToInterface() = %v (%T), want %v (%T)", result, result, tt.expected, tt.expected)
+			}
+		})
+	}
+
+	t.Run("contract error", func(t *testing.T) {
+		scVal := xdr.ScVal{Type: xdr.ScValTypeScvError, Error: &xdr.ScError{Type: xdr.ScErrorTypeSceContract, ContractCode: &contractCode}}
+		result, ok := ScValToInterface(scVal).(map[string]interface{})
+		if !ok {
+			t.Fatalf("ScValToInterface() = %T, want map", result)
+		}
+		if result["type"] != "ScErrorTypeSceContract" || result["code"] != uint32(7) {
+			t.Errorf("ScValToInterface() = %v, want ScErrorTypeSceContract code 7", result)
+		}
+	})
+
+	t.Run("host error", func(t *testing.T) {
+		scVal := xdr.ScVal{Type: xdr.ScValTypeScvError, Error: &xdr.ScError{Type: xdr.ScErrorTypeSceBudget, Code: &budgetCode}}
+		result, ok := ScValToInterface(scVal).(map[string]interface{})
+		if !ok {
+			t.Fatalf("ScValToInterface() = %T, want map", result)
+		}
+		if result["type"] != "ScErrorTypeSceBudget" || result["code"] != "ScErrorCodeScecExceededLimit" {
+			t.Errorf("ScValToInterface() = %v, want ScErrorTypeSceBudget ScErrorCodeScecExceededLimit", result)
+		}
+	})
+}
+
 func TestScValToInterface_Vec(t *testing.T) {
 	// Skip complex XDR structure test - covered by integration tests
 	t.Skip("XDR Vec structure complex, covered by integration tests")
 }
 
+func TestScValToInterface_ContractInstance(t *testing.T) {
+	wasmHash := xdr.Hash{0x01, 0x02}
+	instance := xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &xdr.ScContractInstance{
+		Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableWasm, WasmHash: &wasmHash},
+	}}
+
+	result, ok := ScValToInterface(instance).(map[string]interface{})
+	if !ok {
+		t.Fatalf("ScValToInterface() = %T, want map", ScValToInterface(instance))
+	}
+	// Stored contract instances keep the wasmHash key; only operation details use wasm_hash
+	executable, _ := result["executable"].(map[string]interface{})
+	if executable["type"] != xdr.ContractExecutableTypeContractExecutableWasm.String() || executable["wasmHash"] != hex.EncodeToString(wasmHash[:]) {
+		t.Errorf("executable = %v", executable)
+	}
+	if details := contractExecutableDetails(instance.Instance.Executable); details["wasm_hash"] != executable["wasmHash"] {
+		t.Errorf("operation details executable = %v, want wasm_hash %v", details, executable["wasmHash"])
+	}
+}
+
 func TestParseTransaction(t *testing.T) {
 	// Skip complex XDR transaction test - covered by integration tests
 	t.Skip("XDR transaction structure complex, covered by integration tests")
@@ -586,3 +699,152 @@ func createSignature() xdr.DecoratedSignature {
 		Signature: xdr.Signature(sig),
 	}
 }
+
+func TestParseTransaction_FeeBump(t *testing.T) {
+	innerSource, err := xdr.MuxedAccountFromAccountId("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", 42)
+	if err != nil {
+		t.Fatalf("MuxedAccountFromAccountId() error = %v", err)
+	}
+	feeSource := xdr.MustAddress("GAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAAWHF")
+
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+		FeeBump: &xdr.FeeBumpTransactionEnvelope{
+			Tx: xdr.FeeBumpTransaction{
+				FeeSource: feeSource.ToMuxedAccount(),
+				Fee:       5000,
+				InnerTx: xdr.FeeBumpTransactionInnerTx{
+					Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+					V1: &xdr.TransactionV1Envelope{
+						Tx: xdr.Transaction{
+							SourceAccount: innerSource,
+							Fee:           100,
+							SeqNum:        7,
+							Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+							Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+						},
+						Signatures: []xdr.DecoratedSignature{createSignature()},
+					},
+				},
+			},
+			Signatures: []xdr.DecoratedSignature{createSignature(), createSignature()},
+		},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	innerHash := xdr.Hash{0xab, 0xcd}
+	result, err := xdr.MarshalBase64(xdr.TransactionResult{
+		FeeCharged: 300,
+		Result: xdr.TransactionResultResult{
+			Code: xdr.TransactionResultCodeTxFeeBumpInnerSuccess,
+			InnerResultPair: &xdr.InnerTransactionResultPair{
+				TransactionHash: innerHash,
+				Result: xdr.InnerTransactionResult{
+					Result: xdr.InnerTransactionResultResult{Code: xdr.TransactionResultCodeTxSuccess, Results: &[]xdr.OperationResult{}},
+				},
+			},
+		},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	parsed, err := ParseTransaction(client.Transaction{Hash: "outer", EnvelopeXdr: envelope, ResultXdr: result})
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+
+	if parsed.FeeBump == nil || !*parsed.FeeBump {
+		t.Errorf("FeeBump = %v, want true", parsed.FeeBump)
+	}
+	// The transaction's source is the inner source, decoded from its muxed address
+	if *parsed.SourceAccount != "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H" {
+		t.Errorf("SourceAccount = %s, want the inner source", *parsed.SourceAccount)
+	}
+	if parsed.MuxedAccountId == nil || *parsed.MuxedAccountId != "42" {
+		t.Errorf("MuxedAccountId = %v, want 42", parsed.MuxedAccountId)
+	}
+	if *parsed.Fee != 5000 || *parsed.Sequence != 7 {
+		t.Errorf("Fee = %d, Sequence = %d, want 5000 and 7", *parsed.Fee, *parsed.Sequence)
+	}
+	if parsed.Signatures == nil || len(*parsed.Signatures) != 2 {
+		t.Errorf("Signatures = %v, want the 2 outer signatures", parsed.Signatures)
+	}
+
+	info := parsed.FeeBumpInfo
+	if info == nil {
+		t.Fatal("FeeBumpInfo is nil")
+	}
+	if info.SourceAccount == nil || *info.SourceAccount != feeSource.Address() || info.MuxedAccountId != nil {
+		t.Errorf("Fee source = %v (%v), want %s", info.SourceAccount, info.MuxedAccountId, feeSource.Address())
+	}
+	if info.Fee != 5000 || info.InnerFee != 100 {
+		t.Errorf("Fee = %d, InnerFee = %d, want 5000 and 100", info.Fee, info.InnerFee)
+	}
+	if info.InnerHash != hex.EncodeToString(innerHash[:]) {
+		t.Errorf("InnerHash = %s", info.InnerHash)
+	}
+	if info.InnerSignatures == nil || len(*info.InnerSignatures) != 1 {
+		t.Errorf("InnerSignatures = %v, want 1", info.InnerSignatures)
+	}
+
+	if parsed.ResultCode == nil || *parsed.ResultCode != "txFEE_BUMP_INNER_SUCCESS" {
+		t.Errorf("ResultCode = %v, want txFEE_BUMP_INNER_SUCCESS", parsed.ResultCode)
+	}
+	if parsed.InnerResultCode == nil || *parsed.InnerResultCode != "txSUCCESS" {
+		t.Errorf("InnerResultCode = %v, want txSUCCESS", parsed.InnerResultCode)
+	}
+}
+
+func TestParseTransaction_NotFeeBump(t *testing.T) {
+	source := xdr.MustAddress("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H")
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{Tx: xdr.Transaction{
+			SourceAccount: source.ToMuxedAccount(),
+			Fee:           100,
+			Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+			Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	parsed, err := ParseTransaction(client.Transaction{Hash: "plain", EnvelopeXdr: envelope})
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+	if parsed.FeeBump == nil || *parsed.FeeBump || parsed.FeeBumpInfo != nil || parsed.MuxedAccountId != nil {
+		t.Errorf("Unexpected fee bump fields: %v %+v %v", parsed.FeeBump, parsed.FeeBumpInfo, parsed.MuxedAccountId)
+	}
+	// Without a result there is no result code
+	if parsed.ResultCode != nil || parsed.InnerResultCode != nil {
+		t.Errorf("Unexpected result codes: %v %v", parsed.ResultCode, parsed.InnerResultCode)
+	}
+}
+
+func TestMuxedAccount_HighBitID(t *testing.T) {
+	for _, tc := range []struct {
+		id   uint64
+		want string
+	}{
+		{1 << 63, "9223372036854775808"},
+		{math.MaxUint64, "18446744073709551615"},
+	} {
+		account, err := xdr.MuxedAccountFromAccountId("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", tc.id)
+		if err != nil {
+			t.Fatalf("MuxedAccountFromAccountId() error = %v", err)
+		}
+
+		address, id := muxedAccount(account)
+		if address != "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H" {
+			t.Errorf("Address = %s, want the base account", address)
+		}
+		if id == nil || *id != tc.want {
+			t.Errorf("Muxed ID = %v, want %s", id, tc.want)
+		}
+	}
+}
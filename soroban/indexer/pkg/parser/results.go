@@ -0,0 +1,123 @@
+package parser
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"unicode"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// resultCodeName converts the Go name of an XDR result code back to its name in the XDR
+// definitions, e.g. TransactionResultCodeTxBadSeq to txBAD_SEQ and
+// InvokeHostFunctionResultCodeInvokeHostFunctionTrapped to INVOKE_HOST_FUNCTION_TRAPPED
+func resultCodeName(goName, typePrefix string) string {
+	name := strings.TrimPrefix(goName, typePrefix)
+
+	// Transaction and generic operation codes keep a lowercase tx / op prefix
+	prefix := ""
+	for _, p := range []string{"Tx", "Op"} {
+		if strings.HasPrefix(name, p) && len(name) > len(p) && unicode.IsUpper(rune(name[len(p)])) {
+			prefix = strings.ToLower(p)
+			name = name[len(p):]
+			break
+		}
+	}
+
+	var b strings.Builder
+	for i, r := range name {
+		if i > 0 && unicode.IsUpper(r) {
+			b.WriteByte('_')
+		}
+		b.WriteRune(unicode.ToUpper(r))
+	}
+	return prefix + b.String()
+}
+
+// transactionResultCode returns the XDR name of a transaction result code, e.g. txFAILED
+func transactionResultCode(code xdr.TransactionResultCode) string {
+	return resultCodeName(code.String(), "TransactionResultCode")
+}
+
+// parseResultCodes sets the transaction result code on transaction, and for fee bumps the inner
+// transaction's
+func parseResultCodes(transaction *models.Transaction, result *xdr.TransactionResult) {
+	code := transactionResultCode(result.Result.Code)
+	transaction.ResultCode = &code
+
+	if pair, ok := result.Result.GetInnerResultPair(); ok {
+		inner := transactionResultCode(pair.Result.Result.Code)
+		transaction.InnerResultCode = &inner
+	}
+}
+
+// OperationResultCodes returns the result code of each operation in a transaction result, named
+// as in the XDR definitions: the operation specific code (e.g. INVOKE_HOST_FUNCTION_TRAPPED or
+// PAYMENT_UNDERFUNDED), or the generic one when the operation was not applied (e.g. opBAD_AUTH)
+// Fee bumps report the inner transaction's operations. Transactions rejected before their
+// operations were applied (e.g. txBAD_SEQ) have none
+func OperationResultCodes(result *xdr.TransactionResult) []string {
+	var results []xdr.OperationResult
+	if pair, ok := result.Result.GetInnerResultPair(); ok {
+		results, _ = pair.Result.Result.GetResults()
+	} else {
+		results, _ = result.Result.GetResults()
+	}
+
+	codes := make([]string, len(results))
+	for i, opResult := range results {
+		codes[i] = operationResultCode(opResult)
+	}
+	return codes
+}
+
+// operationResultCode returns the XDR name of a single operation's result code
+func operationResultCode(result xdr.OperationResult) string {
+	generic := resultCodeName(result.Code.String(), "OperationResultCode")
+	tr, ok := result.GetTr()
+	if result.Code != xdr.OperationResultCodeOpInner || !ok {
+		return generic
+	}
+
+	// Every operation result arm is a struct with a Code enum named <Operation>ResultCode
+	arm, ok := tr.ArmForSwitch(int32(tr.Type))
+	if !ok {
+		return generic
+	}
+	field := reflect.ValueOf(tr).FieldByName(arm)
+	if field.Kind() != reflect.Ptr || field.IsNil() {
+		return generic
+	}
+	code := field.Elem().FieldByName("Code")
+	if !code.IsValid() {
+		return generic
+	}
+	stringer, ok := code.Interface().(fmt.Stringer)
+	if !ok {
+		return generic
+	}
+	return resultCodeName(stringer.String(), code.Type().Name())
+}
+
+// ApplyOperationResults sets each operation's result code from the transaction's result XDR
+// Operations are matched by index; a result that fails to decode leaves them unchanged
+func ApplyOperationResults(operations []*models.Operation, resultXdr string) {
+	if resultXdr == "" {
+		return
+	}
+	result, err := decodeResult(resultXdr)
+	if err != nil {
+		return
+	}
+
+	codes := OperationResultCodes(result)
+	for _, op := range operations {
+		if i := int(op.OperationIndex); i < len(codes) {
+			code := codes[i]
+			op.ResultCode = &code
+		}
+	}
+}
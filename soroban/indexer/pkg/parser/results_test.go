@@ -0,0 +1,105 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+func TestTransactionResultCode(t *testing.T) {
+	tests := []struct {
+		code xdr.TransactionResultCode
+		want string
+	}{
+		{xdr.TransactionResultCodeTxSuccess, "txSUCCESS"},
+		{xdr.TransactionResultCodeTxBadSeq, "txBAD_SEQ"},
+		{xdr.TransactionResultCodeTxInsufficientFee, "txINSUFFICIENT_FEE"},
+		{xdr.TransactionResultCodeTxFeeBumpInnerFailed, "txFEE_BUMP_INNER_FAILED"},
+		{xdr.TransactionResultCodeTxSorobanInvalid, "txSOROBAN_INVALID"},
+	}
+	for _, tt := range tests {
+		if got := transactionResultCode(tt.code); got != tt.want {
+			t.Errorf("transactionResultCode(%s) = %s, want %s", tt.code, got, tt.want)
+		}
+	}
+}
+
+func opResult(tr xdr.OperationResultTr) xdr.OperationResult {
+	return xdr.OperationResult{Code: xdr.OperationResultCodeOpInner, Tr: &tr}
+}
+
+func TestOperationResultCodes(t *testing.T) {
+	results := []xdr.OperationResult{
+		opResult(xdr.OperationResultTr{
+			Type:          xdr.OperationTypePayment,
+			PaymentResult: &xdr.PaymentResult{Code: xdr.PaymentResultCodePaymentUnderfunded},
+		}),
+		opResult(xdr.OperationResultTr{
+			Type:                     xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionTrapped},
+		}),
+		opResult(xdr.OperationResultTr{
+			Type:                     xdr.OperationTypeExtendFootprintTtl,
+			ExtendFootprintTtlResult: &xdr.ExtendFootprintTtlResult{Code: xdr.ExtendFootprintTtlResultCodeExtendFootprintTtlResourceLimitExceeded},
+		}),
+		{Code: xdr.OperationResultCodeOpBadAuth},
+	}
+	want := []string{"PAYMENT_UNDERFUNDED", "INVOKE_HOST_FUNCTION_TRAPPED", "EXTEND_FOOTPRINT_TTL_RESOURCE_LIMIT_EXCEEDED", "opBAD_AUTH"}
+
+	result := &xdr.TransactionResult{Result: xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxFailed, Results: &results}}
+	assertCodes := func(name string, got []string) {
+		t.Helper()
+		if len(got) != len(want) {
+			t.Fatalf("%s: codes = %v, want %v", name, got, want)
+		}
+		for i := range want {
+			if got[i] != want[i] {
+				t.Errorf("%s: code %d = %s, want %s", name, i, got[i], want[i])
+			}
+		}
+	}
+	assertCodes("transaction", OperationResultCodes(result))
+
+	// Fee bumps report their inner transaction's operations
+	feeBump := &xdr.TransactionResult{Result: xdr.TransactionResultResult{
+		Code: xdr.TransactionResultCodeTxFeeBumpInnerFailed,
+		InnerResultPair: &xdr.InnerTransactionResultPair{Result: xdr.InnerTransactionResult{
+			Result: xdr.InnerTransactionResultResult{Code: xdr.TransactionResultCodeTxFailed, Results: &results},
+		}},
+	}}
+	assertCodes("fee bump", OperationResultCodes(feeBump))
+
+	// Transactions rejected before applying their operations have no operation results
+	rejected := &xdr.TransactionResult{Result: xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxBadSeq}}
+	if codes := OperationResultCodes(rejected); len(codes) != 0 {
+		t.Errorf("Rejected transaction codes = %v, want none", codes)
+	}
+}
+
+func TestApplyOperationResults(t *testing.T) {
+	results := []xdr.OperationResult{opResult(xdr.OperationResultTr{
+		Type:                     xdr.OperationTypeInvokeHostFunction,
+		InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionResourceLimitExceeded},
+	})}
+	resultXdr, err := xdr.MarshalBase64(xdr.TransactionResult{
+		Result: xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxFailed, Results: &results},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	operations := []*models.Operation{{OperationIndex: 0}}
+	ApplyOperationResults(operations, resultXdr)
+	if code := operations[0].ResultCode; code == nil || *code != "INVOKE_HOST_FUNCTION_RESOURCE_LIMIT_EXCEEDED" {
+		t.Errorf("ResultCode = %v, want INVOKE_HOST_FUNCTION_RESOURCE_LIMIT_EXCEEDED", code)
+	}
+
+	// Undecodable results leave operations unchanged
+	operations = []*models.Operation{{OperationIndex: 0}}
+	ApplyOperationResults(operations, "not-xdr")
+	if operations[0].ResultCode != nil {
+		t.Errorf("ResultCode = %s, want nil", *operations[0].ResultCode)
+	}
+}
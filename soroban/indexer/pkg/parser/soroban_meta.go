@@ -0,0 +1,183 @@
+package parser
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// decodeTransactionMeta decodes base64 TransactionMeta XDR
+func decodeTransactionMeta(metaXdr string) (*xdr.TransactionMeta, error) {
+	data, err := base64.StdEncoding.DecodeString(metaXdr)
+	if err != nil {
+		return nil, fmt.Errorf("decode meta xdr: %w", err)
+	}
+
+	var meta xdr.TransactionMeta
+	if err := xdr.SafeUnmarshal(data, &meta); err != nil {
+		return nil, fmt.Errorf("unmarshal transaction meta: %w", err)
+	}
+	return &meta, nil
+}
+
+// ExtractReturnValue returns the value the Soroban invocation of a transaction returned
+// It is nil for classic transactions, and for failed invocations under meta V4
+func ExtractReturnValue(metaXdr string) (*xdr.ScVal, error) {
+	meta, err := decodeTransactionMeta(metaXdr)
+	if err != nil {
+		return nil, err
+	}
+	return returnValue(meta), nil
+}
+
+func returnValue(meta *xdr.TransactionMeta) *xdr.ScVal {
+	switch {
+	case meta.V4 != nil:
+		if meta.V4.SorobanMeta != nil {
+			return meta.V4.SorobanMeta.ReturnValue
+		}
+	case meta.V3 != nil:
+		if meta.V3.SorobanMeta != nil {
+			return &meta.V3.SorobanMeta.ReturnValue
+		}
+	}
+	return nil
+}
+
+// sorobanMetaExt returns the resource fees a Soroban transaction was charged, if meta records them
+func sorobanMetaExt(meta *xdr.TransactionMeta) (xdr.SorobanTransactionMetaExtV1, bool) {
+	switch {
+	case meta.V4 != nil:
+		if meta.V4.SorobanMeta != nil {
+			return meta.V4.SorobanMeta.Ext.GetV1()
+		}
+	case meta.V3 != nil:
+		if meta.V3.SorobanMeta != nil {
+			return meta.V3.SorobanMeta.Ext.GetV1()
+		}
+	}
+	return xdr.SorobanTransactionMetaExtV1{}, false
+}
+
+// parseSorobanMeta sets the return value and resource fees recorded in metaXdr on transaction
+// Transactions without them (or whose meta fails to decode) are left unchanged
+func parseSorobanMeta(transaction *models.Transaction, metaXdr string) {
+	if metaXdr == "" {
+		return
+	}
+	meta, err := decodeTransactionMeta(metaXdr)
+	if err != nil {
+		return
+	}
+
+	if value := returnValue(meta); value != nil {
+		encoded, err := xdr.MarshalBase64(*value)
+		if err == nil {
+			if decoded, err := json.Marshal(ScValToInterface(*value)); err == nil {
+				transaction.ReturnValue = decoded
+				transaction.ReturnValueXdr = &encoded
+			}
+		}
+	}
+
+	if fees, ok := sorobanMetaExt(meta); ok {
+		parseResourceFees(transaction, fees)
+	}
+}
+
+// ExtractDiagnosticEvents decodes the diagnostic events in a transaction's meta, in emission order
+// Topics and values are decoded like contract events, except that the contract ID an fn_call
+// event carries as bytes is encoded as a contract address
+func ExtractDiagnosticEvents(txHash string, ledger uint32, metaXdr string) ([]*models.DiagnosticEvent, error) {
+	meta, err := decodeTransactionMeta(metaXdr)
+	if err != nil {
+		return nil, err
+	}
+
+	var diagnostics []xdr.DiagnosticEvent
+	switch {
+	case meta.V4 != nil:
+		diagnostics = meta.V4.DiagnosticEvents
+	case meta.V3 != nil:
+		if meta.V3.SorobanMeta != nil {
+			diagnostics = meta.V3.SorobanMeta.DiagnosticEvents
+		}
+	}
+
+	events := make([]*models.DiagnosticEvent, 0, len(diagnostics))
+	for i, diagnostic := range diagnostics {
+		event, err := parseDiagnosticEvent(diagnostic)
+		if err != nil {
+			return nil, fmt.Errorf("diagnostic event %d: %w", i, err)
+		}
+		if event == nil {
+			continue
+		}
+		event.TxHash = txHash
+		event.EventIndex = int32(i)
+		event.Ledger = ledger
+		events = append(events, event)
+	}
+	return events, nil
+}
+
+// parseDiagnosticEvent decodes a single diagnostic event (nil for unknown body versions)
+func parseDiagnosticEvent(diagnostic xdr.DiagnosticEvent) (*models.DiagnosticEvent, error) {
+	event := diagnostic.Event
+	body, ok := event.Body.GetV0()
+	if !ok {
+		return nil, nil
+	}
+
+	row := &models.DiagnosticEvent{
+		EventType:                strings.ToLower(strings.TrimPrefix(event.Type.String(), "ContractEventType")),
+		InSuccessfulContractCall: diagnostic.InSuccessfulContractCall,
+		CreatedAt:                time.Now(),
+	}
+
+	if event.ContractId != nil {
+		contractID, err := strkey.Encode(strkey.VersionByteContract, event.ContractId[:])
+		if err != nil {
+			return nil, err
+		}
+		row.ContractID = contractID
+	}
+
+	topics := make([]interface{}, len(body.Topics))
+	for i, topic := range body.Topics {
+		topics[i] = ScValToInterface(topic)
+	}
+	if len(body.Topics) > 0 {
+		if sym, ok := body.Topics[0].GetSym(); ok {
+			row.Name = string(sym)
+		}
+	}
+	// fn_call topics are [fn_call, contract ID bytes, function]
+	if row.Name == models.DiagnosticFnCall && len(body.Topics) > 1 {
+		if id, ok := body.Topics[1].GetBytes(); ok && len(id) == 32 {
+			if address, err := strkey.Encode(strkey.VersionByteContract, id); err == nil {
+				topics[1] = address
+			}
+		}
+	}
+
+	topicJSON, err := json.Marshal(topics)
+	if err != nil {
+		return nil, err
+	}
+	valueJSON, err := json.Marshal(ScValToInterface(body.Data))
+	if err != nil {
+		return nil, err
+	}
+	row.Topic = topicJSON
+	row.Value = valueJSON
+
+	return row, nil
+}
@@ -0,0 +1,215 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+func symbolVal(s string) xdr.ScVal {
+	sym := xdr.ScSymbol(s)
+	return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym}
+}
+
+func diagnosticEvent(contract *xdr.ContractId, successful bool, data xdr.ScVal, topics ...xdr.ScVal) xdr.DiagnosticEvent {
+	return xdr.DiagnosticEvent{
+		InSuccessfulContractCall: successful,
+		Event: xdr.ContractEvent{
+			ContractId: contract,
+			Type:       xdr.ContractEventTypeDiagnostic,
+			Body: xdr.ContractEventBody{
+				V:  0,
+				V0: &xdr.ContractEventV0{Topics: topics, Data: data},
+			},
+		},
+	}
+}
+
+func encodeMeta(t *testing.T, meta xdr.TransactionMeta) string {
+	encoded, err := xdr.MarshalBase64(meta)
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+	return encoded
+}
+
+// failedCallMeta is the V4 meta of a call to contract's "swap" that failed with contract error 3
+func failedCallMeta(t *testing.T, contract xdr.ScAddress) string {
+	id := contract.MustContractId()
+	idBytes := xdr.ScBytes(id[:])
+	code := xdr.Uint32(3)
+	scErr := xdr.ScError{Type: xdr.ScErrorTypeSceContract, ContractCode: &code}
+	message := xdr.ScString("escalating error to panic")
+	void := xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+
+	return encodeMeta(t, xdr.TransactionMeta{
+		V: 4,
+		V4: &xdr.TransactionMetaV4{
+			DiagnosticEvents: []xdr.DiagnosticEvent{
+				diagnosticEvent(nil, false, void,
+					symbolVal("fn_call"),
+					xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &idBytes},
+					symbolVal("swap")),
+				diagnosticEvent(&id, false, xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &message},
+					symbolVal("error"),
+					xdr.ScVal{Type: xdr.ScValTypeScvError, Error: &scErr}),
+			},
+		},
+	})
+}
+
+func TestExtractDiagnosticEvents(t *testing.T) {
+	contract, contractID := testContractAddress(t, 5)
+	id := contract.MustContractId()
+	idBytes := xdr.ScBytes(id[:])
+	amount := xdr.Uint32(42)
+
+	metaXdr := encodeMeta(t, xdr.TransactionMeta{
+		V: 3,
+		V3: &xdr.TransactionMetaV3{
+			SorobanMeta: &xdr.SorobanTransactionMeta{
+				ReturnValue: xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount},
+				DiagnosticEvents: []xdr.DiagnosticEvent{
+					diagnosticEvent(nil, true, xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount},
+						symbolVal("fn_call"),
+						xdr.ScVal{Type: xdr.ScValTypeScvBytes, Bytes: &idBytes},
+						symbolVal("get")),
+					diagnosticEvent(&id, true, xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount},
+						symbolVal("fn_return"),
+						symbolVal("get")),
+				},
+			},
+		},
+	})
+
+	events, err := ExtractDiagnosticEvents("tx1", 100, metaXdr)
+	if err != nil {
+		t.Fatalf("ExtractDiagnosticEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	call := events[0]
+	if call.TxHash != "tx1" || call.Ledger != 100 || call.EventIndex != 0 {
+		t.Errorf("Unexpected position %s/%d/%d", call.TxHash, call.Ledger, call.EventIndex)
+	}
+	if call.Name != models.DiagnosticFnCall || call.EventType != "diagnostic" || !call.InSuccessfulContractCall {
+		t.Errorf("Unexpected fn_call event %+v", call)
+	}
+	// The called contract is decoded from bytes to its address
+	var topics []string
+	if err := json.Unmarshal(call.Topic, &topics); err != nil {
+		t.Fatalf("Unmarshal topics error = %v", err)
+	}
+	if want := []string{"fn_call", contractID, "get"}; len(topics) != 3 || topics[0] != want[0] || topics[1] != want[1] || topics[2] != want[2] {
+		t.Errorf("Topics = %v, want %v", topics, want)
+	}
+
+	ret := events[1]
+	if ret.Name != models.DiagnosticFnReturn || ret.ContractID != contractID || ret.EventIndex != 1 {
+		t.Errorf("Unexpected fn_return event %+v", ret)
+	}
+	if string(ret.Value) != "42" {
+		t.Errorf("Value = %s, want 42", ret.Value)
+	}
+}
+
+func TestExtractDiagnosticEvents_FailedCall(t *testing.T) {
+	contract, contractID := testContractAddress(t, 6)
+
+	events, err := ExtractDiagnosticEvents("tx2", 200, failedCallMeta(t, contract))
+	if err != nil {
+		t.Fatalf("ExtractDiagnosticEvents() error = %v", err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("Expected 2 events, got %d", len(events))
+	}
+
+	failure := events[1]
+	if failure.Name != models.DiagnosticError || failure.ContractID != contractID || failure.InSuccessfulContractCall {
+		t.Errorf("Unexpected error event %+v", failure)
+	}
+	var topics []interface{}
+	if err := json.Unmarshal(failure.Topic, &topics); err != nil {
+		t.Fatalf("Unmarshal topics error = %v", err)
+	}
+	scErr, ok := topics[1].(map[string]interface{})
+	if !ok || scErr["type"] != "ScErrorTypeSceContract" || scErr["code"] != float64(3) {
+		t.Errorf("Error topic = %v", topics[1])
+	}
+	if string(failure.Value) != `"escalating error to panic"` {
+		t.Errorf("Value = %s", failure.Value)
+	}
+}
+
+func TestExtractDiagnosticEvents_Classic(t *testing.T) {
+	metaXdr := encodeMeta(t, xdr.TransactionMeta{V: 2, V2: &xdr.TransactionMetaV2{}})
+
+	events, err := ExtractDiagnosticEvents("tx3", 300, metaXdr)
+	if err != nil {
+		t.Fatalf("ExtractDiagnosticEvents() error = %v", err)
+	}
+	if len(events) != 0 {
+		t.Errorf("Expected no events, got %d", len(events))
+	}
+
+	if _, err := ExtractDiagnosticEvents("tx3", 300, "not-xdr"); err == nil {
+		t.Error("Expected error for invalid meta")
+	}
+}
+
+func TestParseTransaction_ReturnValue(t *testing.T) {
+	source := xdr.MustAddress(testIssuer)
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{Tx: xdr.Transaction{
+			SourceAccount: source.ToMuxedAccount(),
+			Fee:           100,
+			SeqNum:        1,
+			Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+			Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+	amount := xdr.Uint32(42)
+	ret := xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &amount}
+
+	tx := client.Transaction{
+		Hash:        "tx4",
+		Status:      "SUCCESS",
+		EnvelopeXdr: envelope,
+		ResultMetaXdr: encodeMeta(t, xdr.TransactionMeta{
+			V:  4,
+			V4: &xdr.TransactionMetaV4{SorobanMeta: &xdr.SorobanTransactionMetaV2{ReturnValue: &ret}},
+		}),
+	}
+
+	parsed, err := ParseTransaction(tx)
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+	if string(parsed.ReturnValue) != "42" {
+		t.Errorf("ReturnValue = %s, want 42", parsed.ReturnValue)
+	}
+	if want, _ := xdr.MarshalBase64(ret); parsed.ReturnValueXdr == nil || *parsed.ReturnValueXdr != want {
+		t.Errorf("ReturnValueXdr = %v, want %s", parsed.ReturnValueXdr, want)
+	}
+
+	// Failed V4 invocations record no return value
+	contract, _ := testContractAddress(t, 7)
+	tx.Status = "FAILED"
+	tx.ResultMetaXdr = failedCallMeta(t, contract)
+	if parsed, err = ParseTransaction(tx); err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+	if parsed.ReturnValue != nil || parsed.ReturnValueXdr != nil {
+		t.Errorf("Expected no return value, got %s", parsed.ReturnValue)
+	}
+}
@@ -0,0 +1,99 @@
+package parser
+
+import (
+	"encoding/json"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// sorobanData returns the SorobanTransactionData a transaction envelope declares (the inner
+// transaction's for fee bumps), nil for classic transactions
+func sorobanData(envelope *xdr.TransactionEnvelope) *xdr.SorobanTransactionData {
+	var ext xdr.TransactionExt
+	switch envelope.Type {
+	case xdr.EnvelopeTypeEnvelopeTypeTx:
+		v1, ok := envelope.GetV1()
+		if !ok {
+			return nil
+		}
+		ext = v1.Tx.Ext
+	case xdr.EnvelopeTypeEnvelopeTypeTxFeeBump:
+		fb, ok := envelope.GetFeeBump()
+		if !ok {
+			return nil
+		}
+		inner, ok := fb.Tx.InnerTx.GetV1()
+		if !ok {
+			return nil
+		}
+		ext = inner.Tx.Ext
+	default:
+		return nil
+	}
+
+	data, ok := ext.GetSorobanData()
+	if !ok {
+		return nil
+	}
+	return &data
+}
+
+// parseSorobanResources sets the resources and resource fee declared in data on transaction
+func parseSorobanResources(transaction *models.Transaction, data *xdr.SorobanTransactionData) {
+	if data == nil {
+		return
+	}
+
+	resourceFee := int64(data.ResourceFee)
+	instructions := uint32(data.Resources.Instructions)
+	readBytes := uint32(data.Resources.DiskReadBytes)
+	writeBytes := uint32(data.Resources.WriteBytes)
+
+	transaction.ResourceFee = &resourceFee
+	transaction.Instructions = &instructions
+	transaction.ReadBytes = &readBytes
+	transaction.WriteBytes = &writeBytes
+	transaction.ReadOnlyKeys = footprintKeys(data.Resources.Footprint.ReadOnly)
+	transaction.ReadWriteKeys = footprintKeys(data.Resources.Footprint.ReadWrite)
+}
+
+// footprintKeys encodes footprint ledger keys as a JSON array of base64 XDR
+func footprintKeys(keys []xdr.LedgerKey) models.JSONB {
+	encoded := make([]string, 0, len(keys))
+	for _, key := range keys {
+		k, err := xdr.MarshalBase64(key)
+		if err != nil {
+			continue
+		}
+		encoded = append(encoded, k)
+	}
+
+	data, err := json.Marshal(encoded)
+	if err != nil {
+		return nil
+	}
+	return data
+}
+
+// parseResourceFees sets the resource fees a Soroban transaction was charged on transaction
+// The refund is the part of the declared resource fee that was not charged, so it needs the
+// resources from the envelope to be parsed first
+func parseResourceFees(transaction *models.Transaction, fees xdr.SorobanTransactionMetaExtV1) {
+	nonRefundable := int64(fees.TotalNonRefundableResourceFeeCharged)
+	refundable := int64(fees.TotalRefundableResourceFeeCharged)
+	rent := int64(fees.RentFeeCharged)
+
+	transaction.NonRefundableResourceFee = &nonRefundable
+	transaction.RefundableResourceFee = &refundable
+	transaction.RentFee = &rent
+
+	if transaction.ResourceFee != nil {
+		refunded := *transaction.ResourceFee - nonRefundable - refundable
+		if refunded < 0 {
+			refunded = 0
+		}
+		transaction.FeeRefunded = &refunded
+	}
+}
@@ -0,0 +1,149 @@
+package parser
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+)
+
+// sorobanTransaction builds a Soroban transaction declaring instructions and a footprint of one
+// read-only and one read-write key
+func sorobanTransaction(t *testing.T, resourceFee int64) (xdr.Transaction, xdr.LedgerKey, xdr.LedgerKey) {
+	contract, _ := testContractAddress(t, 8)
+	readOnly := xdr.LedgerKey{
+		Type: xdr.LedgerEntryTypeContractData,
+		ContractData: &xdr.LedgerKeyContractData{
+			Contract:   contract,
+			Key:        xdr.ScVal{Type: xdr.ScValTypeScvLedgerKeyContractInstance},
+			Durability: xdr.ContractDataDurabilityPersistent,
+		},
+	}
+	account := xdr.MustAddress(testIssuer)
+	readWrite := xdr.LedgerKey{Type: xdr.LedgerEntryTypeAccount, Account: &xdr.LedgerKeyAccount{AccountId: account}}
+
+	tx := xdr.Transaction{
+		SourceAccount: account.ToMuxedAccount(),
+		Fee:           xdr.Uint32(resourceFee + 100),
+		SeqNum:        1,
+		Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+		Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+		Ext: xdr.TransactionExt{
+			V: 1,
+			SorobanData: &xdr.SorobanTransactionData{
+				Resources: xdr.SorobanResources{
+					Footprint:     xdr.LedgerFootprint{ReadOnly: []xdr.LedgerKey{readOnly}, ReadWrite: []xdr.LedgerKey{readWrite}},
+					Instructions:  2_000_000,
+					DiskReadBytes: 4096,
+					WriteBytes:    512,
+				},
+				ResourceFee: xdr.Int64(resourceFee),
+			},
+		},
+	}
+	return tx, readOnly, readWrite
+}
+
+func TestParseTransaction_SorobanResources(t *testing.T) {
+	tx, readOnly, readWrite := sorobanTransaction(t, 90_000)
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1:   &xdr.TransactionV1Envelope{Tx: tx},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+	meta := encodeMeta(t, xdr.TransactionMeta{
+		V: 3,
+		V3: &xdr.TransactionMetaV3{SorobanMeta: &xdr.SorobanTransactionMeta{
+			ReturnValue: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+			Ext: xdr.SorobanTransactionMetaExt{V: 1, V1: &xdr.SorobanTransactionMetaExtV1{
+				TotalNonRefundableResourceFeeCharged: 30_000,
+				TotalRefundableResourceFeeCharged:    25_000,
+				RentFeeCharged:                       20_000,
+			}},
+		}},
+	})
+
+	parsed, err := ParseTransaction(client.Transaction{Hash: "tx5", EnvelopeXdr: envelope, ResultMetaXdr: meta})
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+
+	if parsed.ResourceFee == nil || *parsed.ResourceFee != 90_000 {
+		t.Errorf("ResourceFee = %v, want 90000", parsed.ResourceFee)
+	}
+	if parsed.Instructions == nil || *parsed.Instructions != 2_000_000 {
+		t.Errorf("Instructions = %v, want 2000000", parsed.Instructions)
+	}
+	if parsed.ReadBytes == nil || *parsed.ReadBytes != 4096 || parsed.WriteBytes == nil || *parsed.WriteBytes != 512 {
+		t.Errorf("ReadBytes = %v, WriteBytes = %v, want 4096 and 512", parsed.ReadBytes, parsed.WriteBytes)
+	}
+
+	for name, tc := range map[string]struct {
+		got  []byte
+		want xdr.LedgerKey
+	}{
+		"read-only":  {parsed.ReadOnlyKeys, readOnly},
+		"read-write": {parsed.ReadWriteKeys, readWrite},
+	} {
+		var keys []string
+		if err := json.Unmarshal(tc.got, &keys); err != nil {
+			t.Fatalf("Unmarshal %s keys error = %v", name, err)
+		}
+		want, _ := xdr.MarshalBase64(tc.want)
+		if len(keys) != 1 || keys[0] != want {
+			t.Errorf("%s keys = %v, want [%s]", name, keys, want)
+		}
+	}
+
+	if parsed.NonRefundableResourceFee == nil || *parsed.NonRefundableResourceFee != 30_000 {
+		t.Errorf("NonRefundableResourceFee = %v, want 30000", parsed.NonRefundableResourceFee)
+	}
+	if parsed.RefundableResourceFee == nil || *parsed.RefundableResourceFee != 25_000 {
+		t.Errorf("RefundableResourceFee = %v, want 25000", parsed.RefundableResourceFee)
+	}
+	if parsed.RentFee == nil || *parsed.RentFee != 20_000 {
+		t.Errorf("RentFee = %v, want 20000", parsed.RentFee)
+	}
+	// 90000 declared - 30000 non-refundable - 25000 refundable (rent included)
+	if parsed.FeeRefunded == nil || *parsed.FeeRefunded != 35_000 {
+		t.Errorf("FeeRefunded = %v, want 35000", parsed.FeeRefunded)
+	}
+}
+
+func TestParseTransaction_FeeBumpSorobanFees(t *testing.T) {
+	inner, _, _ := sorobanTransaction(t, 1_000)
+	feeSource := xdr.MustAddress(testIssuer)
+	envelope, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTxFeeBump,
+		FeeBump: &xdr.FeeBumpTransactionEnvelope{Tx: xdr.FeeBumpTransaction{
+			FeeSource: feeSource.ToMuxedAccount(),
+			Fee:       5_000_000_000, // Overflows int32
+			InnerTx: xdr.FeeBumpTransactionInnerTx{
+				Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+				V1:   &xdr.TransactionV1Envelope{Tx: inner},
+			},
+		}},
+	})
+	if err != nil {
+		t.Fatalf("MarshalBase64() error = %v", err)
+	}
+
+	parsed, err := ParseTransaction(client.Transaction{Hash: "tx6", EnvelopeXdr: envelope})
+	if err != nil {
+		t.Fatalf("ParseTransaction() error = %v", err)
+	}
+	if parsed.Fee == nil || *parsed.Fee != 5_000_000_000 {
+		t.Errorf("Fee = %v, want 5000000000", parsed.Fee)
+	}
+	// Resources come from the inner transaction; without meta nothing was charged yet
+	if parsed.Instructions == nil || *parsed.Instructions != 2_000_000 {
+		t.Errorf("Instructions = %v, want 2000000", parsed.Instructions)
+	}
+	if parsed.RentFee != nil || parsed.FeeRefunded != nil {
+		t.Errorf("Expected no charged fees without meta, got rent %v refunded %v", parsed.RentFee, parsed.FeeRefunded)
+	}
+}
@@ -0,0 +1,77 @@
+package parser
+
+import (
+	"strings"
+
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// StellarAssetDecimals is the precision of every Stellar Asset Contract (classic amounts are stroops)
+const StellarAssetDecimals = 7
+
+// NativeAsset is the classic asset string of lumens
+const NativeAsset = "native"
+
+// ParseStellarAsset parses a classic asset string, "native" or "CODE:ISSUER"
+func ParseStellarAsset(s string) (xdr.Asset, bool) {
+	if s == NativeAsset {
+		return xdr.MustNewNativeAsset(), true
+	}
+
+	code, issuer, found := strings.Cut(s, ":")
+	if !found || code == "" || len(code) > 12 || !strkey.IsValidEd25519PublicKey(issuer) {
+		return xdr.Asset{}, false
+	}
+	asset, err := xdr.NewCreditAsset(code, issuer)
+	if err != nil {
+		return xdr.Asset{}, false
+	}
+	return asset, true
+}
+
+// ParseStellarAssetTopic returns the classic asset string a Stellar Asset Contract appends as the
+// last topic of its transfer, mint, burn and clawback events ("" for any other event)
+// Any contract can emit such a topic: check the asset's contract ID before trusting it
+func ParseStellarAssetTopic(topics []interface{}) string {
+	if len(topics) < 3 {
+		return ""
+	}
+	switch topics[0] {
+	case "transfer", "mint", "burn", "clawback":
+	default:
+		return ""
+	}
+
+	last, ok := topics[len(topics)-1].(string)
+	if !ok {
+		return ""
+	}
+	if _, ok := ParseStellarAsset(last); !ok {
+		return ""
+	}
+	return last
+}
+
+// StellarAssetMetadata returns the token metadata of the Stellar Asset Contract of asset, named
+// the way the contract names itself ("native" or "CODE:ISSUER", symbol "native" or CODE)
+func StellarAssetMetadata(contractID string, asset xdr.Asset) *models.TokenMetadata {
+	metadata := &models.TokenMetadata{
+		ContractID:   contractID,
+		Decimal:      StellarAssetDecimals,
+		StellarAsset: true,
+	}
+	if asset.IsNative() {
+		metadata.Name, metadata.Symbol, metadata.AssetCode = NativeAsset, NativeAsset, NativeAsset
+		return metadata
+	}
+
+	code := strings.TrimRight(asset.GetCode(), "\x00")
+	metadata.Name = code + ":" + asset.GetIssuer()
+	metadata.Symbol = code
+	metadata.AssetCode = code
+	metadata.AssetIssuer = asset.GetIssuer()
+	return metadata
+}
@@ -0,0 +1,96 @@
+package parser
+
+import (
+	"testing"
+
+	"github.com/stellar/go/xdr"
+)
+
+const testIssuer = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+
+func TestParseStellarAsset(t *testing.T) {
+	tests := []struct {
+		input string
+		want  string // Canonical form, "" when invalid
+	}{
+		{"native", "native"},
+		{"USDC:" + testIssuer, "USDC:" + testIssuer},
+		{"LONGASSETCOD:" + testIssuer, "LONGASSETCOD:" + testIssuer},
+		{"TOOLONGASSETCODE:" + testIssuer, ""},
+		{"USDC:GNOTANISSUER", ""},
+		{":" + testIssuer, ""},
+		{testIssuer, ""},
+		{"XLM", ""},
+	}
+
+	for _, tt := range tests {
+		asset, ok := ParseStellarAsset(tt.input)
+		got := ""
+		if ok {
+			got = asset.StringCanonical()
+		}
+		if got != tt.want {
+			t.Errorf("ParseStellarAsset(%q) = %q, want %q", tt.input, got, tt.want)
+		}
+	}
+}
+
+func TestParseStellarAssetTopic(t *testing.T) {
+	tests := []struct {
+		name   string
+		topics []interface{}
+		want   string
+	}{
+		{"transfer", []interface{}{"transfer", "GA", "GB", "native"}, "native"},
+		{"burn", []interface{}{"burn", "GA", "USDC:" + testIssuer}, "USDC:" + testIssuer},
+		{"custom token transfer", []interface{}{"transfer", "GA", "GB"}, ""},
+		{"other event", []interface{}{"approve", "GA", "GB", "native"}, ""},
+		{"not an asset", []interface{}{"transfer", "GA", "GB", "memo"}, ""},
+	}
+
+	for _, tt := range tests {
+		if got := ParseStellarAssetTopic(tt.topics); got != tt.want {
+			t.Errorf("%s: ParseStellarAssetTopic() = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestParseTokenMetadata_StellarAssetContract(t *testing.T) {
+	sym := func(s string) xdr.ScVal {
+		symbol := xdr.ScSymbol(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &symbol}
+	}
+	str := func(s string) xdr.ScVal {
+		scStr := xdr.ScString(s)
+		return xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &scStr}
+	}
+	decimal := xdr.Uint32(7)
+	admin := xdr.MustAddress(testIssuer)
+
+	metadataMap := &xdr.ScMap{
+		{Key: sym("decimal"), Val: xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &decimal}},
+		{Key: sym("name"), Val: str("USDC:" + testIssuer)},
+		{Key: sym("symbol"), Val: str("USDC")},
+	}
+	// The SAC keys its admin by the unit variant ["Admin"]
+	adminKey := &xdr.ScVec{sym("Admin")}
+	storage := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &adminKey}, Val: xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &admin}}},
+		{Key: sym("METADATA"), Val: xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &metadataMap}},
+	}
+	instance := xdr.ScVal{Type: xdr.ScValTypeScvContractInstance, Instance: &xdr.ScContractInstance{
+		Executable: xdr.ContractExecutable{Type: xdr.ContractExecutableTypeContractExecutableStellarAsset},
+		Storage:    storage,
+	}}
+
+	metadata := ParseTokenMetadata("CSAC", "ScvLedgerKeyContractInstance", ScValToInterface(instance))
+	if metadata == nil {
+		t.Fatal("Expected metadata for a Stellar Asset Contract instance")
+	}
+	if !metadata.StellarAsset || metadata.AssetCode != "USDC" || metadata.AssetIssuer != testIssuer {
+		t.Errorf("Asset = %v %q:%q, want USDC:%s", metadata.StellarAsset, metadata.AssetCode, metadata.AssetIssuer, testIssuer)
+	}
+	if metadata.Symbol != "USDC" || metadata.Decimal != StellarAssetDecimals || metadata.AdminAddress != testIssuer {
+		t.Errorf("Metadata = %+v", metadata)
+	}
+}
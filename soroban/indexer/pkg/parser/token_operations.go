@@ -3,10 +3,13 @@ package parser
 import (
 	"encoding/json"
 	"fmt"
+	"math/big"
 	"strconv"
 	"strings"
 	"time"
 
+	"github.com/stellar/go/xdr"
+
 	"github.com/blockroma/soroban-indexer/pkg/models"
 	"github.com/blockroma/soroban-indexer/pkg/models/util"
 )
@@ -170,10 +173,12 @@ func ParseTokenMetadata(contractID string, key interface{}, value interface{}) *
 	// Parse contract instance
 	var instance struct {
 		Storage []struct {
-			Key   string      `json:"key"`
+			Key   interface{} `json:"key"`
 			Value interface{} `json:"value"`
 		} `json:"storage"`
-		Executable interface{} `json:"executable"`
+		Executable struct {
+			Type string `json:"type"`
+		} `json:"executable"`
 	}
 
 	// Value might already be a map or need to be unmarshaled from JSON
@@ -202,7 +207,13 @@ func ParseTokenMetadata(contractID string, key interface{}, value interface{}) *
 	}
 
 	for _, item := range instance.Storage {
-		if item.Key == "METADATA" {
+		// Keys are symbols ("METADATA") or unit enum variants (["Admin"])
+		key := item.Key
+		if variant, ok := key.([]interface{}); ok && len(variant) == 1 {
+			key = variant[0]
+		}
+
+		if key == "METADATA" {
 			// Parse metadata structure
 			metaBytes, _ := json.Marshal(item.Value)
 			var metaPairs []struct {
@@ -221,11 +232,21 @@ func ParseTokenMetadata(contractID string, key interface{}, value interface{}) *
 					metadata.Decimal = uint32(getIntFromInterface(pair.Value))
 				}
 			}
-		} else if item.Key == "Admin" {
+		} else if key == "Admin" {
 			metadata.AdminAddress = getStringFromInterface(item.Value)
 		}
 	}
 
+	// A Stellar Asset Contract names itself after its classic asset, which maps it to the asset
+	if instance.Executable.Type == xdr.ContractExecutableTypeContractExecutableStellarAsset.String() {
+		if asset, ok := ParseStellarAsset(metadata.Name); ok {
+			sac := StellarAssetMetadata(contractID, asset)
+			sac.AdminAddress = metadata.AdminAddress
+			return sac
+		}
+		metadata.StellarAsset = true
+	}
+
 	// Only return if we found valid metadata
 	if metadata.Name != "" || metadata.Symbol != "" {
 		return metadata
@@ -239,18 +260,11 @@ func ParseTokenMetadata(contractID string, key interface{}, value interface{}) *
 // Value format: either a number or a struct with "amount" field
 func ParseTokenBalance(contractID string, key interface{}, value interface{}) *models.TokenBalance {
 	// Parse key
-	var keyArray []string
-	keyBytes, _ := json.Marshal(key)
-	if err := json.Unmarshal(keyBytes, &keyArray); err != nil {
-		return nil
-	}
-
-	if len(keyArray) != 2 || keyArray[0] != "Balance" {
+	address := ParseTokenBalanceAddress(key)
+	if address == "" {
 		return nil
 	}
 
-	address := keyArray[1]
-
 	// Parse value
 	var balance string
 
@@ -274,26 +288,149 @@ func ParseTokenBalance(contractID string, key interface{}, value interface{}) *m
 		}
 	}
 
-	if balance == "" {
+	var amount util.Int128
+	if _, ok := amount.SetString(balance, 10); !ok {
 		return nil
 	}
 
 	return &models.TokenBalance{
 		ContractID: contractID,
 		Address:    address,
-		Balance:    balance,
+		Balance:    amount,
+	}
+}
+
+// ParseTokenBalanceAddress returns the holder of a balance key ["Balance", "ADDRESS"]
+// Returns "" for any other key
+func ParseTokenBalanceAddress(key interface{}) string {
+	var keyArray []string
+	keyBytes, _ := json.Marshal(key)
+	if err := json.Unmarshal(keyBytes, &keyArray); err != nil {
+		return ""
+	}
+
+	if len(keyArray) != 2 || keyArray[0] != "Balance" {
+		return ""
+	}
+	return keyArray[1]
+}
+
+// ParseTokenAllowance extracts an allowance from contract data
+// Key format: ["Allowance", {from: OWNER, spender: SPENDER}]
+// Value format: {amount: N, expiration_ledger: L}
+func ParseTokenAllowance(contractID string, key interface{}, value interface{}) *models.TokenAllowance {
+	owner, spender := ParseTokenAllowanceKey(key)
+	if owner == "" || spender == "" {
+		return nil
+	}
+
+	fields := mapFields(value)
+	if fields["amount"] == nil {
+		return nil
+	}
+	var amount util.Int128
+	if _, ok := amount.SetString(getStringFromInterface(fields["amount"]), 10); !ok {
+		return nil
+	}
+
+	return &models.TokenAllowance{
+		ContractID:       contractID,
+		Owner:            owner,
+		Spender:          spender,
+		Amount:           amount,
+		ExpirationLedger: uint32(getIntFromInterface(fields["expiration_ledger"])),
 	}
 }
 
+// ParseTokenAllowanceKey returns the owner and spender of an allowance key
+// ["Allowance", {from: OWNER, spender: SPENDER}]; both are "" for any other key
+func ParseTokenAllowanceKey(key interface{}) (owner, spender string) {
+	var keyArray []interface{}
+	keyBytes, _ := json.Marshal(key)
+	if err := json.Unmarshal(keyBytes, &keyArray); err != nil {
+		return "", ""
+	}
+
+	if len(keyArray) != 2 || keyArray[0] != "Allowance" {
+		return "", ""
+	}
+	fields := mapFields(keyArray[1])
+	from, _ := fields["from"].(string)
+	to, _ := fields["spender"].(string)
+	if from == "" || to == "" {
+		return "", ""
+	}
+	return from, to
+}
+
 // Helper functions
 
+// mapFields indexes a decoded ScMap (an array of {key, value} pairs) by its symbol keys
+func mapFields(v interface{}) map[string]interface{} {
+	var pairs []struct {
+		Key   string      `json:"key"`
+		Value interface{} `json:"value"`
+	}
+	valueBytes, _ := json.Marshal(v)
+	if err := json.Unmarshal(valueBytes, &pairs); err != nil {
+		return nil
+	}
+
+	fields := make(map[string]interface{}, len(pairs))
+	for _, pair := range pairs {
+		fields[pair.Key] = pair.Value
+	}
+	return fields
+}
+
 func getInt128FromInterface(v interface{}) util.Int128 {
-	str := getStringFromInterface(v)
 	var i128 util.Int128
-	i128.SetString(str, 10)
+	if n := getBigIntFromInterface(v); n != nil {
+		i128.Set(n)
+	}
 	return i128
 }
 
+// getBigIntFromInterface converts a decoded ScVal number to an exact big.Int
+// Returns nil if the value is not an integer
+func getBigIntFromInterface(v interface{}) *big.Int {
+	switch val := v.(type) {
+	case *big.Int:
+		return new(big.Int).Set(val)
+	case int:
+		return big.NewInt(int64(val))
+	case int32:
+		return big.NewInt(int64(val))
+	case int64:
+		return big.NewInt(val)
+	case uint32:
+		return new(big.Int).SetUint64(uint64(val))
+	case uint64:
+		return new(big.Int).SetUint64(val)
+	case float64:
+		// Values that went through a plain json.Unmarshal; only exact below 2^53
+		n, accuracy := new(big.Float).SetFloat64(val).Int(nil)
+		if accuracy != big.Exact {
+			return nil
+		}
+		return n
+	case json.Number:
+		n, ok := new(big.Int).SetString(val.String(), 10)
+		if !ok {
+			return nil
+		}
+		return n
+	case string:
+		n, ok := new(big.Int).SetString(strings.Trim(val, "\""), 10)
+		if !ok {
+			return nil
+		}
+		return n
+	default:
+		return nil
+	}
+}
+
 func getIntFromInterface(v interface{}) int64 {
 	switch val := v.(type) {
 	case int:
@@ -302,8 +439,15 @@ func getIntFromInterface(v interface{}) int64 {
 		return int64(val)
 	case int64:
 		return val
+	case uint32:
+		return int64(val)
+	case uint64:
+		return int64(val)
 	case float64:
 		return int64(val)
+	case json.Number:
+		i, _ := val.Int64()
+		return i
 	case string:
 		i, _ := strconv.ParseInt(val, 10, 64)
 		return i
@@ -1,6 +1,7 @@
 package parser
 
 import (
+	"encoding/json"
 	"testing"
 	"time"
 )
@@ -37,6 +38,44 @@ func TestParseTokenOperation_Transfer(t *testing.T) {
 	}
 }
 
+func TestParseTokenOperation_AmountAbove64Bits(t *testing.T) {
+	topics := []interface{}{"transfer", "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", "GBVFTZL5HIPT4PFQVTZVIWR77V7LWYCXU4CLYWWHHOEXB64XPG5LDMTU"}
+	value := "340282366920938463463374607431768211455"
+
+	op := ParseTokenOperation("event-123", "contract-456", 12345, time.Now(), 1, topics, value)
+	if op == nil || op.Amount == nil {
+		t.Fatal("Expected token operation with amount")
+	}
+	if op.Amount.String() != value {
+		t.Errorf("Amount = %v, want %v", op.Amount.String(), value)
+	}
+}
+
+func TestGetInt128FromInterface(t *testing.T) {
+	tests := []struct {
+		name     string
+		input    interface{}
+		expected string
+	}{
+		{"decimal string", "18446744073709551621", "18446744073709551621"},
+		{"negative string", "-170141183460469231731687303715884105728", "-170141183460469231731687303715884105728"},
+		{"json.Number", json.Number("9007199254740993"), "9007199254740993"},
+		{"uint64", uint64(18446744073709551615), "18446744073709551615"},
+		{"float64", float64(1000000), "1000000"},
+		{"invalid", "not-a-number", "0"},
+		{"nil", nil, "0"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			result := getInt128FromInterface(tt.input)
+			if result.String() != tt.expected {
+				t.Errorf("getInt128FromInterface(%v) = %v, want %v", tt.input, result.String(), tt.expected)
+			}
+		})
+	}
+}
+
 func TestParseTokenOperation_Mint(t *testing.T) {
 	topics := []interface{}{"mint", "admin-address", "recipient-address"}
 	value := "5000000"
@@ -316,7 +355,7 @@ func TestParseTokenBalance(t *testing.T) {
 	if balance.Address != "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H" {
 		t.Errorf("Address = %v, want GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H", balance.Address)
 	}
-	if balance.Balance != "10000000000" {
+	if balance.Balance.String() != "10000000000" {
 		t.Errorf("Balance = %v, want 10000000000", balance.Balance)
 	}
 }
@@ -361,6 +400,41 @@ func TestParseTokenBalance_InvalidKey(t *testing.T) {
 	}
 }
 
+func TestParseTokenAllowance(t *testing.T) {
+	key := []interface{}{"Allowance", []interface{}{
+		map[string]interface{}{"key": "from", "value": "GOWNER"},
+		map[string]interface{}{"key": "spender", "value": "CSPENDER"},
+	}}
+	value := []interface{}{
+		map[string]interface{}{"key": "amount", "value": "170141183460469231731687303715884105727"},
+		map[string]interface{}{"key": "expiration_ledger", "value": float64(5000)},
+	}
+
+	allowance := ParseTokenAllowance("contract-456", key, value)
+	if allowance == nil {
+		t.Fatal("Expected non-nil allowance")
+	}
+	if allowance.Owner != "GOWNER" || allowance.Spender != "CSPENDER" {
+		t.Errorf("Owner/Spender = %s/%s, want GOWNER/CSPENDER", allowance.Owner, allowance.Spender)
+	}
+	if allowance.Amount.String() != "170141183460469231731687303715884105727" {
+		t.Errorf("Amount = %s, want max i128", allowance.Amount.String())
+	}
+	if allowance.ExpirationLedger != 5000 {
+		t.Errorf("ExpirationLedger = %d, want 5000", allowance.ExpirationLedger)
+	}
+
+	// Balance keys and allowance keys missing a party are not allowances
+	for _, key := range []interface{}{
+		[]string{"Balance", "GOWNER"},
+		[]interface{}{"Allowance", []interface{}{map[string]interface{}{"key": "from", "value": "GOWNER"}}},
+	} {
+		if allowance := ParseTokenAllowance("contract-456", key, value); allowance != nil {
+			t.Errorf("ParseTokenAllowance(%v) = %+v, want nil", key, allowance)
+		}
+	}
+}
+
 func TestGetIntFromInterface(t *testing.T) {
 	tests := []struct {
 		name     string
@@ -371,6 +445,8 @@ func TestGetIntFromInterface(t *testing.T) {
 		{"int32", int32(42), 42},
 		{"int64", int64(42), 42},
 		{"float64", float64(42.0), 42},
+		{"uint64", uint64(42), 42},
+		{"json.Number", json.Number("42"), 42},
 		{"string", "42", 42},
 		{"invalid string", "not-a-number", 0},
 		{"nil", nil, 0},
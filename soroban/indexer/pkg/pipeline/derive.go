@@ -0,0 +1,224 @@
+package pipeline
+
+import (
+	"fmt"
+	"math/big"
+	"time"
+
+	"github.com/stellar/go/strkey"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/models/util"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+// TokenOperationFromEvent derives the token operation (transfer, mint, burn, ...) of an event
+// Returns nil when the event isn't a token operation. Works on freshly parsed and stored events
+func TokenOperationFromEvent(event *models.Event) *models.TokenOperation {
+	ledgerClosedAt, _ := time.Parse(time.RFC3339, event.LedgerClosedAt)
+	topics := []interface{}{}
+	parser.DecodeJSON(jsonBytes(event.Topic), &topics)
+	var value interface{}
+	parser.DecodeJSON(jsonBytes(event.Value), &value)
+
+	return parser.ParseTokenOperation(
+		event.ID,
+		event.ContractID,
+		uint32(event.Ledger),
+		ledgerClosedAt,
+		event.TxIndex,
+		topics,
+		value,
+	)
+}
+
+// StellarAssetFromEvent returns the metadata of the Stellar Asset Contract that emitted an event,
+// found from the classic asset topic of its token events; nil for any other event
+// The topic is only trusted when the asset's contract ID on the network is the event's contract
+func StellarAssetFromEvent(event *models.Event, networkPassphrase string) *models.TokenMetadata {
+	if networkPassphrase == "" {
+		return nil
+	}
+	topics := []interface{}{}
+	parser.DecodeJSON(jsonBytes(event.Topic), &topics)
+	asset, ok := parser.ParseStellarAsset(parser.ParseStellarAssetTopic(topics))
+	if !ok {
+		return nil
+	}
+
+	id, err := asset.ContractID(networkPassphrase)
+	if err != nil {
+		return nil
+	}
+	if contractID, err := strkey.Encode(strkey.VersionByteContract, id[:]); err != nil || contractID != event.ContractID {
+		return nil
+	}
+	return parser.StellarAssetMetadata(event.ContractID, asset)
+}
+
+// TokenBalanceChangesFromOperation derives the balance deltas of a transfer, mint, burn or
+// clawback: the amount leaves From and/or reaches To. Other operations move no balance
+func TokenBalanceChangesFromOperation(op *models.TokenOperation) []*models.TokenBalanceChange {
+	if op.Amount == nil {
+		return nil
+	}
+
+	closedAt, _ := time.Parse(time.RFC3339, op.LedgerClosedAt)
+	change := func(suffix, address string, delta *big.Int) *models.TokenBalanceChange {
+		row := &models.TokenBalanceChange{
+			ID:         op.ID + "-" + suffix,
+			ContractID: op.ContractID,
+			Address:    address,
+			Ledger:     uint32(op.Ledger),
+			TxIndex:    op.TxIndex,
+			ClosedAt:   closedAt,
+			Source:     models.BalanceSourceEvent,
+			Operation:  op.Type,
+		}
+		row.Delta.Set(delta)
+		return row
+	}
+	debit := new(big.Int).Neg(&op.Amount.Int)
+
+	switch op.Type {
+	case "transfer":
+		if op.To == nil {
+			return nil
+		}
+		return []*models.TokenBalanceChange{change("from", op.From, debit), change("to", *op.To, &op.Amount.Int)}
+	case "mint":
+		// From is the admin, nothing leaves it
+		if op.To == nil {
+			return nil
+		}
+		return []*models.TokenBalanceChange{change("to", *op.To, &op.Amount.Int)}
+	case "burn", "clawback":
+		// For clawback To is the admin, nothing reaches it
+		return []*models.TokenBalanceChange{change("from", op.From, debit)}
+	default:
+		return nil
+	}
+}
+
+// TokenSupplyDelta returns how much a token operation changes the token's total supply: mints add
+// their amount, burns and clawbacks remove it; nil for operations that don't change the supply
+func TokenSupplyDelta(op *models.TokenOperation) *big.Int {
+	if op.Amount == nil {
+		return nil
+	}
+	switch op.Type {
+	case "mint":
+		return new(big.Int).Set(&op.Amount.Int)
+	case "burn", "clawback":
+		return new(big.Int).Neg(&op.Amount.Int)
+	default:
+		return nil
+	}
+}
+
+// TokenAllowanceFromOperation returns the allowance an approve operation sets, nil for any other
+// operation
+func TokenAllowanceFromOperation(op *models.TokenOperation) *models.TokenAllowance {
+	if op.Type != "approve" || op.To == nil || op.Amount == nil || op.ExpirationLedger == nil {
+		return nil
+	}
+	return &models.TokenAllowance{
+		ContractID:       op.ContractID,
+		Owner:            op.From,
+		Spender:          *op.To,
+		Amount:           *op.Amount,
+		ExpirationLedger: uint32(*op.ExpirationLedger),
+		Ledger:           uint32(op.Ledger),
+	}
+}
+
+// TokenStateFromEntry derives the token metadata and token balance held in a contract data entry
+// Either is nil when the entry doesn't hold one; removed entries hold neither
+func TokenStateFromEntry(entry *models.ContractDataEntry) (*models.TokenMetadata, *models.TokenBalance) {
+	if entry.Removed() {
+		return nil, nil
+	}
+
+	// Unmarshal JSONB bytes back to interface{} for parsing
+	var keyInterface interface{}
+	var valInterface interface{}
+	if err := parser.DecodeJSON(entry.Key, &keyInterface); err != nil {
+		return nil, nil
+	}
+	if err := parser.DecodeJSON(entry.Val, &valInterface); err != nil {
+		return nil, nil
+	}
+
+	balance := parser.ParseTokenBalance(entry.ContractID, keyInterface, valInterface)
+	if balance != nil {
+		balance.LastModifiedLedger = entry.LastModifiedLedger
+	}
+	return parser.ParseTokenMetadata(entry.ContractID, keyInterface, valInterface), balance
+}
+
+// StorageBalanceChange builds the storage row recording a write or removal of a balance entry at
+// (ledger, txIndex, changeIndex), with its delta from the balance in change.Previous (0 without
+// one); removals store a balance of 0. Returns nil for other entries
+func StorageBalanceChange(change parser.ContractDataChange, ledger uint32, txIndex, changeIndex int32, closedAt time.Time) *models.TokenBalanceChange {
+	entry := change.Entry
+	balance, address := storageBalance(entry)
+	if change.Type == models.ContractDataRemoved {
+		var key interface{}
+		if err := parser.DecodeJSON(entry.Key, &key); err != nil {
+			return nil
+		}
+		address = parser.ParseTokenBalanceAddress(key)
+	}
+	if address == "" {
+		return nil
+	}
+
+	previous := new(big.Int)
+	if change.Previous != nil {
+		previous, _ = storageBalance(change.Previous)
+	}
+
+	row := &models.TokenBalanceChange{
+		ID:          fmt.Sprintf("%s-%d-%d-%d", entry.KeyHash, ledger, txIndex, changeIndex),
+		ContractID:  entry.ContractID,
+		Address:     address,
+		Ledger:      ledger,
+		TxIndex:     txIndex,
+		ChangeIndex: changeIndex,
+		ClosedAt:    closedAt,
+		Source:      models.BalanceSourceStorage,
+		Balance:     &util.Int128{},
+	}
+	row.Balance.Set(balance)
+	row.Delta.Sub(balance, previous)
+	return row
+}
+
+// storageBalance returns the balance a contract data entry holds and its owner, 0 and "" when
+// it is not a balance entry
+func storageBalance(entry *models.ContractDataEntry) (*big.Int, string) {
+	balance := new(big.Int)
+	_, tokenBalance := TokenStateFromEntry(entry)
+	if tokenBalance == nil {
+		return balance, ""
+	}
+	return balance.Set(&tokenBalance.Balance.Int), tokenBalance.Address
+}
+
+// jsonBytes returns the JSON held in a jsonb column: a string when freshly parsed, a pointer to
+// the driver's string or bytes when read back
+func jsonBytes(v interface{}) []byte {
+	switch v := v.(type) {
+	case string:
+		return []byte(v)
+	case []byte:
+		return v
+	case *interface{}:
+		if v == nil {
+			return nil
+		}
+		return jsonBytes(*v)
+	default:
+		return nil
+	}
+}
@@ -0,0 +1,228 @@
+package pipeline
+
+import (
+	"context"
+	"encoding/hex"
+	"fmt"
+	"time"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+// BatchFromLedger builds a batch directly from a LedgerCloseMeta
+// Every transaction in the ledger is included (failed ones too), together with the contract
+// events emitted by its operations, so no per-transaction RPC lookups are needed
+func BatchFromLedger(lcm xdr.LedgerCloseMeta, networkPassphrase string) (*Batch, error) {
+	ledger := lcm.LedgerSequence()
+	closedAt := time.Unix(lcm.LedgerCloseTime(), 0).UTC()
+
+	// TxProcessing is in apply order while the tx set is not, so match envelopes by hash
+	envelopes := make(map[xdr.Hash]xdr.TransactionEnvelope)
+	for _, envelope := range lcm.TransactionEnvelopes() {
+		hash, err := network.HashTransactionInEnvelope(envelope, networkPassphrase)
+		if err != nil {
+			return nil, fmt.Errorf("hash envelope in ledger %d: %w", ledger, err)
+		}
+		envelopes[hash] = envelope
+	}
+
+	var events []client.Event
+	var transactions []*Transaction
+
+	for i := 0; i < lcm.CountTransactions(); i++ {
+		hash := lcm.TransactionHash(i)
+		txHash := hex.EncodeToString(hash[:])
+
+		envelope, ok := envelopes[hash]
+		if !ok {
+			return nil, fmt.Errorf("envelope for tx %s not found in ledger %d (wrong network passphrase?)", txHash, ledger)
+		}
+
+		resultPair := lcm.TransactionResultPair(i)
+		meta := lcm.TxApplyProcessing(i)
+
+		envelopeXdr, err := xdr.MarshalBase64(envelope)
+		if err != nil {
+			return nil, fmt.Errorf("marshal envelope %s: %w", txHash, err)
+		}
+		resultXdr, err := xdr.MarshalBase64(resultPair.Result)
+		if err != nil {
+			return nil, fmt.Errorf("marshal result %s: %w", txHash, err)
+		}
+		metaXdr, err := xdr.MarshalBase64(meta)
+		if err != nil {
+			return nil, fmt.Errorf("marshal meta %s: %w", txHash, err)
+		}
+
+		successful := resultPair.Result.Successful()
+		status := "FAILED"
+		if successful {
+			status = "SUCCESS"
+		}
+
+		transactions = append(transactions, &Transaction{
+			Hash:      txHash,
+			EventHash: txHash,
+			RPC: &client.Transaction{
+				Hash:             txHash,
+				Status:           status,
+				Ledger:           ledger,
+				ApplicationOrder: int32(i + 1),
+				LedgerCloseTime:  closedAt.Unix(),
+				EnvelopeXdr:      envelopeXdr,
+				ResultXdr:        resultXdr,
+				ResultMetaXdr:    metaXdr,
+			},
+		})
+
+		txEvents, err := contractEventsFromMeta(meta, ledger, int32(i+1), closedAt, txHash, successful)
+		if err != nil {
+			return nil, fmt.Errorf("extract events from tx %s: %w", txHash, err)
+		}
+		events = append(events, txEvents...)
+	}
+
+	ledgerRow, err := parser.ParseLedger(lcm)
+	if err != nil {
+		return nil, fmt.Errorf("parse ledger %d: %w", ledger, err)
+	}
+
+	evictedKeys, err := lcm.EvictedLedgerKeys()
+	if err != nil {
+		return nil, fmt.Errorf("evicted keys of ledger %d: %w", ledger, err)
+	}
+
+	batch := NewBatch(events)
+	batch.Transactions = transactions
+	batch.Ledger = ledgerRow
+	batch.evicted = parser.ContractDataRemovals(ledger, evictedKeys)
+	return batch, nil
+}
+
+// operationEvents returns the contract events emitted by each operation of a transaction
+// V3 meta only carries Soroban events (always a single operation); V4 carries them per operation
+func operationEvents(meta xdr.TransactionMeta) [][]xdr.ContractEvent {
+	switch meta.V {
+	case 3:
+		v3 := meta.MustV3()
+		if v3.SorobanMeta == nil {
+			return nil
+		}
+		return [][]xdr.ContractEvent{v3.SorobanMeta.Events}
+	case 4:
+		v4 := meta.MustV4()
+		result := make([][]xdr.ContractEvent, 0, len(v4.Operations))
+		for _, op := range v4.Operations {
+			result = append(result, op.Events)
+		}
+		return result
+	default:
+		return nil
+	}
+}
+
+// contractEventsFromMeta converts the contract events in transaction meta into RPC-shaped events
+// IDs follow the RPC getEvents format so rows match those ingested through getEvents
+func contractEventsFromMeta(meta xdr.TransactionMeta, ledger uint32, applicationOrder int32, closedAt time.Time, txHash string, successful bool) ([]client.Event, error) {
+	var events []client.Event
+
+	for opIndex, opEvents := range operationEvents(meta) {
+		toid := int64(ledger)<<32 | int64(applicationOrder)<<12 | int64(opIndex)
+
+		for eventIndex, event := range opEvents {
+			if event.Body.V != 0 {
+				continue
+			}
+			body := event.Body.MustV0()
+
+			topics := make([]string, 0, len(body.Topics))
+			for _, topic := range body.Topics {
+				encoded, err := xdr.MarshalBase64(topic)
+				if err != nil {
+					return nil, err
+				}
+				topics = append(topics, encoded)
+			}
+			value, err := xdr.MarshalBase64(body.Data)
+			if err != nil {
+				return nil, err
+			}
+
+			contractID := ""
+			if event.ContractId != nil {
+				contractID, err = strkey.Encode(strkey.VersionByteContract, event.ContractId[:])
+				if err != nil {
+					return nil, err
+				}
+			}
+
+			id := fmt.Sprintf("%019d-%010d", toid, eventIndex)
+			events = append(events, client.Event{
+				ID:                       id,
+				Type:                     eventTypeName(event.Type),
+				Ledger:                   ledger,
+				LedgerClosedAt:           closedAt.Format(time.RFC3339),
+				ContractID:               contractID,
+				PagingToken:              id,
+				Topic:                    topics,
+				Value:                    value,
+				InSuccessfulContractCall: successful,
+				TxHash:                   txHash,
+			})
+		}
+	}
+
+	return events, nil
+}
+
+// eventTypeName maps an XDR event type to the name used by getEvents
+func eventTypeName(t xdr.ContractEventType) string {
+	switch t {
+	case xdr.ContractEventTypeSystem:
+		return "system"
+	case xdr.ContractEventTypeDiagnostic:
+		return "diagnostic"
+	default:
+		return "contract"
+	}
+}
+
+// ProcessLedger runs every transaction and event of a ledger through the pipeline in a single database transaction
+func (p *Pipeline) ProcessLedger(ctx context.Context, db *gorm.DB, lcm xdr.LedgerCloseMeta) (Stats, error) {
+	if err := p.ArchiveLedger(lcm); err != nil {
+		return Stats{}, err
+	}
+
+	batch, err := BatchFromLedger(lcm, p.networkPassphrase)
+	if err != nil {
+		return Stats{}, err
+	}
+	return p.ProcessBatch(ctx, db, batch)
+}
+
+// ArchiveLedger stores a ledger's LedgerCloseMeta in the archive (no-op without one)
+func (p *Pipeline) ArchiveLedger(lcm xdr.LedgerCloseMeta) error {
+	if p.archive == nil {
+		return nil
+	}
+
+	encoded, err := xdr.MarshalBase64(lcm)
+	if err != nil {
+		return fmt.Errorf("marshal ledger %d meta: %w", lcm.LedgerSequence(), err)
+	}
+	if err := p.archive.Put(&archive.Record{
+		Ledger:            lcm.LedgerSequence(),
+		NetworkPassphrase: p.networkPassphrase,
+		LedgerCloseMeta:   encoded,
+	}); err != nil {
+		return fmt.Errorf("archive ledger %d: %w", lcm.LedgerSequence(), err)
+	}
+	return nil
+}
@@ -0,0 +1,215 @@
+package pipeline
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// testLedgerTx is a transaction to place in a test ledger
+type testLedgerTx struct {
+	envelope string // base64 TransactionEnvelope
+	success  bool
+	meta     xdr.TransactionMeta
+}
+
+// createLedgerCloseMeta builds a V1 LedgerCloseMeta whose transactions are applied in the given order
+func createLedgerCloseMeta(t *testing.T, sequence uint32, closeTime int64, txs []testLedgerTx) xdr.LedgerCloseMeta {
+	var envelopes []xdr.TransactionEnvelope
+	var processing []xdr.TransactionResultMeta
+
+	for _, tx := range txs {
+		var envelope xdr.TransactionEnvelope
+		if err := xdr.SafeUnmarshalBase64(tx.envelope, &envelope); err != nil {
+			t.Fatalf("Failed to decode envelope: %v", err)
+		}
+		hash, err := network.HashTransactionInEnvelope(envelope, network.TestNetworkPassphrase)
+		if err != nil {
+			t.Fatalf("Failed to hash envelope: %v", err)
+		}
+
+		code := xdr.TransactionResultCodeTxFailed
+		if tx.success {
+			code = xdr.TransactionResultCodeTxSuccess
+		}
+
+		envelopes = append(envelopes, envelope)
+		processing = append(processing, xdr.TransactionResultMeta{
+			Result: xdr.TransactionResultPair{
+				TransactionHash: hash,
+				Result: xdr.TransactionResult{
+					FeeCharged: 100,
+					Result:     xdr.TransactionResultResult{Code: code, Results: &[]xdr.OperationResult{}},
+				},
+			},
+			TxApplyProcessing: tx.meta,
+		})
+	}
+
+	// Reverse the tx set so apply order differs from set order
+	for i, j := 0, len(envelopes)-1; i < j; i, j = i+1, j-1 {
+		envelopes[i], envelopes[j] = envelopes[j], envelopes[i]
+	}
+
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{
+					LedgerSeq:     xdr.Uint32(sequence),
+					LedgerVersion: 22,
+					ScpValue:      xdr.StellarValue{CloseTime: xdr.TimePoint(closeTime)},
+				},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V: 1,
+				V1TxSet: &xdr.TransactionSetV1{
+					Phases: []xdr.TransactionPhase{
+						{
+							V: 0,
+							V0Components: &[]xdr.TxSetComponent{
+								{
+									Type:                  xdr.TxSetComponentTypeTxsetCompTxsMaybeDiscountedFee,
+									TxsMaybeDiscountedFee: &xdr.TxSetComponentTxsMaybeDiscountedFee{Txs: envelopes},
+								},
+							},
+						},
+					},
+				},
+			},
+			TxProcessing: processing,
+		},
+	}
+}
+
+// createTransferMeta creates V3 meta with one transfer event and a ["Balance", holder] storage write
+func createTransferMeta(t *testing.T, contractID xdr.ContractId, amount int64) xdr.TransactionMeta {
+	var meta xdr.TransactionMeta
+	if err := xdr.SafeUnmarshalBase64(createBalanceMeta(t, contractID, testHolder, amount), &meta); err != nil {
+		t.Fatalf("Failed to decode meta: %v", err)
+	}
+
+	event := createTransferEvent(t, "", "", "", 0, amount)
+	topics := make([]xdr.ScVal, len(event.Topic))
+	for i, topic := range event.Topic {
+		if err := xdr.SafeUnmarshalBase64(topic, &topics[i]); err != nil {
+			t.Fatalf("Failed to decode topic: %v", err)
+		}
+	}
+	var value xdr.ScVal
+	if err := xdr.SafeUnmarshalBase64(event.Value, &value); err != nil {
+		t.Fatalf("Failed to decode value: %v", err)
+	}
+
+	meta.V3.SorobanMeta = &xdr.SorobanTransactionMeta{
+		Events: []xdr.ContractEvent{
+			{
+				ContractId: &contractID,
+				Type:       xdr.ContractEventTypeContract,
+				Body:       xdr.ContractEventBody{V: 0, V0: &xdr.ContractEventV0{Topics: topics, Data: value}},
+			},
+		},
+		ReturnValue: xdr.ScVal{Type: xdr.ScValTypeScvVoid},
+	}
+	return meta
+}
+
+func TestBatchFromLedger(t *testing.T) {
+	rawContractID, contractID := testContractID()
+
+	successEnvelope := createUploadEnvelope(t, []byte{0x01})
+	failedEnvelope := createUploadEnvelope(t, []byte{0x02})
+	lcm := createLedgerCloseMeta(t, 100, 1704067200, []testLedgerTx{
+		{envelope: successEnvelope, success: true, meta: createTransferMeta(t, rawContractID, 500)},
+		{envelope: failedEnvelope, success: false, meta: xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}},
+	})
+
+	batch, err := BatchFromLedger(lcm, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("BatchFromLedger() error = %v", err)
+	}
+
+	if len(batch.Transactions) != 2 {
+		t.Fatalf("Transactions = %d, want 2 (failed transactions included)", len(batch.Transactions))
+	}
+	first, second := batch.Transactions[0].RPC, batch.Transactions[1].RPC
+	if first.EnvelopeXdr != successEnvelope || first.Status != "SUCCESS" || first.ApplicationOrder != 1 {
+		t.Errorf("First tx = %+v, want successful tx applied first", first)
+	}
+	if second.EnvelopeXdr != failedEnvelope || second.Status != "FAILED" || second.ApplicationOrder != 2 {
+		t.Errorf("Second tx = %+v, want failed tx applied second", second)
+	}
+	if first.Ledger != 100 || first.LedgerCloseTime != 1704067200 {
+		t.Errorf("First tx ledger = %d at %d, want 100 at 1704067200", first.Ledger, first.LedgerCloseTime)
+	}
+
+	if len(batch.Events) != 1 {
+		t.Fatalf("Events = %d, want 1", len(batch.Events))
+	}
+	event := batch.Events[0]
+	// toid = 100<<32 | 1<<12
+	if event.ID != "0000000429496733696-0000000000" {
+		t.Errorf("Event ID = %s, want 0000000429496733696-0000000000", event.ID)
+	}
+	if event.ContractID != contractID || event.Type != "contract" || event.TxHash != batch.Transactions[0].Hash {
+		t.Errorf("Event = %+v, want contract event from first tx", event)
+	}
+	if event.LedgerClosedAt != "2024-01-01T00:00:00Z" || !event.InSuccessfulContractCall {
+		t.Errorf("Event = %+v, want closed at 2024-01-01T00:00:00Z in successful call", event)
+	}
+}
+
+func TestBatchFromLedger_WrongPassphrase(t *testing.T) {
+	lcm := createLedgerCloseMeta(t, 100, 1704067200, []testLedgerTx{
+		{envelope: createUploadEnvelope(t, []byte{0x01}), success: true, meta: xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}},
+	})
+
+	if _, err := BatchFromLedger(lcm, network.PublicNetworkPassphrase); err == nil {
+		t.Error("BatchFromLedger() error = nil, want error for envelopes hashed under another network")
+	}
+}
+
+func TestProcessLedger_DoesNotFetchTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+
+	lcm := createLedgerCloseMeta(t, 100, 1704067200, []testLedgerTx{
+		{envelope: createUploadEnvelope(t, []byte{0x01}), success: true, meta: createTransferMeta(t, rawContractID, 500)},
+		{envelope: createUploadEnvelope(t, []byte{0x02}), success: false, meta: xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}},
+	})
+
+	fetcher := &fakeFetcher{}
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+
+	stats, err := p.ProcessLedger(context.Background(), db, lcm)
+	if err != nil {
+		t.Fatalf("ProcessLedger() error = %v", err)
+	}
+
+	if len(fetcher.calls) != 0 {
+		t.Errorf("GetTransaction calls = %v, want none", fetcher.calls)
+	}
+	if stats.Events != 1 || stats.TokenOps != 1 || stats.Transactions != 2 {
+		t.Errorf("Stats = %+v, want 1 event, 1 token op and 2 transactions", stats)
+	}
+
+	var balance models.TokenBalance
+	if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err != nil {
+		t.Fatalf("Expected token balance derived from meta: %v", err)
+	}
+	if balance.Balance.String() != "500" {
+		t.Errorf("Balance = %v, want 500", balance.Balance)
+	}
+
+	ledger, err := models.GetLedger(db, 100)
+	if err != nil {
+		t.Fatalf("Expected ledger row: %v", err)
+	}
+	if ledger.TransactionCount != 2 || ledger.FailedTransactionCount != 1 || ledger.OperationCount != 2 {
+		t.Errorf("Ledger = %+v, want 2 transactions (1 failed) and 2 operations", ledger)
+	}
+}
@@ -0,0 +1,296 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+// TransactionFetcher fetches a single transaction by hash (satisfied by *client.Client)
+type TransactionFetcher interface {
+	GetTransaction(ctx context.Context, hash string) (*client.Transaction, error)
+}
+
+// Transaction is an RPC transaction together with the hash it is stored under
+type Transaction struct {
+	Hash      string // Resolved hash (RPC hash, or recomputed from the envelope)
+	EventHash string // Hash referenced by the events that led us to this transaction
+	RPC       *client.Transaction
+
+	// Rows decoded from the XDR by prepare, ready to be written
+	prepared        bool
+	parsed          *models.Transaction
+	parseErr        error
+	operations      []*models.Operation
+	operationsErr   error
+	contractData    *parser.ContractDataChanges
+	contractDataErr error
+	instances       map[string]*models.TokenMetadata
+	instancesErr    error
+	contractCode    []*models.ContractCode
+	diagnostics     []*models.DiagnosticEvent
+	diagnosticsErr  error
+}
+
+// prepare decodes every row derived from the transaction's XDR
+// It is safe to call more than once; only the first call does any work
+func (t *Transaction) prepare() {
+	if t.prepared {
+		return
+	}
+	t.prepared = true
+
+	t.parsed, t.parseErr = parser.ParseTransactionWithHash(*t.RPC, t.Hash)
+	t.operations, t.operationsErr = parser.ParseOperations(t.Hash, t.RPC.EnvelopeXdr)
+	parser.ApplyOperationResults(t.operations, t.RPC.ResultXdr)
+	if t.RPC.ResultMetaXdr != "" {
+		t.contractData, t.contractDataErr = parser.ExtractContractDataChanges(t.RPC.Ledger, t.RPC.ResultMetaXdr)
+		t.instances, t.instancesErr = parser.ExtractContractInstanceFromMeta(t.Hash, t.RPC.ResultMetaXdr)
+		t.diagnostics, t.diagnosticsErr = parser.ExtractDiagnosticEvents(t.Hash, t.RPC.Ledger, t.RPC.ResultMetaXdr)
+	}
+	// Envelopes without uploads (or that fail to decode) simply yield no code
+	t.contractCode, _ = parser.ExtractContractCodeFromEnvelope(t.Hash, t.RPC.Ledger, t.RPC.LedgerCloseTime, t.RPC.EnvelopeXdr)
+}
+
+// Stats counts the rows written for a batch
+type Stats struct {
+	Events           int
+	TokenOps         int
+	Transactions     int
+	TxWithMeta       int
+	Operations       int
+	ContractCode     int
+	ContractData     int
+	Contracts        int
+	DiagnosticEvents int
+}
+
+// Add accumulates another batch's counts
+func (s *Stats) Add(other Stats) {
+	s.Events += other.Events
+	s.TokenOps += other.TokenOps
+	s.Transactions += other.Transactions
+	s.TxWithMeta += other.TxWithMeta
+	s.Operations += other.Operations
+	s.ContractCode += other.ContractCode
+	s.ContractData += other.ContractData
+	s.Contracts += other.Contracts
+	s.DiagnosticEvents += other.DiagnosticEvents
+}
+
+// Batch carries one page of events (or one ledger) through the pipeline stages
+// Each stage reads what earlier stages produced and appends its own results
+type Batch struct {
+	Events       []client.Event
+	Transactions []*Transaction
+	Ledger       *models.Ledger // Header row, set when the batch was built from LedgerCloseMeta
+
+	evicted     []parser.ContractDataChange // Contract data the ledger evicted, after its transactions
+	events      []*models.Event             // Parsed events, in input order
+	txHashes    []string                    // Unique tx hashes, in first-seen order
+	contractIDs map[string]bool
+
+	Stats Stats
+}
+
+// NewBatch creates a batch for a page of RPC events
+func NewBatch(events []client.Event) *Batch {
+	return &Batch{
+		Events:      events,
+		contractIDs: make(map[string]bool),
+	}
+}
+
+// Stage is a single ordered step of the pipeline
+// Prepare stages run before the database transaction opens (RPC calls, XDR decoding) and get the
+// plain connection; store stages run inside the batch's database transaction and only write.
+// Returning an error from any stage aborts the batch (and rolls back its writes)
+type Stage struct {
+	Name string
+	Run  func(ctx context.Context, tx *gorm.DB, batch *Batch) error
+}
+
+// DefaultMaxConcurrency bounds concurrent transaction fetching and decoding
+const DefaultMaxConcurrency = 10
+
+// Pipeline turns pages of RPC events into database rows
+// Live polling and backfill both go through the same ordered stages so their output is identical
+type Pipeline struct {
+	fetcher           TransactionFetcher
+	logger            *logrus.Logger
+	networkPassphrase string
+	maxConcurrency    int
+	archive           archive.Sink // Receives raw payloads before they are stored (may be nil)
+	prepare           []Stage
+	stages            []Stage
+}
+
+// New creates a pipeline with the default stage order
+func New(fetcher TransactionFetcher, logger *logrus.Logger, networkPassphrase string) *Pipeline {
+	p := &Pipeline{
+		fetcher:           fetcher,
+		logger:            logger,
+		networkPassphrase: networkPassphrase,
+		maxConcurrency:    DefaultMaxConcurrency,
+	}
+
+	p.prepare = []Stage{
+		{Name: "parse_events", Run: p.parseEvents},
+		{Name: "fetch_transactions", Run: p.fetchTransactions},
+		{Name: "parse_transactions", Run: p.parseTransactions},
+	}
+
+	p.stages = []Stage{
+		{Name: "ledger", Run: p.storeLedger},
+		{Name: "events", Run: p.storeEvents},
+		{Name: "token_operations", Run: p.storeTokenOperations},
+		{Name: "transactions", Run: p.storeTransactions},
+		{Name: "diagnostic_events", Run: p.storeDiagnosticEvents},
+		{Name: "contract_data", Run: p.storeContractData},
+		{Name: "operations", Run: p.storeOperations},
+		{Name: "contract_code", Run: p.storeContractCode},
+		{Name: "contract_state", Run: p.refreshContractState},
+	}
+
+	return p
+}
+
+// SetNetworkPassphrase sets the passphrase used to recompute transaction hashes
+func (p *Pipeline) SetNetworkPassphrase(passphrase string) {
+	p.networkPassphrase = passphrase
+}
+
+// SetMaxConcurrency bounds the number of concurrent RPC fetches and decoders (<= 0 keeps the default)
+func (p *Pipeline) SetMaxConcurrency(n int) {
+	if n > 0 {
+		p.maxConcurrency = n
+	}
+}
+
+// SetArchive makes every batch store its raw RPC payloads in sink before its rows are written
+// An archive failure aborts the batch, so the archive holds everything the database does
+func (p *Pipeline) SetArchive(sink archive.Sink) {
+	p.archive = sink
+}
+
+// Stages returns the ordered stage list: prepare stages first, then store stages
+func (p *Pipeline) Stages() []Stage {
+	stages := make([]Stage, 0, len(p.prepare)+len(p.stages))
+	stages = append(stages, p.prepare...)
+	return append(stages, p.stages...)
+}
+
+// Process runs a page of events through every stage in a single database transaction
+// Cursor updates are left to the caller
+func (p *Pipeline) Process(ctx context.Context, db *gorm.DB, events []client.Event) (Stats, error) {
+	return p.ProcessBatch(ctx, db, NewBatch(events))
+}
+
+// ProcessTransactions runs a page of transactions (e.g. from getTransactions) through every stage
+// in a single database transaction
+func (p *Pipeline) ProcessTransactions(ctx context.Context, db *gorm.DB, txs []client.Transaction) (Stats, error) {
+	batch := NewBatch(nil)
+	for i := range txs {
+		rpcTx := &txs[i]
+		batch.Transactions = append(batch.Transactions, &Transaction{
+			Hash:      p.resolveTransactionHash(rpcTx.Hash, rpcTx),
+			EventHash: rpcTx.Hash,
+			RPC:       rpcTx,
+		})
+	}
+	return p.ProcessBatch(ctx, db, batch)
+}
+
+// ProcessBatch runs a batch through the prepare stages, then through the store stages in a
+// single database transaction, so the transaction is only held open while writing
+// Transactions already set on the batch are not fetched again
+func (p *Pipeline) ProcessBatch(ctx context.Context, db *gorm.DB, batch *Batch) (Stats, error) {
+	start := time.Now()
+
+	for _, stage := range p.prepare {
+		if err := stage.Run(ctx, db, batch); err != nil {
+			return batch.Stats, fmt.Errorf("%s stage: %w", stage.Name, err)
+		}
+	}
+
+	// Ledger batches were archived as LedgerCloseMeta by ProcessLedger
+	if p.archive != nil && batch.Ledger == nil {
+		if err := p.archiveBatch(batch); err != nil {
+			return batch.Stats, err
+		}
+	}
+
+	err := db.Transaction(func(tx *gorm.DB) error {
+		for _, stage := range p.stages {
+			if err := stage.Run(ctx, tx, batch); err != nil {
+				return fmt.Errorf("%s stage: %w", stage.Name, err)
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return batch.Stats, err
+	}
+
+	lastLedger := uint32(0)
+	if len(batch.Events) > 0 {
+		lastLedger = batch.Events[len(batch.Events)-1].Ledger
+	} else if len(batch.Transactions) > 0 {
+		lastLedger = batch.Transactions[len(batch.Transactions)-1].RPC.Ledger
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"events":       batch.Stats.Events,
+		"transactions": batch.Stats.Transactions,
+		"txWithMeta":   batch.Stats.TxWithMeta,
+		"operations":   batch.Stats.Operations,
+		"contractCode": batch.Stats.ContractCode,
+		"contractData": batch.Stats.ContractData,
+		"tokenOps":     batch.Stats.TokenOps,
+		"contracts":    batch.Stats.Contracts,
+		"diagnostics":  batch.Stats.DiagnosticEvents,
+		"ledger":       lastLedger,
+		"duration":     time.Since(start),
+	}).Info("Batch processed successfully")
+
+	return batch.Stats, nil
+}
+
+// archiveBatch stores the batch's transactions and events, one record per ledger
+func (p *Pipeline) archiveBatch(batch *Batch) error {
+	records := make(map[uint32]*archive.Record)
+	var ledgers []uint32
+	record := func(ledger uint32) *archive.Record {
+		if r, ok := records[ledger]; ok {
+			return r
+		}
+		r := &archive.Record{Ledger: ledger, NetworkPassphrase: p.networkPassphrase}
+		records[ledger] = r
+		ledgers = append(ledgers, ledger)
+		return r
+	}
+
+	for _, t := range batch.Transactions {
+		r := record(t.RPC.Ledger)
+		r.Transactions = append(r.Transactions, *t.RPC)
+	}
+	for _, event := range batch.Events {
+		r := record(event.Ledger)
+		r.Events = append(r.Events, event)
+	}
+
+	for _, ledger := range ledgers {
+		if err := p.archive.Put(records[ledger]); err != nil {
+			return fmt.Errorf("archive ledger %d: %w", ledger, err)
+		}
+	}
+	return nil
+}
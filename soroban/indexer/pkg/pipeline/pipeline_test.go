@@ -0,0 +1,598 @@
+package pipeline
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+const (
+	testSourceAccount = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	testHolder        = "GBVFTZL5HIPT4PFQVTZVIWR77V7LWYCXU4CLYWWHHOEXB64XPG5LDMTU"
+)
+
+// fakeFetcher serves transactions from a map keyed by hash
+// delay, when set, is applied per hash to make concurrent fetches finish out of order
+type fakeFetcher struct {
+	txs   map[string]*client.Transaction
+	delay map[string]time.Duration
+	calls []string
+
+	mu       sync.Mutex
+	inFlight int
+	maxSeen  int
+}
+
+func (f *fakeFetcher) GetTransaction(ctx context.Context, hash string) (*client.Transaction, error) {
+	f.mu.Lock()
+	f.calls = append(f.calls, hash)
+	f.inFlight++
+	if f.inFlight > f.maxSeen {
+		f.maxSeen = f.inFlight
+	}
+	f.mu.Unlock()
+
+	time.Sleep(f.delay[hash])
+
+	f.mu.Lock()
+	f.inFlight--
+	f.mu.Unlock()
+
+	tx, ok := f.txs[hash]
+	if !ok {
+		return nil, errors.New("transaction not found")
+	}
+	return tx, nil
+}
+
+// setupTestDB creates an in-memory SQLite database with every table the pipeline writes
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	if err != nil {
+		t.Fatalf("Failed to open test database: %v", err)
+	}
+
+	if err := db.AutoMigrate(
+		&models.Event{},
+		&models.Transaction{},
+		&models.Operation{},
+		&models.TokenMetadata{},
+		&models.TokenOperation{},
+		&models.TokenBalance{},
+		&models.TokenBalanceChange{},
+		&models.TokenSupply{},
+		&models.TokenAllowance{},
+		&models.DiagnosticEvent{},
+		&models.ContractDataEntry{},
+		&models.ContractDataHistory{},
+		&models.ContractCode{},
+		&models.Ledger{},
+	); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+
+	return db
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+func testContractID() (xdr.ContractId, string) {
+	var raw xdr.ContractId
+	for i := range raw {
+		raw[i] = byte(i + 1)
+	}
+	encoded, _ := strkey.Encode(strkey.VersionByteContract, raw[:])
+	return raw, encoded
+}
+
+// createUploadEnvelope creates a V1 envelope with a single UploadContractWasm operation
+func createUploadEnvelope(t *testing.T, wasm []byte) string {
+	source := xdr.MustAddress(testSourceAccount)
+	envelope := xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: source.ToMuxedAccount(),
+				Fee:           100,
+				SeqNum:        1,
+				Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+				Memo:          xdr.Memo{Type: xdr.MemoTypeMemoNone},
+				Operations: []xdr.Operation{
+					{
+						Body: xdr.OperationBody{
+							Type: xdr.OperationTypeInvokeHostFunction,
+							InvokeHostFunctionOp: &xdr.InvokeHostFunctionOp{
+								HostFunction: xdr.HostFunction{
+									Type: xdr.HostFunctionTypeHostFunctionTypeUploadContractWasm,
+									Wasm: &wasm,
+								},
+							},
+						},
+					},
+				},
+			},
+		},
+	}
+
+	encoded, err := xdr.MarshalBase64(envelope)
+	if err != nil {
+		t.Fatalf("Failed to marshal envelope: %v", err)
+	}
+	return encoded
+}
+
+// balanceEntry creates a ["Balance", holder] storage entry
+func balanceEntry(contractID xdr.ContractId, holder string, amount int64) xdr.LedgerEntry {
+	balanceSym := xdr.ScSymbol("Balance")
+	holderAccount := xdr.MustAddress(holder)
+	keyVec := xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &balanceSym},
+		{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &holderAccount}},
+	}
+	keyVecPtr := &keyVec
+
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 100,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &keyVecPtr},
+				Val:        xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: xdr.Uint64(amount)}},
+				Durability: xdr.ContractDataDurabilityPersistent,
+			},
+		},
+	}
+}
+
+// allowanceEntry is the temporary ["Allowance", {from: owner, spender: spender}] entry of a token
+func allowanceEntry(contractID xdr.ContractId, owner, spender string, amount int64, expiration uint32) xdr.LedgerEntry {
+	allowanceSym, fromSym, spenderSym := xdr.ScSymbol("Allowance"), xdr.ScSymbol("from"), xdr.ScSymbol("spender")
+	amountSym, expirationSym := xdr.ScSymbol("amount"), xdr.ScSymbol("expiration_ledger")
+	ownerAccount, spenderAccount := xdr.MustAddress(owner), xdr.MustAddress(spender)
+	expirationLedger := xdr.Uint32(expiration)
+
+	keyMap := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &fromSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &ownerAccount}}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &spenderSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &spenderAccount}}},
+	}
+	keyVec := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &allowanceSym},
+		{Type: xdr.ScValTypeScvMap, Map: &keyMap},
+	}
+	valMap := &xdr.ScMap{
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &amountSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: xdr.Uint64(amount)}}},
+		{Key: xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &expirationSym}, Val: xdr.ScVal{Type: xdr.ScValTypeScvU32, U32: &expirationLedger}},
+	}
+
+	return xdr.LedgerEntry{
+		LastModifiedLedgerSeq: 100,
+		Data: xdr.LedgerEntryData{
+			Type: xdr.LedgerEntryTypeContractData,
+			ContractData: &xdr.ContractDataEntry{
+				Contract:   xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeContract, ContractId: &contractID},
+				Key:        xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &keyVec},
+				Val:        xdr.ScVal{Type: xdr.ScValTypeScvMap, Map: &valMap},
+				Durability: xdr.ContractDataDurabilityTemporary,
+			},
+		},
+	}
+}
+
+// createMeta creates V3 transaction meta with the given changes after the transaction
+func createMeta(t *testing.T, changes ...xdr.LedgerEntryChange) string {
+	meta := xdr.TransactionMeta{
+		V:  3,
+		V3: &xdr.TransactionMetaV3{TxChangesAfter: changes},
+	}
+
+	encoded, err := xdr.MarshalBase64(meta)
+	if err != nil {
+		t.Fatalf("Failed to marshal meta: %v", err)
+	}
+	return encoded
+}
+
+// createBalanceMeta creates V3 transaction meta that writes a ["Balance", holder] storage entry
+func createBalanceMeta(t *testing.T, contractID xdr.ContractId, holder string, amount int64) string {
+	entry := balanceEntry(contractID, holder, amount)
+	return createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &entry})
+}
+
+// createTransferEvent creates an RPC transfer event with XDR-encoded topics and value
+func createTransferEvent(t *testing.T, id, contractID, txHash string, ledger uint32, amount int64) client.Event {
+	return createTokenEvent(t, "transfer", id, contractID, txHash, ledger, amount)
+}
+
+// createTokenEvent creates a token event named name with topics [name, source account, holder]
+func createTokenEvent(t *testing.T, name, id, contractID, txHash string, ledger uint32, amount int64) client.Event {
+	nameSym := xdr.ScSymbol(name)
+	from := xdr.MustAddress(testSourceAccount)
+	to := xdr.MustAddress(testHolder)
+
+	topics := []xdr.ScVal{
+		{Type: xdr.ScValTypeScvSymbol, Sym: &nameSym},
+		{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &from}},
+		{Type: xdr.ScValTypeScvAddress, Address: &xdr.ScAddress{Type: xdr.ScAddressTypeScAddressTypeAccount, AccountId: &to}},
+	}
+
+	encodedTopics := make([]string, 0, len(topics))
+	for _, topic := range topics {
+		encoded, err := xdr.MarshalBase64(topic)
+		if err != nil {
+			t.Fatalf("Failed to marshal topic: %v", err)
+		}
+		encodedTopics = append(encodedTopics, encoded)
+	}
+
+	value, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: xdr.Uint64(amount)}})
+	if err != nil {
+		t.Fatalf("Failed to marshal value: %v", err)
+	}
+
+	return client.Event{
+		ID:                       id,
+		Type:                     "contract",
+		Ledger:                   ledger,
+		LedgerClosedAt:           "2024-01-01T00:00:00Z",
+		ContractID:               contractID,
+		PagingToken:              id,
+		Topic:                    encodedTopics,
+		Value:                    value,
+		InSuccessfulContractCall: true,
+		TxHash:                   txHash,
+	}
+}
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	var count int64
+	if err := db.Model(model).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count rows: %v", err)
+	}
+	return count
+}
+
+func TestProcess_RunsAllStages(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+
+	envelope := createUploadEnvelope(t, []byte{0x00, 0x61, 0x73, 0x6d})
+	txHash, err := parser.ComputeTransactionHash(envelope, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("ComputeTransactionHash() error = %v", err)
+	}
+
+	fetcher := &fakeFetcher{txs: map[string]*client.Transaction{
+		txHash: {
+			Hash:            txHash,
+			Status:          "SUCCESS",
+			Ledger:          100,
+			LedgerCloseTime: 1704067200,
+			EnvelopeXdr:     envelope,
+			ResultMetaXdr:   createBalanceMeta(t, rawContractID, testHolder, 5000),
+		},
+	}}
+
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+	events := []client.Event{
+		createTransferEvent(t, "0000000429496729601-0000000001", contractID, txHash, 100, 5000),
+		createTransferEvent(t, "0000000429496729601-0000000002", contractID, txHash, 100, 7000),
+	}
+
+	stats, err := p.Process(context.Background(), db, events)
+	if err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	want := Stats{Events: 2, TokenOps: 2, Transactions: 1, TxWithMeta: 1, Operations: 1, ContractCode: 1, ContractData: 1, Contracts: 1}
+	if stats != want {
+		t.Errorf("Stats = %+v, want %+v", stats, want)
+	}
+
+	// Both events reference the same transaction - it must only be fetched once
+	if len(fetcher.calls) != 1 {
+		t.Errorf("GetTransaction calls = %d, want 1", len(fetcher.calls))
+	}
+
+	if n := countRows(t, db, &models.Event{}); n != 2 {
+		t.Errorf("events = %d, want 2", n)
+	}
+	if n := countRows(t, db, &models.TokenOperation{}); n != 2 {
+		t.Errorf("token_operations = %d, want 2", n)
+	}
+	if n := countRows(t, db, &models.Transaction{}); n != 1 {
+		t.Errorf("transactions = %d, want 1", n)
+	}
+	if n := countRows(t, db, &models.Operation{}); n != 1 {
+		t.Errorf("operations = %d, want 1", n)
+	}
+	if n := countRows(t, db, &models.ContractCode{}); n != 1 {
+		t.Errorf("contract_code = %d, want 1", n)
+	}
+
+	var balance models.TokenBalance
+	if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err != nil {
+		t.Fatalf("Expected token balance derived from meta: %v", err)
+	}
+	if balance.Balance.String() != "5000" {
+		t.Errorf("Balance = %v, want 5000", balance.Balance)
+	}
+}
+
+func TestProcess_RollsBackOnStageError(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+	p.stages = append(p.stages, Stage{
+		Name: "failing",
+		Run: func(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+			return errors.New("boom")
+		},
+	})
+
+	events := []client.Event{createTransferEvent(t, "0000000429496729601-0000000001", contractID, "", 100, 1)}
+	_, err := p.Process(context.Background(), db, events)
+	if err == nil {
+		t.Fatal("Process() error = nil, want stage error")
+	}
+	if err.Error() != "failing stage: boom" {
+		t.Errorf("Process() error = %v, want stage name in error", err)
+	}
+
+	if n := countRows(t, db, &models.Event{}); n != 0 {
+		t.Errorf("events = %d, want 0 after rollback", n)
+	}
+}
+
+func TestProcessTransactions_StoresFailedTransactions(t *testing.T) {
+	db := setupTestDB(t)
+	envelope := createUploadEnvelope(t, []byte{0x01})
+
+	fetcher := &fakeFetcher{}
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+
+	results := []xdr.OperationResult{{
+		Code: xdr.OperationResultCodeOpInner,
+		Tr: &xdr.OperationResultTr{
+			Type:                     xdr.OperationTypeInvokeHostFunction,
+			InvokeHostFunctionResult: &xdr.InvokeHostFunctionResult{Code: xdr.InvokeHostFunctionResultCodeInvokeHostFunctionTrapped},
+		},
+	}}
+	result, err := xdr.MarshalBase64(xdr.TransactionResult{
+		FeeCharged: 100,
+		Result:     xdr.TransactionResultResult{Code: xdr.TransactionResultCodeTxFailed, Results: &results},
+	})
+	if err != nil {
+		t.Fatalf("Failed to marshal result: %v", err)
+	}
+
+	stats, err := p.ProcessTransactions(context.Background(), db, []client.Transaction{
+		{Hash: "hash-failed", Status: "FAILED", Ledger: 100, LedgerCloseTime: 1704067200, EnvelopeXdr: envelope, ResultXdr: result},
+	})
+	if err != nil {
+		t.Fatalf("ProcessTransactions() error = %v", err)
+	}
+
+	if len(fetcher.calls) != 0 {
+		t.Errorf("GetTransaction calls = %v, want none", fetcher.calls)
+	}
+	if stats.Transactions != 1 || stats.Operations != 1 {
+		t.Errorf("Stats = %+v, want 1 transaction and 1 operation", stats)
+	}
+
+	var stored models.Transaction
+	if err := db.First(&stored, "id = ?", "hash-failed").Error; err != nil {
+		t.Fatalf("Expected failed transaction to be stored: %v", err)
+	}
+	if stored.Status != "FAILED" {
+		t.Errorf("Status = %s, want FAILED", stored.Status)
+	}
+	if stored.ResultCode == nil || *stored.ResultCode != "txFAILED" {
+		t.Errorf("ResultCode = %v, want txFAILED", stored.ResultCode)
+	}
+
+	operations, err := models.GetOperationsByTxHash(db, "hash-failed")
+	if err != nil {
+		t.Fatalf("GetOperationsByTxHash() error = %v", err)
+	}
+	if len(operations) != 1 || operations[0].ResultCode == nil || *operations[0].ResultCode != "INVOKE_HOST_FUNCTION_TRAPPED" {
+		t.Errorf("Operations = %+v, want one with INVOKE_HOST_FUNCTION_TRAPPED", operations)
+	}
+}
+
+// diagnosticEvent builds a diagnostic event whose first topic is the symbol name
+func diagnosticEvent(contractID *xdr.ContractId, successful bool, name string, data xdr.ScVal) xdr.DiagnosticEvent {
+	sym := xdr.ScSymbol(name)
+	return xdr.DiagnosticEvent{
+		InSuccessfulContractCall: successful,
+		Event: xdr.ContractEvent{
+			ContractId: contractID,
+			Type:       xdr.ContractEventTypeDiagnostic,
+			Body: xdr.ContractEventBody{V0: &xdr.ContractEventV0{
+				Topics: []xdr.ScVal{{Type: xdr.ScValTypeScvSymbol, Sym: &sym}},
+				Data:   data,
+			}},
+		},
+	}
+}
+
+func TestProcessTransactions_StoresReturnValueAndDiagnostics(t *testing.T) {
+	db := setupTestDB(t)
+	envelope := createUploadEnvelope(t, []byte{0x01})
+	contractID, _ := testContractID()
+	value := xdr.ScString("done")
+	ret := xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &value}
+	void := xdr.ScVal{Type: xdr.ScValTypeScvVoid}
+
+	encode := func(meta xdr.TransactionMeta) string {
+		t.Helper()
+		encoded, err := xdr.MarshalBase64(meta)
+		if err != nil {
+			t.Fatalf("Failed to marshal meta: %v", err)
+		}
+		return encoded
+	}
+	succeeded := encode(xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{
+		SorobanMeta: &xdr.SorobanTransactionMeta{
+			ReturnValue: ret,
+			DiagnosticEvents: []xdr.DiagnosticEvent{
+				diagnosticEvent(nil, true, models.DiagnosticFnCall, void),
+				diagnosticEvent(&contractID, true, models.DiagnosticFnReturn, ret),
+			},
+		},
+	}})
+	failed := encode(xdr.TransactionMeta{V: 4, V4: &xdr.TransactionMetaV4{
+		DiagnosticEvents: []xdr.DiagnosticEvent{
+			diagnosticEvent(nil, false, models.DiagnosticFnCall, void),
+			diagnosticEvent(&contractID, false, models.DiagnosticError, ret),
+		},
+	}})
+
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+	stats, err := p.ProcessTransactions(context.Background(), db, []client.Transaction{
+		{Hash: "hash-ok", Status: "SUCCESS", Ledger: 100, EnvelopeXdr: envelope, ResultMetaXdr: succeeded},
+		{Hash: "hash-failed", Status: "FAILED", Ledger: 100, EnvelopeXdr: envelope, ResultMetaXdr: failed},
+	})
+	if err != nil {
+		t.Fatalf("ProcessTransactions() error = %v", err)
+	}
+	if stats.DiagnosticEvents != 4 {
+		t.Errorf("DiagnosticEvents = %d, want 4", stats.DiagnosticEvents)
+	}
+
+	var stored models.Transaction
+	if err := db.First(&stored, "id = ?", "hash-ok").Error; err != nil {
+		t.Fatalf("Failed to load transaction: %v", err)
+	}
+	if string(stored.ReturnValue) != `"done"` || stored.ReturnValueXdr == nil {
+		t.Errorf("ReturnValue = %s, want \"done\"", stored.ReturnValue)
+	}
+
+	trace, err := models.GetFailedCallTrace(db, "hash-failed")
+	if err != nil {
+		t.Fatalf("GetFailedCallTrace() error = %v", err)
+	}
+	if len(trace) != 2 || trace[0].Name != models.DiagnosticFnCall || trace[1].Name != models.DiagnosticError {
+		t.Fatalf("Failed call trace = %+v, want fn_call then error", trace)
+	}
+	if string(trace[1].Topic) != `["error"]` || string(trace[1].Value) != `"done"` {
+		t.Errorf("Error event topic = %s, value = %s", trace[1].Topic, trace[1].Value)
+	}
+	if trace, _ := models.GetFailedCallTrace(db, "hash-ok"); len(trace) != 0 {
+		t.Errorf("Successful transaction has a failed call trace: %+v", trace)
+	}
+
+	// Reprocessing doesn't duplicate diagnostic events
+	if _, err := p.ProcessTransactions(context.Background(), db, []client.Transaction{
+		{Hash: "hash-failed", Status: "FAILED", Ledger: 100, EnvelopeXdr: envelope, ResultMetaXdr: failed},
+	}); err != nil {
+		t.Fatalf("ProcessTransactions() error = %v", err)
+	}
+	if n := countRows(t, db, &models.DiagnosticEvent{}); n != 4 {
+		t.Errorf("Diagnostic events = %d, want 4", n)
+	}
+}
+
+func TestStats_Add(t *testing.T) {
+	total := Stats{Events: 1, Transactions: 2}
+	total.Add(Stats{Events: 3, Transactions: 4, Operations: 5})
+
+	want := Stats{Events: 4, Transactions: 6, Operations: 5}
+	if total != want {
+		t.Errorf("Stats.Add() = %+v, want %+v", total, want)
+	}
+}
+
+// fakeSink collects archived records and fails when err is set
+type fakeSink struct {
+	records []*archive.Record
+	err     error
+}
+
+func (f *fakeSink) Put(record *archive.Record) error {
+	if f.err != nil {
+		return f.err
+	}
+	f.records = append(f.records, record)
+	return nil
+}
+
+func TestProcess_ArchivesRawPayloads(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+	envelope := createUploadEnvelope(t, []byte{0x01})
+
+	fetcher := &fakeFetcher{txs: map[string]*client.Transaction{
+		"hash-101": {Hash: "hash-101", Status: "SUCCESS", Ledger: 101, EnvelopeXdr: envelope},
+	}}
+	sink := &fakeSink{}
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+	p.SetArchive(sink)
+
+	events := []client.Event{
+		createTransferEvent(t, "0000000429496729601-0000000001", contractID, "", 100, 1),
+		createTransferEvent(t, "0000000433791696897-0000000001", contractID, "hash-101", 101, 2),
+	}
+	if _, err := p.Process(context.Background(), db, events); err != nil {
+		t.Fatalf("Process() error = %v", err)
+	}
+
+	// One record per ledger, with the fetched transaction next to its event
+	if len(sink.records) != 2 {
+		t.Fatalf("Archived %d records, want 2", len(sink.records))
+	}
+	first, second := sink.records[0], sink.records[1]
+	if first.Ledger != 101 || len(first.Transactions) != 1 || first.Transactions[0].EnvelopeXdr != envelope || len(first.Events) != 1 {
+		t.Errorf("Record = %+v, want ledger 101 with its transaction and event", first)
+	}
+	if second.Ledger != 100 || len(second.Events) != 1 || second.NetworkPassphrase != network.TestNetworkPassphrase {
+		t.Errorf("Record = %+v, want ledger 100 with one event", second)
+	}
+
+	// Ledger batches are archived as LedgerCloseMeta only
+	sink.records = nil
+	lcm := createLedgerCloseMeta(t, 102, 1704067200, []testLedgerTx{
+		{envelope: envelope, success: true, meta: xdr.TransactionMeta{V: 3, V3: &xdr.TransactionMetaV3{}}},
+	})
+	if _, err := p.ProcessLedger(context.Background(), db, lcm); err != nil {
+		t.Fatalf("ProcessLedger() error = %v", err)
+	}
+	if len(sink.records) != 1 || sink.records[0].LedgerCloseMeta == "" || len(sink.records[0].Transactions) != 0 {
+		t.Errorf("Records = %+v, want a single LedgerCloseMeta record", sink.records)
+	}
+}
+
+func TestProcess_ArchiveFailureAbortsBatch(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+	p.SetArchive(&fakeSink{err: errors.New("disk full")})
+
+	events := []client.Event{createTransferEvent(t, "0000000429496729601-0000000001", contractID, "", 100, 1)}
+	if _, err := p.Process(context.Background(), db, events); err == nil {
+		t.Fatal("Process() error = nil, want archive error")
+	}
+	if n := countRows(t, db, &models.Event{}); n != 0 {
+		t.Errorf("events = %d, want 0 when archiving fails", n)
+	}
+}
@@ -0,0 +1,561 @@
+package pipeline
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+	"github.com/blockroma/soroban-indexer/pkg/worker"
+)
+
+// storeLedger upserts the ledger header row when the batch was built from a ledger
+func (p *Pipeline) storeLedger(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	if batch.Ledger == nil {
+		return nil
+	}
+	if err := models.UpsertLedger(tx, batch.Ledger); err != nil {
+		return fmt.Errorf("upsert ledger: %w", err)
+	}
+	return nil
+}
+
+// parseEvents parses events, collecting tx hashes and contract IDs for later stages
+func (p *Pipeline) parseEvents(ctx context.Context, db *gorm.DB, batch *Batch) error {
+	seen := make(map[string]bool)
+
+	for _, event := range batch.Events {
+		dbEvent, err := parser.ParseEvent(event)
+		if err != nil {
+			p.logger.WithError(err).WithField("eventID", event.ID).Warn("Failed to parse event")
+			continue
+		}
+
+		batch.events = append(batch.events, dbEvent)
+
+		// Track contract IDs for later processing
+		if event.ContractID != "" {
+			batch.contractIDs[event.ContractID] = true
+		}
+
+		// Track unique tx hashes for transaction fetching
+		if event.TxHash != "" && !seen[event.TxHash] {
+			seen[event.TxHash] = true
+			batch.txHashes = append(batch.txHashes, event.TxHash)
+		}
+	}
+
+	return nil
+}
+
+// storeEvents upserts the parsed events
+func (p *Pipeline) storeEvents(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, dbEvent := range batch.events {
+		if err := models.UpsertEvent(tx, dbEvent); err != nil {
+			return fmt.Errorf("upsert event: %w", err)
+		}
+		batch.Stats.Events++
+	}
+
+	batch.Stats.Contracts = len(batch.contractIDs)
+	return nil
+}
+
+// storeTokenOperations derives token operations (transfer, mint, burn, ...) from the parsed events
+// and records the Stellar Asset Contracts their classic asset topics identify
+func (p *Pipeline) storeTokenOperations(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	assets := make(map[string]*models.TokenMetadata)
+	for _, dbEvent := range batch.events {
+		tokenOp := TokenOperationFromEvent(dbEvent)
+		if tokenOp == nil {
+			continue
+		}
+		if asset := StellarAssetFromEvent(dbEvent, p.networkPassphrase); asset != nil {
+			assets[asset.ContractID] = asset
+		}
+
+		if err := models.UpsertTokenOperation(tx, tokenOp); err != nil {
+			return fmt.Errorf("upsert token operation: %w", err)
+		}
+		batch.Stats.TokenOps++
+
+		// Events of failed contract calls were rolled back and moved nothing
+		if !dbEvent.InSuccessfulContractCall {
+			continue
+		}
+		if allowance := TokenAllowanceFromOperation(tokenOp); allowance != nil {
+			if err := models.UpsertTokenAllowance(tx, allowance); err != nil {
+				return fmt.Errorf("upsert token allowance: %w", err)
+			}
+		}
+
+		inserted, err := models.InsertTokenBalanceChanges(tx, TokenBalanceChangesFromOperation(tokenOp))
+		if err != nil {
+			return fmt.Errorf("insert token balance changes: %w", err)
+		}
+
+		// Only newly recorded operations move the supply, so reprocessing a ledger doesn't count it twice
+		if delta := TokenSupplyDelta(tokenOp); delta != nil && inserted > 0 {
+			if err := models.ApplyTokenSupplyChange(tx, tokenOp.ContractID, uint32(tokenOp.Ledger), delta); err != nil {
+				return fmt.Errorf("apply token supply change: %w", err)
+			}
+		}
+	}
+
+	for _, contractID := range sortedKeys(assets) {
+		if err := models.UpsertStellarAsset(tx, assets[contractID]); err != nil {
+			return fmt.Errorf("upsert stellar asset: %w", err)
+		}
+	}
+
+	return nil
+}
+
+// fetchTransactions fetches the transactions referenced by the batch's events concurrently
+// (bounded by maxConcurrency) and appends them in first-seen order
+// Transactions already present on the batch (e.g. taken from ledger meta) or already stored
+// (e.g. ingested through getTransactions) are not fetched again. A failed fetch fails the batch,
+// so it is retried rather than stored without the transaction
+func (p *Pipeline) fetchTransactions(ctx context.Context, db *gorm.DB, batch *Batch) error {
+	present := make(map[string]bool, len(batch.Transactions))
+	for _, t := range batch.Transactions {
+		present[t.EventHash] = true
+	}
+
+	if len(batch.txHashes) > 0 {
+		var stored []string
+		if err := db.Model(&models.Transaction{}).Where("id IN ?", batch.txHashes).Pluck("id", &stored).Error; err != nil {
+			return fmt.Errorf("lookup stored transactions: %w", err)
+		}
+		for _, hash := range stored {
+			present[hash] = true
+		}
+	}
+
+	var missing []string
+	for _, txHash := range batch.txHashes {
+		if !present[txHash] {
+			missing = append(missing, txHash)
+		}
+	}
+	if len(missing) == 0 {
+		return nil
+	}
+
+	fetched := make([]*client.Transaction, len(missing))
+	tasks := make([]worker.Task, len(missing))
+	for i, txHash := range missing {
+		i, txHash := i, txHash
+		tasks[i] = func(ctx context.Context) error {
+			rpcTx, err := p.fetcher.GetTransaction(ctx, txHash)
+			if err != nil {
+				return err
+			}
+			fetched[i] = rpcTx
+			return nil
+		}
+	}
+
+	results := worker.Execute(ctx, p.maxConcurrency, tasks)
+	if err := ctx.Err(); err != nil {
+		return err
+	}
+	for _, result := range results {
+		if result.Error != nil {
+			return fmt.Errorf("fetch transaction %s: %w", missing[result.Index], result.Error)
+		}
+	}
+
+	// Append in first-seen order regardless of which fetch finished first
+	for i, rpcTx := range fetched {
+		if rpcTx == nil {
+			continue
+		}
+		batch.Transactions = append(batch.Transactions, &Transaction{
+			Hash:      p.resolveTransactionHash(missing[i], rpcTx),
+			EventHash: missing[i],
+			RPC:       rpcTx,
+		})
+	}
+
+	return nil
+}
+
+// parseTransactions decodes the XDR of every transaction concurrently (bounded by maxConcurrency)
+// so the store stages only have to write
+func (p *Pipeline) parseTransactions(ctx context.Context, db *gorm.DB, batch *Batch) error {
+	tasks := make([]worker.Task, len(batch.Transactions))
+	for i, t := range batch.Transactions {
+		t := t
+		tasks[i] = func(ctx context.Context) error {
+			t.prepare()
+			return nil
+		}
+	}
+	worker.Execute(ctx, p.maxConcurrency, tasks)
+	return ctx.Err()
+}
+
+// resolveTransactionHash picks the hash a transaction is stored under
+// The RPC hash wins when present; an empty RPC hash is recomputed from the envelope
+func (p *Pipeline) resolveTransactionHash(eventHash string, rpcTx *client.Transaction) string {
+	if rpcTx.Hash == "" {
+		computedHash, err := parser.ComputeTransactionHash(rpcTx.EnvelopeXdr, p.networkPassphrase)
+		if err != nil {
+			// Failed to compute hash - fall back to the event hash
+			return eventHash
+		}
+		if computedHash != eventHash {
+			p.logger.WithFields(logrus.Fields{
+				"eventHash":    eventHash,
+				"computedHash": computedHash,
+			}).Warn("Computed hash from envelope differs from event hash")
+		}
+		return computedHash
+	}
+
+	if rpcTx.Hash != eventHash {
+		p.logger.WithFields(logrus.Fields{
+			"eventHash": eventHash,
+			"rpcHash":   rpcTx.Hash,
+		}).Warn("RPC returned different hash than event")
+	}
+	return rpcTx.Hash
+}
+
+// storeTransactions upserts the fetched transactions
+func (p *Pipeline) storeTransactions(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, t := range batch.Transactions {
+		t.prepare()
+		if t.parseErr != nil {
+			p.logger.WithError(t.parseErr).WithField("txHash", t.Hash).Warn("Failed to parse transaction")
+			continue
+		}
+
+		if err := models.UpsertTransaction(tx, t.parsed); err != nil {
+			return fmt.Errorf("upsert transaction: %w", err)
+		}
+		batch.Stats.Transactions++
+	}
+
+	return nil
+}
+
+// storeDiagnosticEvents stores the diagnostic events of each transaction, including the call
+// traces and errors of failed transactions
+func (p *Pipeline) storeDiagnosticEvents(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, t := range batch.Transactions {
+		t.prepare()
+		if t.diagnosticsErr != nil {
+			p.logger.WithError(t.diagnosticsErr).WithField("txHash", t.Hash).Warn("Failed to extract diagnostic events from meta")
+			continue
+		}
+
+		if err := models.InsertDiagnosticEvents(tx, t.diagnostics); err != nil {
+			return fmt.Errorf("insert diagnostic events: %w", err)
+		}
+		batch.Stats.DiagnosticEvents += len(t.diagnostics)
+	}
+
+	return nil
+}
+
+// storeContractData extracts contract storage changes and contract instances from transaction meta
+// This is the passive indexing path for contract data, token metadata and token balances
+func (p *Pipeline) storeContractData(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, t := range batch.Transactions {
+		if t.RPC.ResultMetaXdr == "" {
+			continue
+		}
+		batch.Stats.TxWithMeta++
+		t.prepare()
+
+		if t.contractDataErr != nil {
+			p.logger.WithError(t.contractDataErr).WithField("txHash", t.Hash).Warn("Failed to extract contract data from meta")
+		} else if changes := t.contractData; len(changes.Changes) > 0 || len(changes.TTLs) > 0 {
+			p.logger.WithFields(logrus.Fields{
+				"txHash":     t.Hash,
+				"entryCount": len(changes.Changes),
+				"ttlCount":   len(changes.TTLs),
+			}).Info("Extracted contract data from transaction metadata")
+
+			origin := changeOrigin{
+				ledger:   t.RPC.Ledger,
+				txIndex:  t.RPC.ApplicationOrder,
+				txHash:   t.Hash,
+				closedAt: time.Unix(t.RPC.LedgerCloseTime, 0).UTC(),
+			}
+			if err := p.applyContractData(tx, batch, origin, changes.Changes); err != nil {
+				return err
+			}
+
+			// TTLs extended without touching the entry itself
+			for _, keyHash := range sortedKeys(changes.TTLs) {
+				if err := models.UpdateContractDataExpiration(tx, keyHash, changes.TTLs[keyHash]); err != nil {
+					p.logger.WithError(err).WithField("keyHash", keyHash).Warn("Failed to update contract data expiration")
+				}
+			}
+		}
+
+		// Extract contract instance metadata from deployment transactions
+		// This captures token metadata (name, symbol, decimals) at contract creation
+		if t.instancesErr != nil {
+			continue
+		}
+		for _, contractID := range sortedKeys(t.instances) {
+			metadata := t.instances[contractID]
+			if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
+				p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to upsert token metadata from deployment")
+			} else {
+				p.logger.WithFields(logrus.Fields{
+					"contractID": contractID,
+					"name":       metadata.Name,
+					"symbol":     metadata.Symbol,
+					"decimals":   metadata.Decimal,
+				}).Info("Successfully extracted and saved token metadata from contract deployment")
+			}
+		}
+	}
+
+	// Evictions happen once every transaction of the ledger was applied
+	if batch.Ledger != nil {
+		origin := changeOrigin{ledger: batch.Ledger.Sequence, txIndex: models.EvictionTxIndex, closedAt: batch.Ledger.ClosedAt}
+		return p.applyContractData(tx, batch, origin, batch.evicted)
+	}
+	return nil
+}
+
+// changeOrigin is where a group of contract data changes was made: a transaction, or the
+// evictions at the end of a ledger
+type changeOrigin struct {
+	ledger   uint32
+	txIndex  int32
+	txHash   string // Empty for evictions
+	closedAt time.Time
+}
+
+// applyContractData stores written entries and tombstones removed ones, in order, keeping the
+// derived token state in step, and appends every change to the contract data history and every
+// balance entry change to the token balance changes
+// History is the audit trail, so failing to write it aborts the batch
+func (p *Pipeline) applyContractData(tx *gorm.DB, batch *Batch, origin changeOrigin, changes []parser.ContractDataChange) error {
+	history := make([]*models.ContractDataHistory, 0, len(changes))
+	for i, change := range changes {
+		history = append(history, &models.ContractDataHistory{
+			Ledger:      origin.ledger,
+			TxIndex:     origin.txIndex,
+			ChangeIndex: int32(i),
+			TxHash:      origin.txHash,
+			OpIndex:     change.OpIndex,
+			ChangeType:  change.Type,
+			KeyHash:     change.Entry.KeyHash,
+			ContractID:  change.Entry.ContractID,
+			KeyXdr:      change.Entry.KeyXdr,
+			Key:         change.Entry.Key,
+			Durability:  change.Entry.Durability,
+			ValXdr:      change.Entry.ValXdr,
+			Val:         change.Entry.Val,
+		})
+	}
+	if err := models.InsertContractDataHistory(tx, history); err != nil {
+		return fmt.Errorf("insert contract data history: %w", err)
+	}
+
+	for i, change := range changes {
+		if err := p.storeStorageBalanceChange(tx, origin, int32(i), change); err != nil {
+			return err
+		}
+		if _, err := StoreStorageAllowance(tx, origin.ledger, change); err != nil {
+			return err
+		}
+		// Entries already written by a later ledger keep their state, and so does the token
+		// state derived from them
+		entry := change.Entry
+		if change.Type == models.ContractDataRemoved {
+			applied, err := models.RemoveContractDataEntry(tx, entry)
+			if err != nil {
+				p.logger.WithError(err).WithField("keyHash", entry.KeyHash).Warn("Failed to remove contract data entry")
+				continue
+			}
+			batch.Stats.ContractData++
+			if applied {
+				p.removeTokenState(tx, entry)
+			}
+			continue
+		}
+
+		applied, err := models.UpsertContractDataEntry(tx, entry)
+		if err != nil {
+			p.logger.WithError(err).WithField("keyHash", entry.KeyHash).Warn("Failed to upsert contract data entry from meta")
+			continue
+		}
+		batch.Stats.ContractData++
+		if applied {
+			p.deriveTokenState(tx, entry)
+		}
+	}
+	return nil
+}
+
+// storeOperations parses and upserts the operations of each transaction
+func (p *Pipeline) storeOperations(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, t := range batch.Transactions {
+		t.prepare()
+		operations := t.operations
+		if t.operationsErr != nil {
+			p.logger.WithError(t.operationsErr).WithField("txHash", t.Hash).Warn("Failed to parse operations")
+			continue
+		}
+		if len(operations) == 0 {
+			continue
+		}
+
+		if err := models.BatchUpsertOperations(tx, operations); err != nil {
+			p.logger.WithError(err).WithField("txHash", t.Hash).Warn("Failed to batch upsert operations")
+			continue
+		}
+		batch.Stats.Operations += len(operations)
+	}
+
+	return nil
+}
+
+// storeContractCode stores WASM uploaded by UploadContractWasm host functions
+func (p *Pipeline) storeContractCode(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, t := range batch.Transactions {
+		t.prepare()
+		for _, code := range t.contractCode {
+			if err := models.UpsertContractCode(tx, code); err != nil {
+				p.logger.WithError(err).WithField("hash", code.Hash).Warn("Failed to upsert contract code")
+			} else {
+				batch.Stats.ContractCode++
+			}
+		}
+	}
+
+	return nil
+}
+
+// refreshContractState re-derives token metadata and balances from the stored storage
+// of every contract that emitted events in this batch
+// Proactive fetching via getLedgerEntries is disabled - Soroban RPC returns corrupted XDR,
+// so we rely on entries captured passively from transaction metadata
+func (p *Pipeline) refreshContractState(ctx context.Context, tx *gorm.DB, batch *Batch) error {
+	for _, contractID := range sortedKeys(batch.contractIDs) {
+		var entries []models.ContractDataEntry
+		if err := tx.Where("contract_id = ? AND removed_ledger IS NULL", contractID).Find(&entries).Error; err != nil {
+			p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to fetch contract data")
+			continue
+		}
+
+		for i := range entries {
+			p.deriveTokenState(tx, &entries[i])
+		}
+	}
+
+	return nil
+}
+
+// deriveTokenState upserts token metadata and token balances found in a contract data entry
+// Failures are logged and do not abort the batch
+func (p *Pipeline) deriveTokenState(tx *gorm.DB, entry *models.ContractDataEntry) {
+	metadata, balance := TokenStateFromEntry(entry)
+
+	if metadata != nil {
+		if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
+			p.logger.WithError(err).WithField("contractID", entry.ContractID).Warn("Failed to upsert token metadata")
+		}
+	}
+
+	if balance != nil {
+		if err := models.UpsertTokenBalance(tx, balance); err != nil {
+			p.logger.WithError(err).WithField("contractID", entry.ContractID).Warn("Failed to upsert token balance")
+		}
+	}
+}
+
+// storeStorageBalanceChange records the balance stored by a change of a balance entry, with its
+// delta from the balance the entry held before; removals store a balance of 0
+// Evictions carry no prior state, so their delta is from the previous stored balance change
+func (p *Pipeline) storeStorageBalanceChange(tx *gorm.DB, origin changeOrigin, changeIndex int32, change parser.ContractDataChange) error {
+	row := StorageBalanceChange(change, origin.ledger, origin.txIndex, changeIndex, origin.closedAt)
+	if row == nil {
+		return nil
+	}
+	if change.Previous == nil && origin.txIndex == models.EvictionTxIndex {
+		if err := models.SetDeltaFromPreviousStorage(tx, row); err != nil {
+			return fmt.Errorf("get previous storage balance: %w", err)
+		}
+	}
+
+	if _, err := models.InsertTokenBalanceChanges(tx, []*models.TokenBalanceChange{row}); err != nil {
+		return fmt.Errorf("insert token balance change: %w", err)
+	}
+	return nil
+}
+
+// StoreStorageAllowance applies a write or removal of an allowance entry in ledger, and reports
+// whether change was one; spends through transfer_from and burn_from only show up here, as their
+// events don't name the spender
+func StoreStorageAllowance(tx *gorm.DB, ledger uint32, change parser.ContractDataChange) (bool, error) {
+	entry := change.Entry
+	var key interface{}
+	if err := parser.DecodeJSON(entry.Key, &key); err != nil {
+		return false, nil
+	}
+
+	if change.Type == models.ContractDataRemoved {
+		owner, spender := parser.ParseTokenAllowanceKey(key)
+		if owner == "" {
+			return false, nil
+		}
+		if err := models.DeleteTokenAllowance(tx, entry.ContractID, owner, spender, ledger); err != nil {
+			return false, fmt.Errorf("delete token allowance: %w", err)
+		}
+		return true, nil
+	}
+
+	var val interface{}
+	if err := parser.DecodeJSON(entry.Val, &val); err != nil {
+		return false, nil
+	}
+	allowance := parser.ParseTokenAllowance(entry.ContractID, key, val)
+	if allowance == nil {
+		return false, nil
+	}
+	allowance.Ledger = ledger
+	if err := models.UpsertTokenAllowance(tx, allowance); err != nil {
+		return false, fmt.Errorf("upsert token allowance: %w", err)
+	}
+	return true, nil
+}
+
+// removeTokenState deletes the token balance held in a removed contract data entry, so balances
+// of deleted or evicted storage don't linger
+func (p *Pipeline) removeTokenState(tx *gorm.DB, entry *models.ContractDataEntry) {
+	var key interface{}
+	if err := parser.DecodeJSON(entry.Key, &key); err != nil {
+		return
+	}
+	if address := parser.ParseTokenBalanceAddress(key); address != "" {
+		if err := models.DeleteTokenBalance(tx, entry.ContractID, address, entry.LastModifiedLedger); err != nil {
+			p.logger.WithError(err).WithField("contractID", entry.ContractID).Warn("Failed to delete token balance")
+		}
+	}
+}
+
+// sortedKeys returns map keys in ascending order so writes happen in a deterministic order
+func sortedKeys[V any](m map[string]V) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
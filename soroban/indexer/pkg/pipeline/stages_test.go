@@ -0,0 +1,712 @@
+package pipeline
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/strkey"
+	"github.com/stellar/go/xdr"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+func TestParseAndStoreEvents_CollectsUniqueTxHashesInOrder(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	batch := NewBatch([]client.Event{
+		createTransferEvent(t, "0000000429496729601-0000000001", contractID, "hash-b", 100, 1),
+		createTransferEvent(t, "0000000429496729601-0000000002", contractID, "hash-a", 100, 1),
+		createTransferEvent(t, "0000000429496729601-0000000003", contractID, "hash-b", 100, 1),
+	})
+
+	if err := p.parseEvents(context.Background(), db, batch); err != nil {
+		t.Fatalf("parseEvents() error = %v", err)
+	}
+	if err := p.storeEvents(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeEvents() error = %v", err)
+	}
+
+	if len(batch.txHashes) != 2 || batch.txHashes[0] != "hash-b" || batch.txHashes[1] != "hash-a" {
+		t.Errorf("txHashes = %v, want [hash-b hash-a]", batch.txHashes)
+	}
+	if batch.Stats.Events != 3 || batch.Stats.Contracts != 1 {
+		t.Errorf("Stats = %+v, want 3 events and 1 contract", batch.Stats)
+	}
+}
+
+func TestStoreTokenOperations(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	failed := createTransferEvent(t, "0000000429496729601-0000000002", contractID, "hash-a", 100, 99)
+	failed.InSuccessfulContractCall = false
+	batch := NewBatch([]client.Event{
+		createTransferEvent(t, "0000000429496729601-0000000001", contractID, "hash-a", 100, 1234),
+		failed,
+	})
+	if err := p.parseEvents(context.Background(), db, batch); err != nil {
+		t.Fatalf("parseEvents() error = %v", err)
+	}
+	if err := p.storeEvents(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeEvents() error = %v", err)
+	}
+	if err := p.storeTokenOperations(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeTokenOperations() error = %v", err)
+	}
+
+	var op models.TokenOperation
+	if err := db.First(&op, "id = ?", "0000000429496729601-0000000001").Error; err != nil {
+		t.Fatalf("Expected token operation: %v", err)
+	}
+	if op.Type != "transfer" || op.From != testSourceAccount {
+		t.Errorf("TokenOperation = %+v, want transfer from %s", op, testSourceAccount)
+	}
+	if op.Amount == nil || op.Amount.String() != "1234" {
+		t.Errorf("Amount = %v, want 1234", op.Amount)
+	}
+
+	// The transfer moves the amount between holders; the failed call moved nothing
+	var changes []models.TokenBalanceChange
+	if err := db.Order("id ASC").Find(&changes).Error; err != nil {
+		t.Fatalf("Failed to load balance changes: %v", err)
+	}
+	if len(changes) != 2 {
+		t.Fatalf("Balance changes = %d, want 2", len(changes))
+	}
+	if changes[0].Address != testSourceAccount || changes[0].Delta.String() != "-1234" || changes[0].Source != models.BalanceSourceEvent {
+		t.Errorf("Debit = %+v, want -1234 from %s", changes[0], testSourceAccount)
+	}
+	if changes[1].Address != testHolder || changes[1].Delta.String() != "1234" || changes[1].Operation != "transfer" {
+		t.Errorf("Credit = %+v, want 1234 to %s", changes[1], testHolder)
+	}
+}
+
+func TestStoreTokenOperations_MaintainsSupply(t *testing.T) {
+	db := setupTestDB(t)
+	_, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	store := func(events ...client.Event) {
+		t.Helper()
+		batch := NewBatch(events)
+		for _, stage := range []func(context.Context, *gorm.DB, *Batch) error{p.parseEvents, p.storeEvents, p.storeTokenOperations} {
+			if err := stage(context.Background(), db, batch); err != nil {
+				t.Fatalf("stage error = %v", err)
+			}
+		}
+	}
+	supplyAt := func(ledger uint32) string {
+		t.Helper()
+		supply, err := models.GetTokenSupplyAt(db, contractID, ledger)
+		if err != nil {
+			t.Fatalf("GetTokenSupplyAt() error = %v", err)
+		}
+		if supply == nil {
+			return "none"
+		}
+		return supply.Supply.String()
+	}
+
+	store(
+		createTokenEvent(t, "mint", "0000000429496729601-0000000001", contractID, "hash-a", 100, 1000),
+		createTokenEvent(t, "transfer", "0000000429496729601-0000000002", contractID, "hash-a", 100, 400),
+		createTokenEvent(t, "burn", "0000000433791696897-0000000001", contractID, "hash-b", 101, 300),
+	)
+	// Reprocessing the same events doesn't count them twice
+	store(createTokenEvent(t, "mint", "0000000429496729601-0000000001", contractID, "hash-a", 100, 1000))
+	// An earlier ledger processed late shifts every later supply
+	store(createTokenEvent(t, "mint", "0000000425201762305-0000000001", contractID, "hash-c", 99, 50))
+
+	for ledger, want := range map[uint32]string{98: "none", 99: "50", 100: "1050", 101: "750", 200: "750"} {
+		if got := supplyAt(ledger); got != want {
+			t.Errorf("Supply at %d = %s, want %s", ledger, got, want)
+		}
+	}
+
+	var row models.TokenSupply
+	if err := db.First(&row, "contract_id = ? AND ledger = ?", contractID, 101).Error; err != nil {
+		t.Fatalf("Expected supply row for ledger 101: %v", err)
+	}
+	if row.Minted.String() != "0" || row.Burned.String() != "300" {
+		t.Errorf("Ledger 101 minted/burned = %s/%s, want 0/300", row.Minted.String(), row.Burned.String())
+	}
+}
+
+func TestTokenAllowances(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	// approve(owner, spender, 500, 1000): topics [approve, owner, spender], value [amount, expiration]
+	approve := createTokenEvent(t, "approve", "0000000429496729601-0000000001", contractID, "hash-a", 100, 0)
+	expiration := xdr.Uint32(1000)
+	value := &xdr.ScVec{
+		{Type: xdr.ScValTypeScvI128, I128: &xdr.Int128Parts{Hi: 0, Lo: 500}},
+		{Type: xdr.ScValTypeScvU32, U32: &expiration},
+	}
+	var err error
+	if approve.Value, err = xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvVec, Vec: &value}); err != nil {
+		t.Fatalf("Failed to marshal value: %v", err)
+	}
+
+	storeEvents := func() {
+		t.Helper()
+		batch := NewBatch([]client.Event{approve})
+		for _, stage := range []func(context.Context, *gorm.DB, *Batch) error{p.parseEvents, p.storeEvents, p.storeTokenOperations} {
+			if err := stage(context.Background(), db, batch); err != nil {
+				t.Fatalf("stage error = %v", err)
+			}
+		}
+	}
+	storeMeta := func(ledger uint32, meta string) {
+		t.Helper()
+		batch := NewBatch(nil)
+		batch.Transactions = []*Transaction{{Hash: fmt.Sprintf("hash-%d", ledger), RPC: &client.Transaction{Ledger: ledger, ResultMetaXdr: meta}}}
+		if err := p.storeContractData(context.Background(), db, batch); err != nil {
+			t.Fatalf("storeContractData() error = %v", err)
+		}
+	}
+	active := func(ledger uint32) []models.TokenAllowance {
+		t.Helper()
+		allowances, err := models.GetActiveAllowances(db, testSourceAccount, ledger)
+		if err != nil {
+			t.Fatalf("GetActiveAllowances() error = %v", err)
+		}
+		return allowances
+	}
+
+	storeEvents()
+	allowances := active(100)
+	if len(allowances) != 1 {
+		t.Fatalf("Active allowances = %d, want 1", len(allowances))
+	}
+	if got := allowances[0]; got.ContractID != contractID || got.Spender != testHolder || got.Amount.String() != "500" || got.ExpirationLedger != 1000 {
+		t.Errorf("Allowance = %+v, want 500 to %s until 1000", got, testHolder)
+	}
+	if len(active(1001)) != 0 {
+		t.Errorf("Allowance still active after its expiration ledger")
+	}
+
+	// transfer_from spends the allowance: only its storage entry shows the remainder
+	spent := allowanceEntry(rawContractID, testSourceAccount, testHolder, 200, 1000)
+	storeMeta(101, createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &spent}))
+	// Processing the approve again doesn't roll the spend back
+	storeEvents()
+	if allowances := active(101); len(allowances) != 1 || allowances[0].Amount.String() != "200" || allowances[0].Ledger != 101 {
+		t.Errorf("Allowances after spend = %+v, want 200 at ledger 101", allowances)
+	}
+
+	// Removing the entry drops the allowance
+	key, err := spent.LedgerKey()
+	if err != nil {
+		t.Fatalf("LedgerKey() error = %v", err)
+	}
+	storeMeta(102, createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key}))
+	if n := countRows(t, db, &models.TokenAllowance{}); n != 0 {
+		t.Errorf("Token allowances = %d, want 0", n)
+	}
+}
+
+func TestStoreTokenOperations_StellarAssets(t *testing.T) {
+	db := setupTestDB(t)
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	usdc := xdr.MustNewCreditAsset("USDC", testSourceAccount)
+	contractHash, err := usdc.ContractID(network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("ContractID() error = %v", err)
+	}
+	sacID := strkey.MustEncode(strkey.VersionByteContract, contractHash[:])
+	_, otherID := testContractID()
+
+	// SAC transfers carry the classic asset as a fourth topic
+	withAsset := func(event client.Event) client.Event {
+		assetStr := xdr.ScString("USDC:" + testSourceAccount)
+		topic, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvString, Str: &assetStr})
+		if err != nil {
+			t.Fatalf("Failed to marshal topic: %v", err)
+		}
+		event.Topic = append(event.Topic, topic)
+		return event
+	}
+
+	batch := NewBatch([]client.Event{
+		withAsset(createTransferEvent(t, "0000000429496729601-0000000001", sacID, "hash-a", 100, 10)),
+		// Any contract can claim to be the asset; only its real contract is recorded
+		withAsset(createTransferEvent(t, "0000000429496729601-0000000002", otherID, "hash-a", 100, 10)),
+	})
+	for _, stage := range []func(context.Context, *gorm.DB, *Batch) error{p.parseEvents, p.storeEvents, p.storeTokenOperations} {
+		if err := stage(context.Background(), db, batch); err != nil {
+			t.Fatalf("stage error = %v", err)
+		}
+	}
+
+	metadata, err := models.GetStellarAssetContract(db, "USDC", testSourceAccount)
+	if err != nil {
+		t.Fatalf("GetStellarAssetContract() error = %v", err)
+	}
+	if metadata == nil || metadata.ContractID != sacID {
+		t.Fatalf("Stellar asset contract = %+v, want %s", metadata, sacID)
+	}
+	if metadata.Symbol != "USDC" || metadata.Name != "USDC:"+testSourceAccount || metadata.Decimal != 7 {
+		t.Errorf("Metadata = %+v, want USDC with 7 decimals", metadata)
+	}
+	if n := countRows(t, db, &models.TokenMetadata{}); n != 1 {
+		t.Errorf("Token metadata rows = %d, want 1", n)
+	}
+}
+
+func TestFetchTransactions_FailsOnFetchError(t *testing.T) {
+	fetcher := &fakeFetcher{txs: map[string]*client.Transaction{
+		"hash-a": {Hash: "hash-a"},
+	}}
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+
+	batch := NewBatch(nil)
+	batch.txHashes = []string{"hash-a", "hash-missing"}
+
+	// The batch is retried instead of being stored without the transaction
+	err := p.fetchTransactions(context.Background(), setupTestDB(t), batch)
+	if err == nil || !strings.Contains(err.Error(), "hash-missing") {
+		t.Fatalf("fetchTransactions() error = %v, want error for hash-missing", err)
+	}
+	if len(batch.Transactions) != 0 {
+		t.Errorf("Transactions = %v, want none", batch.Transactions)
+	}
+}
+
+func TestFetchTransactions_SkipsStored(t *testing.T) {
+	db := setupTestDB(t)
+	if err := models.UpsertTransaction(db, &models.Transaction{ID: "hash-stored", Status: "FAILED"}); err != nil {
+		t.Fatalf("UpsertTransaction() error = %v", err)
+	}
+
+	fetcher := &fakeFetcher{txs: map[string]*client.Transaction{
+		"hash-a":      {Hash: "hash-a"},
+		"hash-stored": {Hash: "hash-stored"},
+	}}
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+
+	batch := NewBatch(nil)
+	batch.txHashes = []string{"hash-stored", "hash-a"}
+
+	if err := p.fetchTransactions(context.Background(), db, batch); err != nil {
+		t.Fatalf("fetchTransactions() error = %v", err)
+	}
+
+	if len(fetcher.calls) != 1 || fetcher.calls[0] != "hash-a" {
+		t.Errorf("GetTransaction calls = %v, want only hash-a", fetcher.calls)
+	}
+}
+
+func TestFetchTransactions_ConcurrentKeepsOrder(t *testing.T) {
+	fetcher := &fakeFetcher{txs: map[string]*client.Transaction{}, delay: map[string]time.Duration{}}
+	var hashes []string
+	for i := 0; i < 8; i++ {
+		hash := fmt.Sprintf("hash-%d", i)
+		hashes = append(hashes, hash)
+		fetcher.txs[hash] = &client.Transaction{Hash: hash}
+		// Earlier hashes take longer, so fetches complete in reverse order
+		fetcher.delay[hash] = time.Duration(8-i) * 5 * time.Millisecond
+	}
+
+	p := New(fetcher, testLogger(), network.TestNetworkPassphrase)
+	p.SetMaxConcurrency(3)
+
+	batch := NewBatch(nil)
+	batch.txHashes = hashes
+
+	if err := p.fetchTransactions(context.Background(), setupTestDB(t), batch); err != nil {
+		t.Fatalf("fetchTransactions() error = %v", err)
+	}
+
+	if len(batch.Transactions) != len(hashes) {
+		t.Fatalf("Transactions = %d, want %d", len(batch.Transactions), len(hashes))
+	}
+	for i, tx := range batch.Transactions {
+		if tx.Hash != hashes[i] {
+			t.Errorf("Transactions[%d] = %s, want %s", i, tx.Hash, hashes[i])
+		}
+	}
+	if fetcher.maxSeen > 3 {
+		t.Errorf("Concurrent fetches = %d, want at most 3", fetcher.maxSeen)
+	}
+	if fetcher.maxSeen < 2 {
+		t.Errorf("Concurrent fetches = %d, want fetches to overlap", fetcher.maxSeen)
+	}
+}
+
+func TestResolveTransactionHash(t *testing.T) {
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+	envelope := createUploadEnvelope(t, []byte{0x01})
+	computed, err := parser.ComputeTransactionHash(envelope, network.TestNetworkPassphrase)
+	if err != nil {
+		t.Fatalf("ComputeTransactionHash() error = %v", err)
+	}
+
+	tests := []struct {
+		name      string
+		eventHash string
+		rpcTx     *client.Transaction
+		want      string
+	}{
+		{"rpc hash matches", "hash-a", &client.Transaction{Hash: "hash-a"}, "hash-a"},
+		{"rpc hash differs", "hash-a", &client.Transaction{Hash: "hash-b"}, "hash-b"},
+		{"empty rpc hash recomputed", "hash-a", &client.Transaction{EnvelopeXdr: envelope}, computed},
+		{"empty rpc hash with bad envelope", "hash-a", &client.Transaction{EnvelopeXdr: "invalid"}, "hash-a"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := p.resolveTransactionHash(tt.eventHash, tt.rpcTx); got != tt.want {
+				t.Errorf("resolveTransactionHash() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestStoreContractData(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	batch := NewBatch(nil)
+	batch.Transactions = []*Transaction{
+		{Hash: "hash-a", RPC: &client.Transaction{ResultMetaXdr: createBalanceMeta(t, rawContractID, testHolder, 42)}},
+		{Hash: "hash-b", RPC: &client.Transaction{}}, // No meta
+	}
+
+	if err := p.storeContractData(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeContractData() error = %v", err)
+	}
+
+	if batch.Stats.TxWithMeta != 1 || batch.Stats.ContractData != 1 {
+		t.Errorf("Stats = %+v, want 1 tx with meta and 1 contract data entry", batch.Stats)
+	}
+
+	var entry models.ContractDataEntry
+	if err := db.Where("contract_id = ?", contractID).First(&entry).Error; err != nil {
+		t.Fatalf("Expected contract data entry: %v", err)
+	}
+
+	var balance models.TokenBalance
+	if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err != nil {
+		t.Fatalf("Expected token balance: %v", err)
+	}
+	if balance.Balance.String() != "42" {
+		t.Errorf("Balance = %v, want 42", balance.Balance)
+	}
+}
+
+func TestStoreOperationsAndContractCode(t *testing.T) {
+	db := setupTestDB(t)
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	batch := NewBatch(nil)
+	batch.Transactions = []*Transaction{
+		{Hash: "hash-a", RPC: &client.Transaction{Ledger: 100, LedgerCloseTime: 1704067200, EnvelopeXdr: createUploadEnvelope(t, []byte{0x00, 0x61})}},
+	}
+
+	if err := p.storeOperations(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeOperations() error = %v", err)
+	}
+	if err := p.storeContractCode(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeContractCode() error = %v", err)
+	}
+
+	ops, err := models.GetOperationsByTxHash(db, "hash-a")
+	if err != nil {
+		t.Fatalf("GetOperationsByTxHash() error = %v", err)
+	}
+	if len(ops) != 1 || ops[0].OperationType != "OperationTypeInvokeHostFunction" {
+		t.Errorf("Operations = %+v, want one InvokeHostFunction", ops)
+	}
+
+	var code models.ContractCode
+	if err := db.First(&code).Error; err != nil {
+		t.Fatalf("Expected contract code: %v", err)
+	}
+	if code.TxHash != "hash-a" || code.Ledger != 100 {
+		t.Errorf("ContractCode = %+v, want tx hash-a at ledger 100", code)
+	}
+}
+
+func TestRefreshContractState(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	// Seed a storage entry as if a previous batch captured it
+	entries, err := parser.ExtractContractDataFromMeta("hash-a", createBalanceMeta(t, rawContractID, testHolder, 99))
+	if err != nil || len(entries) != 1 {
+		t.Fatalf("ExtractContractDataFromMeta() = %v, %v", entries, err)
+	}
+	if _, err := models.UpsertContractDataEntry(db, entries[0]); err != nil {
+		t.Fatalf("UpsertContractDataEntry() error = %v", err)
+	}
+
+	batch := NewBatch(nil)
+	batch.contractIDs[contractID] = true
+
+	if err := p.refreshContractState(context.Background(), db, batch); err != nil {
+		t.Fatalf("refreshContractState() error = %v", err)
+	}
+
+	var balance models.TokenBalance
+	if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err != nil {
+		t.Fatalf("Expected token balance: %v", err)
+	}
+	if balance.Balance.String() != "99" {
+		t.Errorf("Balance = %v, want 99", balance.Balance)
+	}
+}
+
+// TestStoreContractData_OutOfOrder tests that ledgers indexed out of order don't overwrite newer
+// storage or balances, and that balance deltas come from each change's own prior state
+func TestStoreContractData_OutOfOrder(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	store := func(ledger uint32, changes ...xdr.LedgerEntryChange) {
+		batch := NewBatch(nil)
+		batch.Transactions = []*Transaction{{Hash: fmt.Sprintf("hash-%d", ledger), RPC: &client.Transaction{Ledger: ledger, ResultMetaXdr: createMeta(t, changes...)}}}
+		if err := p.storeContractData(context.Background(), db, batch); err != nil {
+			t.Fatalf("storeContractData() error = %v", err)
+		}
+	}
+	update := func(ledger uint32, from, to int64) {
+		before := balanceEntry(rawContractID, testHolder, from)
+		after := balanceEntry(rawContractID, testHolder, to)
+		store(ledger,
+			xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &before},
+			xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &after},
+		)
+	}
+	balance := func() string {
+		var balance models.TokenBalance
+		if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err != nil {
+			return ""
+		}
+		return balance.Balance.String()
+	}
+
+	update(200, 42, 50)
+	update(150, 30, 42)
+	if got := balance(); got != "50" {
+		t.Errorf("Balance = %s, want 50 from the later ledger", got)
+	}
+
+	var changes []models.TokenBalanceChange
+	if err := db.Where("address = ?", testHolder).Order("ledger ASC").Find(&changes).Error; err != nil {
+		t.Fatalf("Failed to load balance changes: %v", err)
+	}
+	if len(changes) != 2 || changes[0].Delta.String() != "12" || changes[1].Delta.String() != "8" {
+		t.Errorf("Balance changes = %+v, want deltas 12 and 8", changes)
+	}
+
+	// A removal is not undone by an earlier write indexed after it
+	entry := balanceEntry(rawContractID, testHolder, 50)
+	key, err := entry.LedgerKey()
+	if err != nil {
+		t.Fatalf("LedgerKey() error = %v", err)
+	}
+	store(300,
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &entry},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+	)
+	update(250, 50, 60)
+
+	var stored models.ContractDataEntry
+	if err := db.Where("contract_id = ?", contractID).First(&stored).Error; err != nil {
+		t.Fatalf("Expected contract data entry: %v", err)
+	}
+	if stored.RemovedLedger == nil || *stored.RemovedLedger != 300 || stored.LastModifiedLedger != 300 {
+		t.Errorf("Entry = %+v, want removed at 300", stored)
+	}
+	if got := balance(); got != "" {
+		t.Errorf("Balance = %s, want none after the removal", got)
+	}
+}
+
+func TestStoreContractData_HistorySharedPosition(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	// Two transactions of a ledger without an application order both write change 0 at tx index 0
+	holder := balanceEntry(rawContractID, testHolder, 42)
+	source := balanceEntry(rawContractID, testSourceAccount, 7)
+	batch := NewBatch(nil)
+	batch.Transactions = []*Transaction{
+		{Hash: "hash-a", RPC: &client.Transaction{Ledger: 100, ResultMetaXdr: createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &holder})}},
+		{Hash: "hash-b", RPC: &client.Transaction{Ledger: 100, ResultMetaXdr: createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &source})}},
+	}
+	if err := p.storeContractData(context.Background(), db, batch); err != nil {
+		t.Fatalf("storeContractData() error = %v", err)
+	}
+
+	if n := countRows(t, db, &models.ContractDataHistory{}); n != 2 {
+		t.Fatalf("History rows = %d, want both versions", n)
+	}
+	for _, entry := range []xdr.LedgerEntry{holder, source} {
+		keyXdr, _ := xdr.MarshalBase64(entry.Data.ContractData.Key)
+		version, err := models.GetContractDataAt(db, contractID, keyXdr, "persistent", 100)
+		if err != nil || version == nil || version.ChangeType != models.ContractDataCreated {
+			t.Errorf("GetContractDataAt(%s) = %+v, %v; want the creation", keyXdr, version, err)
+		}
+	}
+}
+
+func TestStoreContractData_RemovalsAndTTL(t *testing.T) {
+	db := setupTestDB(t)
+	rawContractID, contractID := testContractID()
+	p := New(&fakeFetcher{}, testLogger(), network.TestNetworkPassphrase)
+
+	entry := balanceEntry(rawContractID, testHolder, 42)
+	key, err := entry.LedgerKey()
+	if err != nil {
+		t.Fatalf("LedgerKey() error = %v", err)
+	}
+	bin, err := key.MarshalBinary()
+	if err != nil {
+		t.Fatalf("MarshalBinary() error = %v", err)
+	}
+	keyHash := xdr.Hash(sha256.Sum256(bin))
+
+	evictedEntry := balanceEntry(rawContractID, testSourceAccount, 7)
+	evictedKey, err := evictedEntry.LedgerKey()
+	if err != nil {
+		t.Fatalf("LedgerKey() error = %v", err)
+	}
+
+	ttl := xdr.LedgerEntry{Data: xdr.LedgerEntryData{Type: xdr.LedgerEntryTypeTtl, Ttl: &xdr.TtlEntry{KeyHash: keyHash, LiveUntilLedgerSeq: 5000}}}
+	store := func(ledger uint32, meta string, evicted ...xdr.LedgerKey) {
+		batch := NewBatch(nil)
+		batch.Transactions = []*Transaction{{Hash: fmt.Sprintf("hash-%d", ledger), RPC: &client.Transaction{Ledger: ledger, ResultMetaXdr: meta}}}
+		batch.Ledger = &models.Ledger{Sequence: ledger}
+		batch.evicted = parser.ContractDataRemovals(ledger, evicted)
+		if err := p.storeContractData(context.Background(), db, batch); err != nil {
+			t.Fatalf("storeContractData() error = %v", err)
+		}
+	}
+	stored := func() models.ContractDataEntry {
+		var stored models.ContractDataEntry
+		if err := db.Where("key_hash = ?", hex.EncodeToString(keyHash[:])).First(&stored).Error; err != nil {
+			t.Fatalf("Expected contract data entry: %v", err)
+		}
+		return stored
+	}
+
+	store(100, createMeta(t,
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &entry},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &evictedEntry},
+	))
+	if countRows(t, db, &models.TokenBalance{}) != 2 {
+		t.Fatalf("Expected 2 token balances")
+	}
+
+	// A TTL extension on its own sets the expiration of the stored entry
+	store(101, createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryUpdated, Updated: &ttl}))
+	if got := stored(); got.ExpirationLedgerSeq != 5000 || got.Expired(5000) || !got.Expired(5001) {
+		t.Errorf("ExpirationLedgerSeq = %d, want 5000", got.ExpirationLedgerSeq)
+	}
+
+	// Removal tombstones the entry with its ledger and drops the derived balance
+	store(102, createMeta(t,
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryState, State: &entry},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryRemoved, Removed: &key},
+	))
+	got := stored()
+	if got.RemovedLedger == nil || *got.RemovedLedger != 102 {
+		t.Errorf("RemovedLedger = %v, want 102", got.RemovedLedger)
+	}
+	if len(got.Val) == 0 || got.ExpirationLedgerSeq != 5000 {
+		t.Errorf("Tombstone lost its last value: %+v", got)
+	}
+	var balance models.TokenBalance
+	if err := db.Where("contract_id = ? AND address = ?", contractID, testHolder).First(&balance).Error; err == nil {
+		t.Errorf("Token balance of a removed entry was kept: %+v", balance)
+	}
+
+	// Evictions remove entries too, and re-deriving the contract's state doesn't bring them back
+	store(103, "", evictedKey)
+	batch := NewBatch(nil)
+	batch.contractIDs[contractID] = true
+	if err := p.refreshContractState(context.Background(), db, batch); err != nil {
+		t.Fatalf("refreshContractState() error = %v", err)
+	}
+	if n := countRows(t, db, &models.TokenBalance{}); n != 0 {
+		t.Errorf("Token balances = %d, want 0", n)
+	}
+
+	// Restoring the entry makes it live again
+	store(104, createMeta(t, xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryRestored, Restored: &entry}))
+	if got := stored(); got.Removed() {
+		t.Errorf("Restored entry still removed at %d", *got.RemovedLedger)
+	}
+	if countRows(t, db, &models.TokenBalance{}) != 1 {
+		t.Errorf("Expected the restored balance")
+	}
+
+	// Every change is in the history, once, even when a ledger is processed again
+	store(100, createMeta(t,
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &entry},
+		xdr.LedgerEntryChange{Type: xdr.LedgerEntryChangeTypeLedgerEntryCreated, Created: &evictedEntry},
+	))
+	if n := countRows(t, db, &models.ContractDataHistory{}); n != 5 {
+		t.Errorf("History rows = %d, want 5", n)
+	}
+
+	keyXdr, _ := xdr.MarshalBase64(entry.Data.ContractData.Key)
+	wantAt := map[uint32]string{99: "", 100: models.ContractDataCreated, 101: models.ContractDataCreated, 102: models.ContractDataRemoved, 200: models.ContractDataRestored}
+	for ledger, want := range wantAt {
+		version, err := models.GetContractDataAt(db, contractID, keyXdr, "persistent", ledger)
+		if err != nil {
+			t.Fatalf("GetContractDataAt(%d) error = %v", ledger, err)
+		}
+		got := ""
+		if version != nil {
+			got = version.ChangeType
+		}
+		if got != want {
+			t.Errorf("GetContractDataAt(%d) = %q, want %q", ledger, got, want)
+		}
+	}
+
+	// Each write of a balance entry is a storage balance change
+	var changes []models.TokenBalanceChange
+	if err := db.Where("address = ?", testHolder).Order("ledger ASC").Find(&changes).Error; err != nil {
+		t.Fatalf("Failed to load balance changes: %v", err)
+	}
+	wantChanges := [][2]string{{"42", "42"}, {"-42", "0"}, {"42", "42"}}
+	if len(changes) != len(wantChanges) {
+		t.Fatalf("Balance changes = %d, want %d", len(changes), len(wantChanges))
+	}
+	for i, want := range wantChanges {
+		if changes[i].Delta.String() != want[0] || changes[i].Balance == nil || changes[i].Balance.String() != want[1] {
+			t.Errorf("Balance change %d = %s -> %v, want %s -> %s", i, changes[i].Delta.String(), changes[i].Balance, want[0], want[1])
+		}
+	}
+	if balance, _ := models.GetTokenBalanceAt(db, contractID, testHolder, 103); balance != "0" {
+		t.Errorf("GetTokenBalanceAt(103) = %s, want 0", balance)
+	}
+
+	evictedKeyXdr, _ := xdr.MarshalBase64(evictedEntry.Data.ContractData.Key)
+	version, err := models.GetContractDataAt(db, contractID, evictedKeyXdr, "persistent", 103)
+	if err != nil || version == nil || version.TxIndex != models.EvictionTxIndex || version.TxHash != "" {
+		t.Errorf("GetContractDataAt(evicted) = %+v, %v; want the eviction", version, err)
+	}
+}
@@ -0,0 +1,496 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+	"github.com/blockroma/soroban-indexer/pkg/worker"
+)
+
+// backfillRetryBackoff is the pause before a queued batch is retried, multiplied by its attempt count
+var backfillRetryBackoff = 2 * time.Second
+
+// BackfillConfig defines configuration for backfill mode
+type BackfillConfig struct {
+	StartLedger uint32 // First ledger to process
+	EndLedger   uint32 // Last ledger to process (0 = current ledger)
+	BatchSize   uint32 // Number of ledgers to process per batch
+	RateLimit   uint32 // Max batches per second, shared by all shards
+	Workers     int    // Shards processed concurrently (default: 4)
+	Job         string // Checkpoint and cursor name; re-running a job resumes it (default: "backfill-<start>-<end>")
+	MaxRetries  int    // Attempts per failed batch during one run (default: 3)
+}
+
+// ledgerRange is an inclusive range of ledgers
+type ledgerRange struct {
+	start, end uint32
+}
+
+// splitRange divides [start, end] into at most n contiguous ranges of near-equal size
+func splitRange(start, end uint32, n int) []ledgerRange {
+	total := uint64(end) - uint64(start) + 1
+	if n < 1 {
+		n = 1
+	}
+	if uint64(n) > total {
+		n = int(total)
+	}
+
+	ranges := make([]ledgerRange, 0, n)
+	size, rest := total/uint64(n), total%uint64(n)
+	next := uint64(start)
+	for i := 0; i < n; i++ {
+		length := size
+		if uint64(i) < rest {
+			length++
+		}
+		ranges = append(ranges, ledgerRange{start: uint32(next), end: uint32(next + length - 1)})
+		next += length
+	}
+	return ranges
+}
+
+// backfillProgress aggregates the work done by all shards of a backfill
+type backfillProgress struct {
+	mu           sync.Mutex
+	total        uint32
+	processed    uint32
+	stats        pipeline.Stats
+	started      time.Time
+	lastLog      time.Time
+	failedRanges int
+
+	// Serializes job cursor updates so a slower shard never writes a stale position
+	cursorMu sync.Mutex
+}
+
+// add records a finished batch
+func (b *backfillProgress) add(ledgers uint32, stats pipeline.Stats, failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.processed += ledgers
+	b.stats.Add(stats)
+	if failed {
+		b.failedRanges++
+	}
+}
+
+// due reports whether a progress line should be logged, at most every 10 seconds
+func (b *backfillProgress) due() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if time.Since(b.lastLog) < 10*time.Second {
+		return false
+	}
+	b.lastLog = time.Now()
+	return true
+}
+
+// Backfill processes historical ledgers
+// The range is split into shards that are processed concurrently; each shard persists a
+// checkpoint after every batch, so a killed backfill resumes where it stopped when re-run with
+// the same job. A batch that fails is queued for retry instead of being skipped, and the queue is
+// drained once every shard is done. Backfill returns an error while any batch is still queued.
+func (p *Poller) Backfill(ctx context.Context, config BackfillConfig) error {
+	start := time.Now()
+
+	// Validate configuration
+	if config.StartLedger == 0 {
+		return fmt.Errorf("start ledger must be > 0")
+	}
+	if config.BatchSize == 0 {
+		config.BatchSize = 100 // Default batch size
+	}
+	if config.RateLimit == 0 {
+		config.RateLimit = 10 // Default 10 requests/sec
+	}
+	if config.Workers <= 0 {
+		config.Workers = 4
+	}
+	if config.MaxRetries <= 0 {
+		config.MaxRetries = 3
+	}
+
+	// Get network passphrase for transaction hashing
+	if err := p.configureNetwork(ctx); err != nil {
+		return err
+	}
+
+	// Get current ledger if end ledger not specified
+	if config.EndLedger == 0 {
+		latestLedger, err := p.rpcClient.GetLatestLedger(ctx)
+		if err != nil {
+			return fmt.Errorf("get latest ledger: %w", err)
+		}
+		config.EndLedger = latestLedger
+	}
+
+	// Validate range
+	if config.StartLedger > config.EndLedger {
+		return fmt.Errorf("start ledger (%d) must be <= end ledger (%d)", config.StartLedger, config.EndLedger)
+	}
+
+	if config.Job == "" {
+		config.Job = fmt.Sprintf("backfill-%d-%d", config.StartLedger, config.EndLedger)
+	}
+
+	shards, err := p.loadShards(config)
+	if err != nil {
+		return err
+	}
+	// A resumed job covers the range it was created with
+	config.StartLedger, config.EndLedger = shards[0].StartLedger, shards[len(shards)-1].EndLedger
+
+	progress := &backfillProgress{started: start, lastLog: start}
+	for _, shard := range shards {
+		progress.total += shard.EndLedger - shard.StartLedger + 1
+		// Ledgers finished by a previous run count as processed
+		progress.processed += shard.Checkpoint - (shard.StartLedger - 1)
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"job":          config.Job,
+		"startLedger":  config.StartLedger,
+		"endLedger":    config.EndLedger,
+		"totalLedgers": progress.total,
+		"resumedAt":    progress.processed,
+		"shards":       len(shards),
+		"workers":      config.Workers,
+		"batchSize":    config.BatchSize,
+		"rateLimit":    config.RateLimit,
+		"mode":         p.mode,
+	}).Info("Starting backfill")
+
+	// Rate limiter: batches per second across all shards
+	rateLimiter := time.NewTicker(time.Second / time.Duration(config.RateLimit))
+	defer rateLimiter.Stop()
+
+	tasks := make([]worker.Task, len(shards))
+	for i := range shards {
+		shard := shards[i]
+		tasks[i] = func(ctx context.Context) error {
+			return p.backfillShard(ctx, config, shard, rateLimiter.C, progress)
+		}
+	}
+
+	for i, result := range worker.Execute(ctx, config.Workers, tasks) {
+		if result.Error != nil {
+			return fmt.Errorf("shard %s: %w", shards[i].ID, result.Error)
+		}
+	}
+	if ctx.Err() != nil {
+		p.logger.WithFields(logrus.Fields{
+			"job":              config.Job,
+			"processedLedgers": progress.processed,
+			"duration":         time.Since(start),
+		}).Info("Backfill cancelled")
+		return ctx.Err()
+	}
+
+	if err := p.drainBackfillRetries(ctx, config, progress); err != nil {
+		return err
+	}
+
+	// Final summary
+	duration := time.Since(start)
+	rate := float64(progress.processed) / duration.Seconds()
+
+	p.logger.WithFields(logrus.Fields{
+		"job":              config.Job,
+		"totalLedgers":     progress.processed,
+		"events":           progress.stats.Events,
+		"transactions":     progress.stats.Transactions,
+		"operations":       progress.stats.Operations,
+		"retriedBatches":   progress.failedRanges,
+		"duration":         duration.Round(time.Second),
+		"ledgersPerSecond": fmt.Sprintf("%.2f", rate),
+	}).Info("Backfill completed successfully")
+
+	return nil
+}
+
+// loadShards returns the shards of the job, creating them on its first run
+// A resumed job keeps its original shard boundaries even if Workers changed
+func (p *Poller) loadShards(config BackfillConfig) ([]models.BackfillShard, error) {
+	shards, err := models.GetBackfillShards(p.db, config.Job)
+	if err != nil {
+		return nil, fmt.Errorf("load backfill shards: %w", err)
+	}
+	if len(shards) > 0 {
+		return shards, nil
+	}
+
+	var created []models.BackfillShard
+	for _, r := range splitRange(config.StartLedger, config.EndLedger, config.Workers) {
+		created = append(created, models.BackfillShard{StartLedger: r.start, EndLedger: r.end})
+	}
+	if err := models.CreateBackfillShards(p.db, config.Job, created); err != nil {
+		return nil, fmt.Errorf("create backfill shards: %w", err)
+	}
+
+	return models.GetBackfillShards(p.db, config.Job)
+}
+
+// backfillShard processes a shard batch by batch from its checkpoint
+// A failed batch is queued for retry and the checkpoint moves past it; cancellation stops the
+// shard without checkpointing the interrupted batch
+func (p *Poller) backfillShard(ctx context.Context, config BackfillConfig, shard models.BackfillShard, limiter <-chan time.Time, progress *backfillProgress) error {
+	if shard.Checkpoint >= shard.EndLedger {
+		if shard.Status != models.ShardStatusCompleted {
+			if err := models.UpdateShardCheckpoint(p.db, shard.ID, shard.EndLedger, models.ShardStatusCompleted); err != nil {
+				return fmt.Errorf("update checkpoint: %w", err)
+			}
+		}
+		return p.advanceJobCursor(config.Job, progress)
+	}
+
+	for current := shard.Checkpoint + 1; current <= shard.EndLedger; {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-limiter:
+			// Rate limit: process one batch per tick
+		}
+
+		endBatchLedger := shard.EndLedger
+		if current+config.BatchSize-1 < shard.EndLedger {
+			endBatchLedger = current + config.BatchSize - 1
+		}
+
+		stats, err := p.processLedgerBatch(ctx, current, endBatchLedger)
+		if err != nil {
+			if ctx.Err() != nil {
+				return nil
+			}
+
+			p.logger.WithError(err).WithFields(logrus.Fields{
+				"shard":       shard.ID,
+				"startLedger": current,
+				"endLedger":   endBatchLedger,
+			}).Error("Failed to process ledger batch, queued for retry")
+
+			if err := models.EnqueueBackfillRetry(p.db, config.Job, current, endBatchLedger, err); err != nil {
+				return fmt.Errorf("queue retry for ledgers %d-%d: %w", current, endBatchLedger, err)
+			}
+		} else {
+			p.recordCoverage(config.Job, current, endBatchLedger)
+		}
+
+		status := models.ShardStatusRunning
+		if endBatchLedger == shard.EndLedger {
+			status = models.ShardStatusCompleted
+		}
+		if err := models.UpdateShardCheckpoint(p.db, shard.ID, endBatchLedger, status); err != nil {
+			return fmt.Errorf("update checkpoint: %w", err)
+		}
+		if err := p.advanceJobCursor(config.Job, progress); err != nil {
+			return fmt.Errorf("update job cursor: %w", err)
+		}
+
+		progress.add(endBatchLedger-current+1, stats, err != nil)
+		if progress.due() {
+			p.logProgress(progress, shard.ID, endBatchLedger)
+		}
+
+		current = endBatchLedger + 1
+	}
+
+	return nil
+}
+
+// advanceJobCursor moves the job's named cursor to the highest ledger up to which every shard is
+// checkpointed and no batch is queued for retry, so it never passes an unprocessed ledger
+// The live cursor is never touched by a backfill
+func (p *Poller) advanceJobCursor(job string, progress *backfillProgress) error {
+	progress.cursorMu.Lock()
+	defer progress.cursorMu.Unlock()
+
+	shards, err := models.GetBackfillShards(p.db, job)
+	if err != nil || len(shards) == 0 {
+		return err
+	}
+
+	ledger := shards[0].StartLedger - 1
+	for _, shard := range shards {
+		ledger = shard.Checkpoint
+		if shard.Checkpoint < shard.EndLedger {
+			break
+		}
+	}
+
+	retries, err := models.GetBackfillRetries(p.db, job)
+	if err != nil {
+		return err
+	}
+	if len(retries) > 0 && retries[0].StartLedger-1 < ledger {
+		ledger = retries[0].StartLedger - 1
+	}
+
+	return models.UpdateCursor(p.db, job, ledger)
+}
+
+// drainBackfillRetries re-processes every queued batch of the job, up to MaxRetries attempts each
+func (p *Poller) drainBackfillRetries(ctx context.Context, config BackfillConfig, progress *backfillProgress) error {
+	retries, err := models.GetBackfillRetries(p.db, config.Job)
+	if err != nil {
+		return fmt.Errorf("load backfill retries: %w", err)
+	}
+
+	var remaining []string
+	for i := range retries {
+		retry := &retries[i]
+
+		var lastErr error
+		for attempt := 1; attempt <= config.MaxRetries; attempt++ {
+			select {
+			case <-ctx.Done():
+				return ctx.Err()
+			case <-time.After(time.Duration(attempt) * backfillRetryBackoff):
+			}
+
+			stats, err := p.processLedgerBatch(ctx, retry.StartLedger, retry.EndLedger)
+			if err == nil {
+				p.recordCoverage(config.Job, retry.StartLedger, retry.EndLedger)
+				progress.add(0, stats, false)
+				lastErr = nil
+				break
+			}
+			lastErr = err
+
+			if err := models.RecordBackfillRetryFailure(p.db, retry, err); err != nil {
+				return fmt.Errorf("record retry failure: %w", err)
+			}
+			p.logger.WithError(err).WithFields(logrus.Fields{
+				"startLedger": retry.StartLedger,
+				"endLedger":   retry.EndLedger,
+				"attempts":    retry.Attempts,
+			}).Warn("Retry of ledger batch failed")
+		}
+
+		if lastErr != nil {
+			remaining = append(remaining, fmt.Sprintf("%d-%d", retry.StartLedger, retry.EndLedger))
+			continue
+		}
+		if err := models.DeleteBackfillRetry(p.db, retry.ID); err != nil {
+			return fmt.Errorf("delete retry: %w", err)
+		}
+		p.logger.WithFields(logrus.Fields{
+			"startLedger": retry.StartLedger,
+			"endLedger":   retry.EndLedger,
+		}).Info("Retried ledger batch")
+	}
+
+	if err := p.advanceJobCursor(config.Job, progress); err != nil {
+		return fmt.Errorf("update job cursor: %w", err)
+	}
+
+	if len(remaining) > 0 {
+		return fmt.Errorf("%d ledger batches still failing after %d retries (queued for the next run of job %s): %v",
+			len(remaining), config.MaxRetries, config.Job, remaining)
+	}
+	return nil
+}
+
+// logProgress logs the overall backfill progress
+func (p *Poller) logProgress(progress *backfillProgress, shard string, currentLedger uint32) {
+	progress.mu.Lock()
+	defer progress.mu.Unlock()
+
+	percent := float64(progress.processed) / float64(progress.total) * 100
+	elapsed := time.Since(progress.started)
+	var estimated time.Duration
+	if progress.processed > 0 {
+		estimated = time.Duration(float64(elapsed) / float64(progress.processed) * float64(progress.total-progress.processed))
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"progress":           fmt.Sprintf("%.2f%%", percent),
+		"processedLedgers":   progress.processed,
+		"totalLedgers":       progress.total,
+		"shard":              shard,
+		"currentLedger":      currentLedger,
+		"events":             progress.stats.Events,
+		"transactions":       progress.stats.Transactions,
+		"operations":         progress.stats.Operations,
+		"failedBatches":      progress.failedRanges,
+		"duration":           elapsed.Round(time.Second),
+		"estimatedRemaining": estimated.Round(time.Second),
+	}).Info("Backfill progress")
+}
+
+// processLedgerBatch processes a range of ledgers
+// In ledgers mode the range is read with getLedgers. Otherwise every transaction in the range is
+// stored through getTransactions, then events are paginated with the RPC cursor and each page goes
+// through the shared pipeline
+// Progress is tracked by the caller; the live cursor is left untouched
+func (p *Poller) processLedgerBatch(ctx context.Context, startLedger, endLedger uint32) (pipeline.Stats, error) {
+	if p.mode == ModeLedgers {
+		return p.processLedgerRange(ctx, startLedger, endLedger, nil)
+	}
+
+	total, err := p.ingestTransactions(ctx, startLedger, endLedger)
+	if err != nil {
+		return total, err
+	}
+
+	req := client.GetEventsRequest{
+		StartLedger: startLedger,
+		Pagination: &client.EventPaginationParams{
+			Limit: p.batchSize,
+		},
+	}
+
+	// Keep fetching until we've processed all events in this range
+	for {
+		resp, err := p.rpcClient.GetEvents(ctx, req)
+		if err != nil {
+			return total, fmt.Errorf("get events: %w", err)
+		}
+
+		// Only keep events inside the requested range
+		page := resp.Events
+		pastEnd := false
+		for i, event := range page {
+			if event.Ledger > endLedger {
+				page = page[:i]
+				pastEnd = true
+				break
+			}
+		}
+
+		if len(page) > 0 {
+			stats, err := p.pipeline.Process(ctx, p.db, page)
+			if err != nil {
+				return total, err
+			}
+			total.Add(stats)
+		}
+
+		// Done once we've gone past the end ledger or the RPC has no more pages
+		if pastEnd || uint(len(resp.Events)) < p.batchSize || resp.Cursor == "" {
+			break
+		}
+
+		// Next page: the RPC rejects requests that set both startLedger and cursor
+		req.StartLedger = 0
+		req.Pagination.Cursor = resp.Cursor
+	}
+
+	if err := p.recordLedgers(ctx, startLedger, endLedger); err != nil {
+		p.logger.WithError(err).WithFields(logrus.Fields{
+			"startLedger": startLedger,
+			"endLedger":   endLedger,
+		}).Warn("Failed to record ledger headers")
+	}
+
+	return total, nil
+}
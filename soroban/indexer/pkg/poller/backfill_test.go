@@ -0,0 +1,375 @@
+package poller
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// setupBackfillTestDB creates a test database with the backfill checkpoint tables
+// The in-memory database lives on a single connection, so concurrent shards share it
+func setupBackfillTestDB(t *testing.T) *gorm.DB {
+	db := setupPollerTestDB(t)
+	if err := db.AutoMigrate(&models.BackfillShard{}, &models.BackfillRetry{}); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	sqlDB, err := db.DB()
+	if err != nil {
+		t.Fatalf("Failed to get sql.DB: %v", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+	return db
+}
+
+// ledgerRPC is a fake RPC serving empty ledgers from oldest (0 = 1) up to latest through getLedgers,
+// and no events or transactions
+// fail is consulted for every request and can reject it by start ledger
+type ledgerRPC struct {
+	mu            sync.Mutex
+	latest        uint32
+	oldest        uint32
+	requests      []uint32 // First ledger of every getLedgers request
+	eventRequests []uint32 // Start ledger of every getEvents request without a cursor
+	fail          func(start uint32) bool
+}
+
+func (r *ledgerRPC) server(t *testing.T) *client.Client {
+	server := newTestRPCServer(t, map[string]rpcHandler{
+		"getNetwork": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"passphrase": "Test SDF Network ; September 2015"}, nil
+		},
+		"getHealth": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"status": "healthy", "latestLedger": r.latest, "oldestLedger": r.oldest}, nil
+		},
+		"getLedgers": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetLedgersRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+
+			first := req.StartLedger
+			if req.Pagination.Cursor != "" {
+				last, err := strconv.ParseUint(req.Pagination.Cursor, 10, 32)
+				if err != nil {
+					return nil, err
+				}
+				first = uint32(last) + 1
+			}
+
+			r.mu.Lock()
+			r.requests = append(r.requests, first)
+			fail := r.fail != nil && r.fail(first)
+			r.mu.Unlock()
+			if fail {
+				return nil, fmt.Errorf("ledger %d unavailable", first)
+			}
+			if first < r.oldest {
+				return nil, fmt.Errorf("start ledger must be between the oldest ledger: %d and the latest ledger: %d", r.oldest, r.latest)
+			}
+
+			ledgers := []map[string]interface{}{}
+			seq := first
+			for ; seq < first+uint32(req.Pagination.Limit) && seq <= r.latest; seq++ {
+				ledgers = append(ledgers, map[string]interface{}{
+					"hash":            "",
+					"sequence":        seq,
+					"ledgerCloseTime": "1704067200",
+					"metadataXdr":     createEmptyLedgerMeta(t, seq),
+				})
+			}
+			return map[string]interface{}{
+				"ledgers":      ledgers,
+				"latestLedger": r.latest,
+				"cursor":       fmt.Sprintf("%d", seq-1),
+			}, nil
+		},
+		"getTransactions": emptyTransactionsHandler,
+		"getEvents": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetEventsRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+			if req.Pagination == nil || req.Pagination.Cursor == "" {
+				r.mu.Lock()
+				r.eventRequests = append(r.eventRequests, req.StartLedger)
+				r.mu.Unlock()
+				if req.StartLedger < r.oldest {
+					return nil, fmt.Errorf("startLedger must be between the oldest ledger: %d and the latest ledger: %d", r.oldest, r.latest)
+				}
+			}
+			return client.GetEventsResponse{Events: []client.Event{}, LatestLedger: r.latest}, nil
+		},
+	})
+	return client.NewClient(server.URL)
+}
+
+// requested reports whether a getLedgers request started at ledger
+func (r *ledgerRPC) requested(ledger uint32) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, start := range r.requests {
+		if start == ledger {
+			return true
+		}
+	}
+	return false
+}
+
+func newBackfillTestPoller(t *testing.T, db *gorm.DB, rpc *ledgerRPC) *Poller {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return NewWithConfig(rpc.server(t), db, logger, PollerConfig{BatchSize: 2, Mode: ModeLedgers})
+}
+
+func TestSplitRange(t *testing.T) {
+	tests := []struct {
+		start, end uint32
+		n          int
+		want       []ledgerRange
+	}{
+		{101, 110, 2, []ledgerRange{{101, 105}, {106, 110}}},
+		{101, 110, 3, []ledgerRange{{101, 104}, {105, 107}, {108, 110}}},
+		{101, 102, 4, []ledgerRange{{101, 101}, {102, 102}}},
+		{101, 101, 0, []ledgerRange{{101, 101}}},
+	}
+
+	for _, tt := range tests {
+		got := splitRange(tt.start, tt.end, tt.n)
+		if fmt.Sprint(got) != fmt.Sprint(tt.want) {
+			t.Errorf("splitRange(%d, %d, %d) = %v, want %v", tt.start, tt.end, tt.n, got, tt.want)
+		}
+	}
+}
+
+// TestBackfill_ResumesFromShardCheckpoints verifies a re-run job skips the ledgers its shards already checkpointed
+func TestBackfill_ResumesFromShardCheckpoints(t *testing.T) {
+	db := setupBackfillTestDB(t)
+	rpc := &ledgerRPC{latest: 110}
+
+	// A previous run of the job stopped after ledger 103 in the first shard
+	if err := models.CreateBackfillShards(db, "job", []models.BackfillShard{
+		{StartLedger: 101, EndLedger: 105},
+		{StartLedger: 106, EndLedger: 110},
+	}); err != nil {
+		t.Fatalf("CreateBackfillShards() error = %v", err)
+	}
+	if err := models.UpdateShardCheckpoint(db, "job:101-105", 103, models.ShardStatusRunning); err != nil {
+		t.Fatalf("UpdateShardCheckpoint() error = %v", err)
+	}
+
+	p := newBackfillTestPoller(t, db, rpc)
+	err := p.Backfill(context.Background(), BackfillConfig{
+		StartLedger: 101,
+		EndLedger:   110,
+		BatchSize:   2,
+		RateLimit:   1000,
+		Workers:     2,
+		Job:         "job",
+	})
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+
+	for _, ledger := range []uint32{101, 102, 103} {
+		if rpc.requested(ledger) {
+			t.Errorf("Ledger %d requested again, want resume after checkpoint 103", ledger)
+		}
+	}
+	if count := countLedgers(t, db); count != 7 {
+		t.Errorf("Stored ledgers = %d, want 7 (104-110)", count)
+	}
+
+	shards, err := models.GetBackfillShards(db, "job")
+	if err != nil {
+		t.Fatalf("GetBackfillShards() error = %v", err)
+	}
+	for _, shard := range shards {
+		if shard.Status != models.ShardStatusCompleted || shard.Checkpoint != shard.EndLedger {
+			t.Errorf("Shard %+v, want completed at its end ledger", shard)
+		}
+	}
+
+	cursor, err := models.GetCursor(db, "job")
+	if err != nil {
+		t.Fatalf("GetCursor() error = %v", err)
+	}
+	if cursor != 110 {
+		t.Errorf("Job cursor = %d, want 110 after backfill", cursor)
+	}
+}
+
+// TestBackfill_RetriesFailedBatch verifies a failed batch is queued and retried instead of skipped
+func TestBackfill_RetriesFailedBatch(t *testing.T) {
+	defer func(backoff time.Duration) { backfillRetryBackoff = backoff }(backfillRetryBackoff)
+	backfillRetryBackoff = time.Millisecond
+
+	db := setupBackfillTestDB(t)
+	failures := 0
+	rpc := &ledgerRPC{latest: 106, fail: func(start uint32) bool {
+		// The batch starting at 103 fails once
+		if start == 103 && failures == 0 {
+			failures++
+			return true
+		}
+		return false
+	}}
+
+	p := newBackfillTestPoller(t, db, rpc)
+	err := p.Backfill(context.Background(), BackfillConfig{
+		StartLedger: 101,
+		EndLedger:   106,
+		BatchSize:   2,
+		RateLimit:   1000,
+		Workers:     1,
+	})
+	if err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+
+	if count := countLedgers(t, db); count != 6 {
+		t.Errorf("Stored ledgers = %d, want 6 (failed batch retried)", count)
+	}
+	retries, err := models.GetBackfillRetries(db, "backfill-101-106")
+	if err != nil {
+		t.Fatalf("GetBackfillRetries() error = %v", err)
+	}
+	if len(retries) != 0 {
+		t.Errorf("Retry queue = %+v, want empty after successful retry", retries)
+	}
+	if cursor, _ := models.GetCursor(db, "backfill-101-106"); cursor != 106 {
+		t.Errorf("Job cursor = %d, want 106 once the retried batch is stored", cursor)
+	}
+	if gaps, _ := models.FindCoverageGaps(db, 101, 106); len(gaps) != 0 {
+		t.Errorf("Coverage gaps = %v, want none after retry", gaps)
+	}
+}
+
+// TestBackfill_LeavesLiveCursorAlone verifies a backfill running next to live polling never moves its cursor
+func TestBackfill_LeavesLiveCursorAlone(t *testing.T) {
+	db := setupBackfillTestDB(t)
+	if err := models.UpdateCursorWithToken(db, models.LiveCursor, 5000, "0000021474836480000-0000000001"); err != nil {
+		t.Fatalf("UpdateCursorWithToken() error = %v", err)
+	}
+
+	p := newBackfillTestPoller(t, db, &ledgerRPC{latest: 104})
+	if err := p.Backfill(context.Background(), BackfillConfig{
+		StartLedger: 101,
+		EndLedger:   104,
+		RateLimit:   1000,
+	}); err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+
+	live, err := models.GetCursorState(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if live.LastLedger != 5000 || live.PagingToken != "0000021474836480000-0000000001" {
+		t.Errorf("Live cursor = %+v, want untouched ledger 5000 with its paging token", live)
+	}
+
+	cursors, err := models.ListCursors(db)
+	if err != nil {
+		t.Fatalf("ListCursors() error = %v", err)
+	}
+	if len(cursors) != 2 || cursors[0].Name != "backfill-101-104" || cursors[0].LastLedger != 104 {
+		t.Errorf("Cursors = %+v, want the job cursor at 104 next to the live cursor", cursors)
+	}
+}
+
+// TestBackfill_KeepsFailingBatchQueued verifies a batch that never succeeds stays queued and fails the run
+func TestBackfill_KeepsFailingBatchQueued(t *testing.T) {
+	defer func(backoff time.Duration) { backfillRetryBackoff = backoff }(backfillRetryBackoff)
+	backfillRetryBackoff = time.Millisecond
+
+	db := setupBackfillTestDB(t)
+	rpc := &ledgerRPC{latest: 104, fail: func(start uint32) bool { return start == 103 }}
+
+	p := newBackfillTestPoller(t, db, rpc)
+	err := p.Backfill(context.Background(), BackfillConfig{
+		StartLedger: 101,
+		EndLedger:   104,
+		BatchSize:   2,
+		RateLimit:   1000,
+		Workers:     2,
+		Job:         "job",
+		MaxRetries:  2,
+	})
+	if err == nil {
+		t.Fatal("Backfill() error = nil, want error for batch that keeps failing")
+	}
+
+	retries, err := models.GetBackfillRetries(db, "job")
+	if err != nil {
+		t.Fatalf("GetBackfillRetries() error = %v", err)
+	}
+	if len(retries) != 1 {
+		t.Fatalf("Retry queue = %+v, want the failing batch", retries)
+	}
+	if retries[0].StartLedger != 103 || retries[0].EndLedger != 104 || retries[0].Attempts != 3 {
+		t.Errorf("Retry = %+v, want ledgers 103-104 after 3 attempts", retries[0])
+	}
+
+	// The job cursor stops before the queued batch even though both shards are checkpointed
+	cursor, err := models.GetCursor(db, "job")
+	if err != nil {
+		t.Fatalf("GetCursor() error = %v", err)
+	}
+	if cursor != 102 {
+		t.Errorf("Job cursor = %d, want 102", cursor)
+	}
+
+	gaps, err := models.FindCoverageGaps(db, 101, 104)
+	if err != nil {
+		t.Fatalf("FindCoverageGaps() error = %v", err)
+	}
+	if len(gaps) != 1 || gaps[0] != (models.LedgerRange{Start: 103, End: 104}) {
+		t.Errorf("Coverage gaps = %v, want the failing batch 103-104", gaps)
+	}
+}
+
+// TestBackfill_FromLedgerSource verifies a backfill reads a local ledger source without any RPC calls
+func TestBackfill_FromLedgerSource(t *testing.T) {
+	db := setupBackfillTestDB(t)
+
+	// Every RPC method is unknown to this server
+	server := newTestRPCServer(t, map[string]rpcHandler{})
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	p := NewWithConfig(client.NewClient(server.URL), db, logger, PollerConfig{
+		BatchSize:         3,
+		LedgerSource:      &historyLedgers{t: t, first: 1, last: 200},
+		NetworkPassphrase: "Test SDF Network ; September 2015",
+	})
+
+	if err := p.Backfill(context.Background(), BackfillConfig{
+		StartLedger: 101,
+		EndLedger:   110,
+		RateLimit:   1000,
+		Workers:     2,
+	}); err != nil {
+		t.Fatalf("Backfill() error = %v", err)
+	}
+
+	if got := countLedgers(t, db); got != 10 {
+		t.Errorf("Stored %d ledgers, want 10", got)
+	}
+}
+
+func countLedgers(t *testing.T, db *gorm.DB) int64 {
+	var count int64
+	if err := db.Model(&models.Ledger{}).Count(&count).Error; err != nil {
+		t.Fatalf("Failed to count ledgers: %v", err)
+	}
+	return count
+}
@@ -0,0 +1,107 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+)
+
+// pollLedgers ingests every ledger closed since the cursor through getLedgers
+// The cursor advances after each ledger so a failure never skips or repeats work
+func (p *Poller) pollLedgers(ctx context.Context) error {
+	start := time.Now()
+
+	cursor, err := models.GetCursor(p.db, p.cursorName)
+	if err != nil {
+		return fmt.Errorf("get cursor: %w", err)
+	}
+
+	health, err := p.rpcClient.GetHealth(ctx)
+	if err != nil {
+		return fmt.Errorf("get health: %w", err)
+	}
+	latestLedger := health.LatestLedger
+
+	// No new ledgers
+	if cursor >= latestLedger {
+		return nil
+	}
+
+	if caughtUp, err := p.checkRetention(ctx, cursor, health.OldestLedger); err != nil || caughtUp {
+		return err
+	}
+
+	startLedger := cursor + 1
+	if cursor == 0 {
+		// First run - start from current ledger
+		startLedger = latestLedger
+	}
+
+	stats, err := p.processLedgerRange(ctx, startLedger, latestLedger, func(ledger uint32) error {
+		if err := models.UpdateCursor(p.db, p.cursorName, ledger); err != nil {
+			return err
+		}
+		p.recordCoverage(p.cursorName, ledger, ledger)
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"startLedger":  startLedger,
+		"endLedger":    latestLedger,
+		"events":       stats.Events,
+		"transactions": stats.Transactions,
+		"operations":   stats.Operations,
+		"duration":     time.Since(start),
+	}).Debug("Ingested ledgers")
+
+	return nil
+}
+
+// processLedgerRange reads ledgers [startLedger, endLedger] from the ledger source and runs each one
+// through the pipeline in its own database transaction
+// done is called after each ledger is stored (may be nil)
+func (p *Poller) processLedgerRange(ctx context.Context, startLedger, endLedger uint32, done func(ledger uint32) error) (pipeline.Stats, error) {
+	var total pipeline.Stats
+
+	err := p.ledgers.Ledgers(ctx, startLedger, endLedger, func(lcm xdr.LedgerCloseMeta) error {
+		stats, err := p.pipeline.ProcessLedger(ctx, p.db, lcm)
+		if err != nil {
+			return fmt.Errorf("ledger %d: %w", lcm.LedgerSequence(), err)
+		}
+		total.Add(stats)
+
+		if done != nil {
+			if err := done(lcm.LedgerSequence()); err != nil {
+				return fmt.Errorf("update cursor: %w", err)
+			}
+		}
+		return nil
+	})
+
+	return total, err
+}
+
+// recordLedgers stores the ledger header rows for [startLedger, endLedger]
+// Used in events mode, where the pipeline never sees LedgerCloseMeta
+func (p *Poller) recordLedgers(ctx context.Context, startLedger, endLedger uint32) error {
+	return p.ledgers.Ledgers(ctx, startLedger, endLedger, func(lcm xdr.LedgerCloseMeta) error {
+		if err := p.pipeline.ArchiveLedger(lcm); err != nil {
+			return err
+		}
+		ledger, err := parser.ParseLedger(lcm)
+		if err != nil {
+			return fmt.Errorf("parse ledger %d: %w", lcm.LedgerSequence(), err)
+		}
+		return models.UpsertLedger(p.db, ledger)
+	})
+}
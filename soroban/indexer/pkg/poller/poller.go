@@ -2,16 +2,18 @@ package poller
 
 import (
 	"context"
-	"encoding/json"
 	"fmt"
+	"sync"
 	"time"
 
 	"github.com/sirupsen/logrus"
 	"gorm.io/gorm"
 
+	"github.com/blockroma/soroban-indexer/pkg/archive"
 	"github.com/blockroma/soroban-indexer/pkg/client"
 	"github.com/blockroma/soroban-indexer/pkg/models"
-	"github.com/blockroma/soroban-indexer/pkg/parser"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+	"github.com/blockroma/soroban-indexer/pkg/source"
 )
 
 type Poller struct {
@@ -20,19 +22,70 @@ type Poller struct {
 	logger         *logrus.Logger
 	batchSize      uint
 	maxConcurrency int // Maximum number of concurrent RPC requests
+	mode           IngestMode
+	cursorName     string // Cursor the poll loop reads and advances
+
+	// Shared ingestion stages used by both live polling and backfill
+	pipeline *pipeline.Pipeline
 
 	// Network passphrase for transaction hashing
 	networkPassphrase string
 
+	// LedgerCloseMeta for ledgers mode, backfill and ledger headers (getLedgers by default)
+	ledgers source.Source
+
+	// Ledgers older than the RPC retention window are read from historySource (may be nil)
+	historySource  source.Source
+	retentionMu    sync.Mutex
+	retentionAlert *RetentionAlert
+
 	// Statistics for empty hash responses (deprecated - now computed from envelope)
 	emptyHashCount   int
 	lastEmptyHashLog time.Time
 }
 
+// IngestMode selects the RPC method data is ingested from
+type IngestMode string
+
+const (
+	// ModeEvents follows getEvents and fetches each referenced transaction (default)
+	ModeEvents IngestMode = "events"
+	// ModeLedgers reads full LedgerCloseMeta from getLedgers, covering every transaction
+	ModeLedgers IngestMode = "ledgers"
+)
+
+// ParseIngestMode validates an ingestion mode name (empty = events)
+func ParseIngestMode(s string) (IngestMode, error) {
+	switch IngestMode(s) {
+	case "", ModeEvents:
+		return ModeEvents, nil
+	case ModeLedgers:
+		return ModeLedgers, nil
+	default:
+		return "", fmt.Errorf("unknown ingest mode %q (want %q or %q)", s, ModeEvents, ModeLedgers)
+	}
+}
+
 // PollerConfig defines configuration for the poller
 type PollerConfig struct {
-	BatchSize      uint // Events per request (default: 1000)
-	MaxConcurrency int  // Max concurrent RPC requests (default: 10)
+	BatchSize      uint       // Events per request (default: 1000)
+	MaxConcurrency int        // Max concurrent RPC requests (default: 10)
+	Mode           IngestMode // Ingestion source (default: events)
+	CursorName     string     // Named cursor for live polling (default: "live")
+
+	// LedgerSource replaces getLedgers as the source of LedgerCloseMeta, e.g. a local datastore
+	// export for offline backfill; backfill then always ingests full ledgers (default: RPC)
+	LedgerSource source.Source
+
+	// NetworkPassphrase skips the getNetwork lookup when set
+	NetworkPassphrase string
+
+	// Archive receives the raw RPC payloads of every ingested batch (default: none)
+	Archive archive.Sink
+
+	// HistorySource serves ledgers the RPC no longer retains (default: none, polling stops
+	// with a retention alert instead)
+	HistorySource source.Source
 }
 
 func New(rpcClient *client.Client, db *gorm.DB, logger *logrus.Logger) *Poller {
@@ -49,6 +102,25 @@ func NewWithConfig(rpcClient *client.Client, db *gorm.DB, logger *logrus.Logger,
 	if config.MaxConcurrency == 0 {
 		config.MaxConcurrency = 10
 	}
+	if config.Mode == "" {
+		config.Mode = ModeEvents
+	}
+	if config.CursorName == "" {
+		config.CursorName = models.LiveCursor
+	}
+
+	if config.LedgerSource == nil {
+		config.LedgerSource = source.NewRPC(rpcClient, config.BatchSize)
+	} else {
+		// getEvents would bypass the configured source
+		config.Mode = ModeLedgers
+	}
+
+	ingest := pipeline.New(rpcClient, logger, config.NetworkPassphrase)
+	ingest.SetMaxConcurrency(config.MaxConcurrency)
+	if config.Archive != nil {
+		ingest.SetArchive(config.Archive)
+	}
 
 	return &Poller{
 		rpcClient:      rpcClient,
@@ -56,25 +128,28 @@ func NewWithConfig(rpcClient *client.Client, db *gorm.DB, logger *logrus.Logger,
 		logger:         logger,
 		batchSize:      config.BatchSize,
 		maxConcurrency: config.MaxConcurrency,
+		mode:           config.Mode,
+		cursorName:     config.CursorName,
+		pipeline:       ingest,
+		ledgers:        config.LedgerSource,
+		historySource:  config.HistorySource,
+
+		networkPassphrase: config.NetworkPassphrase,
 	}
 }
 
 // Start begins the polling loop (1 second interval)
 func (p *Poller) Start(ctx context.Context) error {
-	p.logger.Info("Starting poller with 1-second interval")
+	p.logger.WithField("mode", p.mode).Info("Starting poller with 1-second interval")
 
 	// Check RPC health first
 	if err := p.rpcClient.Health(ctx); err != nil {
 		return fmt.Errorf("rpc health check failed: %w", err)
 	}
 
-	// Get network passphrase for transaction hashing
-	networkInfo, err := p.rpcClient.GetNetwork(ctx)
-	if err != nil {
-		return fmt.Errorf("get network info: %w", err)
+	if err := p.configureNetwork(ctx); err != nil {
+		return err
 	}
-	p.networkPassphrase = networkInfo.Passphrase
-	p.logger.WithField("networkPassphrase", p.networkPassphrase).Info("Network passphrase configured")
 
 	ticker := time.NewTicker(1 * time.Second)
 	defer ticker.Stop()
@@ -93,365 +168,175 @@ func (p *Poller) Start(ctx context.Context) error {
 	}
 }
 
-// poll fetches and processes new data
+// configureNetwork fetches the network passphrase used to recompute transaction hashes
+func (p *Poller) configureNetwork(ctx context.Context) error {
+	if p.networkPassphrase != "" {
+		return nil
+	}
+
+	networkInfo, err := p.rpcClient.GetNetwork(ctx)
+	if err != nil {
+		return fmt.Errorf("get network info: %w", err)
+	}
+	p.networkPassphrase = networkInfo.Passphrase
+	p.pipeline.SetNetworkPassphrase(p.networkPassphrase)
+	p.logger.WithField("networkPassphrase", p.networkPassphrase).Info("Network passphrase configured")
+	return nil
+}
+
+// poll fetches and processes new data using the configured ingestion mode
 func (p *Poller) poll(ctx context.Context) error {
+	if p.mode == ModeLedgers {
+		return p.pollLedgers(ctx)
+	}
+	return p.pollEvents(ctx)
+}
+
+// pollEvents fetches and processes new transactions and events
+// Events are fetched page by page following the RPC pagination cursor until the
+// ledgers are drained; the indexer cursor is only advanced once every page is stored
+func (p *Poller) pollEvents(ctx context.Context) error {
 	start := time.Now()
 
-	// Get current cursor (last processed ledger)
-	cursor, err := models.GetCursor(p.db)
+	// Get current cursor (last processed ledger and paging token)
+	cursor, err := models.GetCursorState(p.db, p.cursorName)
 	if err != nil {
 		return fmt.Errorf("get cursor: %w", err)
 	}
 
-	// Get latest ledger from RPC
-	latestLedger, err := p.rpcClient.GetLatestLedger(ctx)
+	// Get the latest and oldest retained ledgers from RPC
+	health, err := p.rpcClient.GetHealth(ctx)
 	if err != nil {
-		return fmt.Errorf("get latest ledger: %w", err)
+		return fmt.Errorf("get health: %w", err)
 	}
+	latestLedger := health.LatestLedger
 
 	// No new ledgers
-	if cursor >= latestLedger {
+	if cursor.LastLedger >= latestLedger {
 		return nil
 	}
 
-	// Fetch events since cursor
-	startLedger := cursor
-	if startLedger == 0 {
-		// First run - start from current ledger
-		startLedger = latestLedger
+	// The RPC can't serve ledgers that left its window; the next poll resumes after a catch-up
+	if caughtUp, err := p.checkRetention(ctx, cursor.LastLedger, health.OldestLedger); err != nil || caughtUp {
+		return err
+	}
+
+	firstLedger := cursor.LastLedger + 1
+	if cursor.LastLedger == 0 {
+		firstLedger = latestLedger
+	}
+
+	// Store every transaction in the new ledgers first (including failed and event-less ones)
+	// so event batches don't need per-hash lookups
+	if _, err := p.ingestTransactions(ctx, firstLedger, latestLedger); err != nil {
+		return err
 	}
 
 	req := client.GetEventsRequest{
-		StartLedger: startLedger,
 		Pagination: &client.EventPaginationParams{
 			Limit: p.batchSize,
 		},
 	}
 
-	resp, err := p.rpcClient.GetEvents(ctx, req)
-	if err != nil {
-		return fmt.Errorf("get events: %w", err)
+	// Resume from the stored paging token when we have one, otherwise from the first new ledger
+	// (the cursor itself may have left the RPC window after a catch-up from history)
+	if cursor.PagingToken != "" {
+		req.Pagination.Cursor = cursor.PagingToken
+	} else {
+		req.StartLedger = firstLedger
 	}
 
-	if len(resp.Events) == 0 {
-		// Update cursor even if no events
-		if err := models.UpdateCursor(p.db, latestLedger); err != nil {
-			return fmt.Errorf("update cursor: %w", err)
-		}
-		return nil
-	}
-
-	p.logger.WithFields(logrus.Fields{
-		"events":       len(resp.Events),
-		"startLedger":  startLedger,
-		"latestLedger": latestLedger,
-		"duration":     time.Since(start),
-	}).Info("Processing batch")
-
-	// Process events and transactions in a single transaction
-	return p.db.Transaction(func(tx *gorm.DB) error {
-		// Process events
-		eventCount := 0
-		tokenOpCount := 0
-		txHashes := make(map[string]bool)
-		contractIDs := make(map[string]bool)
-
-		for _, event := range resp.Events {
-			dbEvent, err := parser.ParseEvent(event)
-			if err != nil {
-				p.logger.WithError(err).WithField("eventID", event.ID).Warn("Failed to parse event")
-				continue
-			}
-
-			if err := models.UpsertEvent(tx, dbEvent); err != nil {
-				return fmt.Errorf("upsert event: %w", err)
-			}
-
-			eventCount++
-
-			// Track contract IDs for later processing
-			if event.ContractID != "" {
-				contractIDs[event.ContractID] = true
-			}
-
-			// Try to parse token operation from this event
-			ledgerClosedAt, _ := time.Parse(time.RFC3339, dbEvent.LedgerClosedAt)
-			topics := []interface{}{}
-			json.Unmarshal([]byte(dbEvent.Topic.(string)), &topics)
-			var value interface{}
-			json.Unmarshal([]byte(dbEvent.Value.(string)), &value)
-
-			if tokenOp := parser.ParseTokenOperation(
-				event.ID,
-				event.ContractID,
-				event.Ledger,
-				ledgerClosedAt,
-				dbEvent.TxIndex,
-				topics,
-				value,
-			); tokenOp != nil {
-				if err := models.UpsertTokenOperation(tx, tokenOp); err != nil {
-					return fmt.Errorf("upsert token operation: %w", err)
-				}
-				tokenOpCount++
-			}
+	pagingToken := cursor.PagingToken
+	pages := 0
+	totalEvents := 0
 
-			// Track unique tx hashes for transaction fetching
-			if event.TxHash != "" {
-				txHashes[event.TxHash] = true
-			}
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
 		}
 
-		// Fetch and process transactions
-		txCount := 0
-		operationCount := 0
-		contractCodeCount := 0
-		contractDataCount := 0
-		txWithMetaCount := 0
-
-		for txHash := range txHashes {
-			rpcTx, err := p.rpcClient.GetTransaction(ctx, txHash)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to fetch transaction")
-				continue
-			}
-
-			// Determine the correct transaction hash
-			actualTxHash := txHash // Start with the hash from events
-
-			// If RPC returned an empty hash, compute it from the envelope
-			if rpcTx.Hash == "" {
-				computedHash, err := parser.ComputeTransactionHash(rpcTx.EnvelopeXdr, p.networkPassphrase)
-				if err != nil {
-					// Failed to compute hash - will use event hash
-				} else {
-					actualTxHash = computedHash
-					// Verify it matches the event hash
-					if computedHash != txHash {
-						p.logger.WithFields(logrus.Fields{
-							"eventHash":    txHash,
-							"computedHash": computedHash,
-						}).Warn("Computed hash from envelope differs from event hash")
-					}
-				}
-			} else if rpcTx.Hash != txHash {
-				// RPC returned a hash but it doesn't match the event hash
-				p.logger.WithFields(logrus.Fields{
-					"eventHash": txHash,
-					"rpcHash":   rpcTx.Hash,
-				}).Warn("RPC returned different hash than event")
-				// Use the RPC hash if available
-				actualTxHash = rpcTx.Hash
-			} else {
-				// RPC hash matches event hash - use it
-				actualTxHash = rpcTx.Hash
-			}
-
-			// Parse transaction with the determined hash
-			dbTx, err := parser.ParseTransactionWithHash(*rpcTx, actualTxHash)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to parse transaction")
-				continue
-			}
-
-			if err := models.UpsertTransaction(tx, dbTx); err != nil {
-				return fmt.Errorf("upsert transaction: %w", err)
-			}
-
-			txCount++
-
-			// Extract contract data from transaction metadata (passive indexing)
-			// This captures contract storage changes from successful transactions
-			if rpcTx.ResultMetaXdr != "" {
-				txWithMetaCount++
-				contractDataEntries, err := parser.ExtractContractDataFromMeta(txHash, rpcTx.ResultMetaXdr)
-				if err != nil {
-					p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to extract contract data from meta")
-				} else if len(contractDataEntries) > 0 {
-					p.logger.WithFields(logrus.Fields{
-						"txHash":      txHash,
-						"entryCount":  len(contractDataEntries),
-					}).Info("Extracted contract data from transaction metadata")
-
-					for _, entry := range contractDataEntries {
-						if err := models.UpsertContractDataEntry(tx, entry); err != nil {
-							p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to upsert contract data entry from meta")
-						} else {
-							contractDataCount++
-
-							// Unmarshal JSONB bytes back to interface{} for parsing
-							var keyInterface interface{}
-							var valInterface interface{}
-							if err := json.Unmarshal(entry.Key, &keyInterface); err != nil {
-								continue
-							}
-							if err := json.Unmarshal(entry.Val, &valInterface); err != nil {
-								continue
-							}
-
-							// Try to parse token metadata from this contract data entry
-							if metadata := parser.ParseTokenMetadata(entry.ContractID, keyInterface, valInterface); metadata != nil {
-								if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
-									p.logger.WithError(err).WithField("contractID", entry.ContractID).Warn("Failed to upsert token metadata from meta")
-								} else {
-									p.logger.WithFields(logrus.Fields{
-										"contractID": entry.ContractID,
-										"name":       metadata.Name,
-										"symbol":     metadata.Symbol,
-									}).Info("Successfully extracted and saved token metadata")
-								}
-							}
-
-							// Try to parse token balance from this contract data entry
-							if balance := parser.ParseTokenBalance(entry.ContractID, keyInterface, valInterface); balance != nil {
-								if err := models.UpsertTokenBalance(tx, balance); err != nil {
-									p.logger.WithError(err).WithField("contractID", entry.ContractID).Warn("Failed to upsert token balance from meta")
-								}
-							}
-						}
-					}
-				}
-
-				// Extract contract instance metadata from deployment transactions
-				// This captures token metadata (name, symbol, decimals) at contract creation
-				instanceMetadata, err := parser.ExtractContractInstanceFromMeta(txHash, rpcTx.ResultMetaXdr)
-				if err == nil && len(instanceMetadata) > 0 {
-					for contractID, metadata := range instanceMetadata {
-						if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
-							p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to upsert token metadata from deployment")
-						} else {
-							p.logger.WithFields(logrus.Fields{
-								"contractID": contractID,
-								"name":       metadata.Name,
-								"symbol":     metadata.Symbol,
-								"decimals":   metadata.Decimal,
-							}).Info("Successfully extracted and saved token metadata from contract deployment")
-						}
-					}
-				}
-			}
-
-			// Parse and collect operations from this transaction
-			operations, err := parser.ParseOperations(txHash, rpcTx.EnvelopeXdr)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to parse operations")
-			} else {
-				// Batch upsert all operations for this transaction
-				if len(operations) > 0 {
-					if err := models.BatchUpsertOperations(tx, operations); err != nil {
-						p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to batch upsert operations")
-					} else {
-						operationCount += len(operations)
-					}
-				}
-			}
+		resp, err := p.rpcClient.GetEvents(ctx, req)
+		if err != nil {
+			return fmt.Errorf("get events: %w", err)
+		}
+		pages++
 
-			// Extract and store contract code (WASM) from UploadContractWasm operations
-			contractCodes, err := parser.ExtractContractCodeFromEnvelope(txHash, rpcTx.Ledger, rpcTx.LedgerCloseTime, rpcTx.EnvelopeXdr)
-			if err == nil {
-				for _, code := range contractCodes {
-					if err := models.UpsertContractCode(tx, code); err != nil {
-						p.logger.WithError(err).WithField("hash", code.Hash).Warn("Failed to upsert contract code")
-					} else {
-						contractCodeCount++
-					}
-				}
+		if len(resp.Events) > 0 {
+			p.logger.WithFields(logrus.Fields{
+				"events":       len(resp.Events),
+				"page":         pages,
+				"startLedger":  req.StartLedger,
+				"cursor":       req.Pagination.Cursor,
+				"latestLedger": latestLedger,
+				"duration":     time.Since(start),
+			}).Info("Processing batch")
+
+			if _, err := p.pipeline.Process(ctx, p.db, resp.Events); err != nil {
+				return err
 			}
+			totalEvents += len(resp.Events)
 		}
 
-		// Process contract data for discovered contracts
-		// This extracts token metadata and balances from contract storage
-		if err := p.processContractData(ctx, tx, contractIDs); err != nil {
-			p.logger.WithError(err).Warn("Failed to process contract data")
-			// Don't fail the whole batch if contract data processing fails
+		// Prefer the cursor returned by the RPC, fall back to the last event's paging token
+		if resp.Cursor != "" {
+			pagingToken = resp.Cursor
+		} else if len(resp.Events) > 0 {
+			pagingToken = resp.Events[len(resp.Events)-1].PagingToken
 		}
 
-		// Note: Account/trustline/offer/claimable balance processing removed - Soroban RPC returns corrupted XDR
-		// See SOROBAN_RPC_LIMITATIONS.md for details - these tables cannot be populated via Soroban RPC
-
-		// Update cursor to latest ledger
-		if err := models.UpdateCursor(tx, latestLedger); err != nil {
-			return fmt.Errorf("update cursor: %w", err)
+		// A short page means the ledgers are drained
+		if uint(len(resp.Events)) < p.batchSize || pagingToken == "" {
+			break
 		}
 
-		p.logger.WithFields(logrus.Fields{
-			"events":        eventCount,
-			"transactions":  txCount,
-			"txWithMeta":    txWithMetaCount,
-			"operations":    operationCount,
-			"contractCode":  contractCodeCount,
-			"contractData":  contractDataCount,
-			"tokenOps":      tokenOpCount,
-			"contracts":     len(contractIDs),
-			"ledger":        latestLedger,
-			"duration":      time.Since(start),
-		}).Info("Batch processed successfully")
-
-		return nil
-	})
-}
-
-// processContractData proactively fetches contract storage data for metadata and balances
-// This is called after processing events to update contract state
-func (p *Poller) processContractData(ctx context.Context, tx *gorm.DB, contractIDs map[string]bool) error {
-	if len(contractIDs) == 0 {
-		return nil
+		// Next page: the RPC rejects requests that set both startLedger and cursor
+		req.StartLedger = 0
+		req.Pagination.Cursor = pagingToken
 	}
 
-	metadataCount := 0
-	balanceCount := 0
-	contractDataCount := 0
-
-	// For each contract, query existing contract data entries for this contract (passive indexing)
-	// Note: Proactive fetching via getLedgerEntries disabled - Soroban RPC returns corrupted XDR
-	// We rely on passive indexing from transaction metadata instead
-	for contractID := range contractIDs {
-		// Query existing contract data entries for this contract (passive indexing)
-		var entries []models.ContractDataEntry
-		if err := p.db.Where("contract_id = ?", contractID).Find(&entries).Error; err != nil {
-			p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to fetch contract data")
-			continue
-		}
-
-		for _, entry := range entries {
-			contractDataCount++
-
-			// Unmarshal JSONB bytes back to interface{} for parsing
-			var keyInterface interface{}
-			var valInterface interface{}
-			if err := json.Unmarshal(entry.Key, &keyInterface); err != nil {
-				continue
-			}
-			if err := json.Unmarshal(entry.Val, &valInterface); err != nil {
-				continue
-			}
-
-			// Try to parse token metadata from existing data
-			if metadata := parser.ParseTokenMetadata(contractID, keyInterface, valInterface); metadata != nil {
-				if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
-					p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to upsert token metadata")
-				} else {
-					metadataCount++
-				}
-			}
+	// All pages stored - advance cursor to latest ledger
+	if err := models.UpdateCursorWithToken(p.db, p.cursorName, latestLedger, pagingToken); err != nil {
+		return fmt.Errorf("update cursor: %w", err)
+	}
+	p.recordCoverage(p.cursorName, firstLedger, latestLedger)
+
+	// Ledger headers are best-effort in events mode - a failure must not block event ingestion
+	if err := p.recordLedgers(ctx, firstLedger, latestLedger); err != nil {
+		p.logger.WithError(err).WithFields(logrus.Fields{
+			"startLedger": firstLedger,
+			"endLedger":   latestLedger,
+		}).Warn("Failed to record ledger headers")
+	}
 
-			// Try to parse token balance from existing data
-			if balance := parser.ParseTokenBalance(contractID, keyInterface, valInterface); balance != nil {
-				if err := models.UpsertTokenBalance(tx, balance); err != nil {
-					p.logger.WithError(err).WithField("contractID", contractID).Warn("Failed to upsert token balance")
-				} else {
-					balanceCount++
-				}
-			}
-		}
+	if pages > 1 {
+		p.logger.WithFields(logrus.Fields{
+			"events":   totalEvents,
+			"pages":    pages,
+			"ledger":   latestLedger,
+			"duration": time.Since(start),
+		}).Info("Drained paginated events")
 	}
 
 	return nil
 }
 
+// recordCoverage marks a range as ingested for gap detection
+// Best-effort: a missing row only makes verify re-check (and repair) the range
+func (p *Poller) recordCoverage(source string, startLedger, endLedger uint32) {
+	if err := models.RecordLedgerCoverage(p.db, source, startLedger, endLedger); err != nil {
+		p.logger.WithError(err).WithFields(logrus.Fields{
+			"source":      source,
+			"startLedger": startLedger,
+			"endLedger":   endLedger,
+		}).Warn("Failed to record ledger coverage")
+	}
+}
+
 // GetStats returns poller statistics
 func (p *Poller) GetStats() (map[string]interface{}, error) {
-	cursor, err := models.GetCursor(p.db)
+	cursor, err := models.GetCursor(p.db, p.cursorName)
 	if err != nil {
 		return nil, err
 	}
@@ -477,336 +362,11 @@ func (p *Poller) GetStats() (map[string]interface{}, error) {
 	}
 
 	return map[string]interface{}{
-		"lastLedger":         cursor,
-		"totalEvents":        eventCount,
-		"totalTransactions":  txCount,
-		"totalTokenOps":      tokenOpCount,
-		"totalContractData":  contractDataCount,
+		"lastLedger":        cursor,
+		"totalEvents":       eventCount,
+		"totalTransactions": txCount,
+		"totalTokenOps":     tokenOpCount,
+		"totalContractData": contractDataCount,
+		"retentionAlert":    p.RetentionAlert(),
 	}, nil
 }
-
-// BackfillConfig defines configuration for backfill mode
-type BackfillConfig struct {
-	StartLedger uint32 // First ledger to process
-	EndLedger   uint32 // Last ledger to process (0 = current ledger)
-	BatchSize   uint32 // Number of ledgers to process per batch
-	RateLimit   uint32 // Max requests per second
-}
-
-// Backfill processes historical ledgers sequentially
-func (p *Poller) Backfill(ctx context.Context, config BackfillConfig) error {
-	start := time.Now()
-
-	// Validate configuration
-	if config.StartLedger == 0 {
-		return fmt.Errorf("start ledger must be > 0")
-	}
-	if config.BatchSize == 0 {
-		config.BatchSize = 100 // Default batch size
-	}
-	if config.RateLimit == 0 {
-		config.RateLimit = 10 // Default 10 requests/sec
-	}
-
-	// Get current ledger if end ledger not specified
-	if config.EndLedger == 0 {
-		latestLedger, err := p.rpcClient.GetLatestLedger(ctx)
-		if err != nil {
-			return fmt.Errorf("get latest ledger: %w", err)
-		}
-		config.EndLedger = latestLedger
-	}
-
-	// Validate range
-	if config.StartLedger > config.EndLedger {
-		return fmt.Errorf("start ledger (%d) must be <= end ledger (%d)", config.StartLedger, config.EndLedger)
-	}
-
-	totalLedgers := config.EndLedger - config.StartLedger + 1
-	p.logger.WithFields(logrus.Fields{
-		"startLedger":  config.StartLedger,
-		"endLedger":    config.EndLedger,
-		"totalLedgers": totalLedgers,
-		"batchSize":    config.BatchSize,
-		"rateLimit":    config.RateLimit,
-	}).Info("Starting backfill")
-
-	// Rate limiter: requests per second
-	rateLimiter := time.NewTicker(time.Second / time.Duration(config.RateLimit))
-	defer rateLimiter.Stop()
-
-	// Progress tracking
-	processedLedgers := uint32(0)
-	totalEvents := 0
-	totalTransactions := 0
-	totalOperations := 0
-	lastProgressLog := time.Now()
-
-	// Process ledgers in sequential order
-	currentLedger := config.StartLedger
-
-	for currentLedger <= config.EndLedger {
-		select {
-		case <-ctx.Done():
-			p.logger.WithFields(logrus.Fields{
-				"processedLedgers": processedLedgers,
-				"currentLedger":    currentLedger,
-				"duration":         time.Since(start),
-			}).Info("Backfill cancelled")
-			return ctx.Err()
-		case <-rateLimiter.C:
-			// Rate limit: process one batch per tick
-		}
-
-		// Determine batch range
-		endBatchLedger := currentLedger
-		if currentLedger+config.BatchSize-1 < config.EndLedger {
-			endBatchLedger = currentLedger + config.BatchSize - 1
-		} else {
-			endBatchLedger = config.EndLedger
-		}
-
-		// Process this batch of ledgers
-		batchStart := time.Now()
-		batchEvents, batchTxs, batchOps, err := p.processLedgerBatch(ctx, currentLedger, endBatchLedger)
-		if err != nil {
-			p.logger.WithError(err).WithFields(logrus.Fields{
-				"startLedger": currentLedger,
-				"endLedger":   endBatchLedger,
-			}).Error("Failed to process ledger batch")
-
-			// Try to resume from the failed ledger
-			// For now, we skip and continue (can be made configurable)
-			p.logger.Warn("Continuing to next batch after error")
-		}
-
-		processedLedgers += (endBatchLedger - currentLedger + 1)
-		totalEvents += batchEvents
-		totalTransactions += batchTxs
-		totalOperations += batchOps
-
-		// Log progress every 10 seconds
-		if time.Since(lastProgressLog) >= 10*time.Second {
-			progress := float64(processedLedgers) / float64(totalLedgers) * 100
-			estimated := time.Duration(float64(time.Since(start)) / float64(processedLedgers) * float64(totalLedgers-processedLedgers))
-
-			p.logger.WithFields(logrus.Fields{
-				"progress":          fmt.Sprintf("%.2f%%", progress),
-				"processedLedgers":  processedLedgers,
-				"totalLedgers":      totalLedgers,
-				"currentLedger":     endBatchLedger,
-				"events":            totalEvents,
-				"transactions":      totalTransactions,
-				"operations":        totalOperations,
-				"duration":          time.Since(start).Round(time.Second),
-				"estimatedRemaining": estimated.Round(time.Second),
-				"batchDuration":     batchStart.Sub(lastProgressLog).Round(time.Millisecond),
-			}).Info("Backfill progress")
-			lastProgressLog = time.Now()
-		}
-
-		// Move to next batch
-		currentLedger = endBatchLedger + 1
-	}
-
-	// Final summary
-	duration := time.Since(start)
-	rate := float64(processedLedgers) / duration.Seconds()
-
-	p.logger.WithFields(logrus.Fields{
-		"totalLedgers":     processedLedgers,
-		"events":           totalEvents,
-		"transactions":     totalTransactions,
-		"operations":       totalOperations,
-		"duration":         duration.Round(time.Second),
-		"ledgersPerSecond": fmt.Sprintf("%.2f", rate),
-	}).Info("Backfill completed successfully")
-
-	return nil
-}
-
-// processLedgerBatch processes a range of ledgers and returns counts
-func (p *Poller) processLedgerBatch(ctx context.Context, startLedger, endLedger uint32) (events, txs, ops int, err error) {
-	// Fetch events for this ledger range
-	req := client.GetEventsRequest{
-		StartLedger: startLedger,
-		Pagination: &client.EventPaginationParams{
-			Limit: p.batchSize,
-		},
-	}
-
-	// Keep fetching until we've processed all events in this range
-	for {
-		resp, err := p.rpcClient.GetEvents(ctx, req)
-		if err != nil {
-			return events, txs, ops, fmt.Errorf("get events: %w", err)
-		}
-
-		if len(resp.Events) == 0 {
-			break
-		}
-
-		// Process this page of events
-		pageEvents, pageTxs, pageOps, err := p.processEventBatch(ctx, resp.Events)
-		if err != nil {
-			return events, txs, ops, err
-		}
-
-		events += pageEvents
-		txs += pageTxs
-		ops += pageOps
-
-		// Check if we've gone past the end ledger
-		if len(resp.Events) > 0 && resp.Events[len(resp.Events)-1].Ledger > endLedger {
-			break
-		}
-
-		// If there's a cursor, use it for pagination
-		if resp.LatestLedger > 0 && resp.LatestLedger >= endLedger {
-			break
-		}
-
-		// No more pages
-		if len(resp.Events) < int(p.batchSize) {
-			break
-		}
-
-		// Update request to fetch next page (using last event's ledger)
-		if len(resp.Events) > 0 {
-			lastLedger := resp.Events[len(resp.Events)-1].Ledger
-			req.StartLedger = lastLedger
-		}
-	}
-
-	// Update cursor to end of this batch
-	if err := models.UpdateCursor(p.db, endLedger); err != nil {
-		return events, txs, ops, fmt.Errorf("update cursor: %w", err)
-	}
-
-	return events, txs, ops, nil
-}
-
-// processEventBatch processes a batch of events (similar to poll() but without cursor updates)
-func (p *Poller) processEventBatch(ctx context.Context, eventList []client.Event) (events, txs, ops int, err error) {
-	// Process events and transactions in a single transaction
-	err = p.db.Transaction(func(tx *gorm.DB) error {
-		eventCount := 0
-		txHashes := make(map[string]bool)
-		contractIDs := make(map[string]bool)
-
-		for _, event := range eventList {
-			dbEvent, err := parser.ParseEvent(event)
-			if err != nil {
-				p.logger.WithError(err).WithField("eventID", event.ID).Warn("Failed to parse event")
-				continue
-			}
-
-			if err := models.UpsertEvent(tx, dbEvent); err != nil {
-				return fmt.Errorf("upsert event: %w", err)
-			}
-
-			eventCount++
-
-			// Track contract IDs for later processing
-			if event.ContractID != "" {
-				contractIDs[event.ContractID] = true
-			}
-
-			// Try to parse token operation from this event
-			ledgerClosedAt, _ := time.Parse(time.RFC3339, dbEvent.LedgerClosedAt)
-			topics := []interface{}{}
-			json.Unmarshal([]byte(dbEvent.Topic.(string)), &topics)
-			var value interface{}
-			json.Unmarshal([]byte(dbEvent.Value.(string)), &value)
-
-			if tokenOp := parser.ParseTokenOperation(
-				event.ID,
-				event.ContractID,
-				event.Ledger,
-				ledgerClosedAt,
-				dbEvent.TxIndex,
-				topics,
-				value,
-			); tokenOp != nil {
-				if err := models.UpsertTokenOperation(tx, tokenOp); err != nil {
-					return fmt.Errorf("upsert token operation: %w", err)
-				}
-			}
-
-			// Track unique tx hashes for transaction fetching
-			if event.TxHash != "" {
-				txHashes[event.TxHash] = true
-			}
-		}
-
-		// Fetch and process transactions
-		txCount := 0
-		operationCount := 0
-		contractCodeCount := 0
-
-		for txHash := range txHashes {
-			rpcTx, err := p.rpcClient.GetTransaction(ctx, txHash)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to fetch transaction")
-				continue
-			}
-
-			// Use the hash we requested (from the event) as the transaction ID
-			dbTx, err := parser.ParseTransactionWithHash(*rpcTx, txHash)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to parse transaction")
-				continue
-			}
-
-			if err := models.UpsertTransaction(tx, dbTx); err != nil {
-				return fmt.Errorf("upsert transaction: %w", err)
-			}
-
-			txCount++
-
-			// Parse and collect operations from this transaction
-			operations, err := parser.ParseOperations(txHash, rpcTx.EnvelopeXdr)
-			if err != nil {
-				p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to parse operations")
-			} else {
-				// Batch upsert all operations for this transaction
-				if len(operations) > 0 {
-					if err := models.BatchUpsertOperations(tx, operations); err != nil {
-						p.logger.WithError(err).WithField("txHash", txHash).Warn("Failed to batch upsert operations")
-					} else {
-						operationCount += len(operations)
-					}
-				}
-			}
-
-			// Extract and store contract code (WASM) from UploadContractWasm operations
-			contractCodes, err := parser.ExtractContractCodeFromEnvelope(txHash, rpcTx.Ledger, rpcTx.LedgerCloseTime, rpcTx.EnvelopeXdr)
-			if err == nil {
-				for _, code := range contractCodes {
-					if err := models.UpsertContractCode(tx, code); err != nil {
-						p.logger.WithError(err).WithField("hash", code.Hash).Warn("Failed to upsert contract code")
-					} else {
-						contractCodeCount++
-					}
-				}
-			}
-		}
-
-		// Process contract data for discovered contracts
-		if err := p.processContractData(ctx, tx, contractIDs); err != nil {
-			p.logger.WithError(err).Warn("Failed to process contract data")
-		}
-
-		// Note: Account/trustline/offer/data/claimable balance/liquidity pool processing not supported
-		// Soroban RPC returns corrupted XDR for these ledger entry types
-		// Use Horizon API for indexing these classic Stellar ledger entries
-
-		events = eventCount
-		txs = txCount
-		ops = operationCount
-
-		return nil
-	})
-
-	return events, txs, ops, err
-}
@@ -1,6 +1,13 @@
 package poller
 
 import (
+	"context"
+	"encoding/binary"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/sirupsen/logrus"
@@ -8,6 +15,7 @@ import (
 	"gorm.io/driver/sqlite"
 	"gorm.io/gorm"
 
+	"github.com/blockroma/soroban-indexer/pkg/client"
 	"github.com/blockroma/soroban-indexer/pkg/models"
 )
 
@@ -514,3 +522,414 @@ func TestGetLedgerEntriesResponse(t *testing.T) {
 	}
 }
 */
+
+// rpcHandler returns the JSON result (or an error) for a single JSON-RPC method call
+type rpcHandler func(params json.RawMessage) (interface{}, error)
+
+// newTestRPCServer starts a fake Stellar RPC that dispatches JSON-RPC calls by method name
+func newTestRPCServer(t *testing.T, handlers map[string]rpcHandler) *httptest.Server {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int             `json:"id"`
+			Method string          `json:"method"`
+			Params json.RawMessage `json:"params"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			t.Errorf("Failed to decode request: %v", err)
+			return
+		}
+
+		resp := map[string]interface{}{"jsonrpc": "2.0", "id": req.ID}
+		handler, ok := handlers[req.Method]
+		if !ok {
+			resp["error"] = map[string]interface{}{"code": -32601, "message": "method not found"}
+		} else if result, err := handler(req.Params); err != nil {
+			resp["error"] = map[string]interface{}{"code": -32600, "message": err.Error()}
+		} else {
+			resp["result"] = result
+		}
+		json.NewEncoder(w).Encode(resp)
+	}))
+	t.Cleanup(server.Close)
+	return server
+}
+
+// emptyTransactionsHandler answers getTransactions with no transactions
+func emptyTransactionsHandler(json.RawMessage) (interface{}, error) {
+	return client.GetTransactionsResponse{Transactions: []client.TransactionInfo{}}, nil
+}
+
+// setupPollerTestDB creates an in-memory SQLite database with every table the poll loop writes
+func setupPollerTestDB(t *testing.T) *gorm.DB {
+	db := setupTestDB(t)
+	if err := db.AutoMigrate(
+		&models.Event{},
+		&models.Operation{},
+		&models.Ledger{},
+		&models.LedgerCoverage{},
+		&models.Cursor{},
+		&models.TokenMetadata{},
+		&models.TokenOperation{},
+		&models.TokenBalance{},
+		&models.TokenBalanceChange{},
+		&models.TokenSupply{},
+		&models.TokenAllowance{},
+		&models.DiagnosticEvent{},
+		&models.ContractDataEntry{},
+		&models.ContractDataHistory{},
+		&models.ContractCode{},
+	); err != nil {
+		t.Fatalf("Failed to migrate test database: %v", err)
+	}
+	return db
+}
+
+// TestPoll_FollowsPaginationCursor verifies that poll drains every page before advancing the cursor
+func TestPoll_FollowsPaginationCursor(t *testing.T) {
+	db := setupPollerTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	if err := models.UpdateCursor(db, models.LiveCursor, 100); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+
+	var requests []client.GetEventsRequest
+	pages := map[string]client.GetEventsResponse{
+		"": {
+			Events: []client.Event{
+				{ID: "0000000429496729601-0000000001", Ledger: 100, PagingToken: "0000000429496729601-0000000001", Topic: []string{}},
+				{ID: "0000000429496729601-0000000002", Ledger: 100, PagingToken: "0000000429496729601-0000000002", Topic: []string{}},
+			},
+			Cursor: "0000000429496729601-0000000002",
+		},
+		"0000000429496729601-0000000002": {
+			Events: []client.Event{
+				{ID: "0000000433791696897-0000000001", Ledger: 101, PagingToken: "0000000433791696897-0000000001", Topic: []string{}},
+				{ID: "0000000433791696897-0000000002", Ledger: 101, PagingToken: "0000000433791696897-0000000002", Topic: []string{}},
+			},
+			Cursor: "0000000433791696897-0000000002",
+		},
+		"0000000433791696897-0000000002": {
+			Events: []client.Event{
+				{ID: "0000000438086664193-0000000001", Ledger: 102, PagingToken: "0000000438086664193-0000000001", Topic: []string{}},
+			},
+			Cursor: "0000000442381631487-4294967295",
+		},
+	}
+
+	server := newTestRPCServer(t, map[string]rpcHandler{
+		"getHealth": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"status": "healthy", "latestLedger": 102}, nil
+		},
+		"getTransactions": emptyTransactionsHandler,
+		"getEvents": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetEventsRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+			requests = append(requests, req)
+			page := pages[req.Pagination.Cursor]
+			page.LatestLedger = 102
+			return page, nil
+		},
+	})
+
+	p := NewWithConfig(client.NewClient(server.URL), db, logger, PollerConfig{BatchSize: 2})
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+
+	if len(requests) != 3 {
+		t.Fatalf("getEvents calls = %d, want 3", len(requests))
+	}
+	if requests[0].StartLedger != 101 || requests[0].Pagination.Cursor != "" {
+		t.Errorf("First request = %+v, want startLedger 101 without cursor", requests[0])
+	}
+	for _, req := range requests[1:] {
+		if req.StartLedger != 0 {
+			t.Errorf("Paginated request should omit startLedger, got %d", req.StartLedger)
+		}
+	}
+
+	var eventCount int64
+	db.Model(&models.Event{}).Count(&eventCount)
+	if eventCount != 5 {
+		t.Errorf("Stored events = %d, want 5", eventCount)
+	}
+
+	state, err := models.GetCursorState(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 102 {
+		t.Errorf("Cursor ledger = %d, want 102", state.LastLedger)
+	}
+	if state.PagingToken != "0000000442381631487-4294967295" {
+		t.Errorf("Cursor paging token = %s, want 0000000442381631487-4294967295", state.PagingToken)
+	}
+}
+
+// TestPoll_CursorNotAdvancedOnPageError verifies that a failed page leaves the cursor untouched
+func TestPoll_CursorNotAdvancedOnPageError(t *testing.T) {
+	db := setupPollerTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	if err := models.UpdateCursor(db, models.LiveCursor, 100); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+
+	server := newTestRPCServer(t, map[string]rpcHandler{
+		"getHealth": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"status": "healthy", "latestLedger": 102}, nil
+		},
+		"getTransactions": emptyTransactionsHandler,
+		"getEvents": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetEventsRequest
+			json.Unmarshal(params, &req)
+			if req.Pagination.Cursor != "" {
+				return nil, errors.New("cursor expired")
+			}
+			return client.GetEventsResponse{
+				Events: []client.Event{
+					{ID: "0000000429496729601-0000000001", Ledger: 100, PagingToken: "0000000429496729601-0000000001", Topic: []string{}},
+				},
+				Cursor:       "0000000429496729601-0000000001",
+				LatestLedger: 102,
+			}, nil
+		},
+	})
+
+	p := NewWithConfig(client.NewClient(server.URL), db, logger, PollerConfig{BatchSize: 1})
+	if err := p.poll(context.Background()); err == nil {
+		t.Fatal("poll() error = nil, want error from second page")
+	}
+
+	state, err := models.GetCursorState(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursorState() error = %v", err)
+	}
+	if state.LastLedger != 100 || state.PagingToken != "" {
+		t.Errorf("Cursor = %+v, want unchanged ledger 100 with no token", state)
+	}
+}
+
+// createEmptyLedgerMeta returns base64 LedgerCloseMeta for a ledger without transactions
+// Hashes are derived from the sequence so consecutive ledgers form a valid chain
+func createEmptyLedgerMeta(t *testing.T, sequence uint32) string {
+	var hash, previousHash xdr.Hash
+	binary.BigEndian.PutUint32(hash[:], sequence)
+	binary.BigEndian.PutUint32(previousHash[:], sequence-1)
+
+	lcm := xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Hash:   hash,
+				Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(sequence), PreviousLedgerHash: previousHash},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V:       1,
+				V1TxSet: &xdr.TransactionSetV1{},
+			},
+		},
+	}
+	encoded, err := xdr.MarshalBase64(lcm)
+	if err != nil {
+		t.Fatalf("Failed to marshal ledger meta: %v", err)
+	}
+	return encoded
+}
+
+// TestPollLedgers_AdvancesCursorPerLedger verifies ledger mode pages through getLedgers from cursor+1
+func TestPollLedgers_AdvancesCursorPerLedger(t *testing.T) {
+	db := setupPollerTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	if err := models.UpdateCursor(db, models.LiveCursor, 100); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+
+	var requests []client.GetLedgersRequest
+	server := newTestRPCServer(t, map[string]rpcHandler{
+		"getHealth": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"status": "healthy", "latestLedger": 103}, nil
+		},
+		"getLedgers": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetLedgersRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+			requests = append(requests, req)
+
+			first := req.StartLedger
+			if req.Pagination.Cursor == "102" {
+				first = 103
+			} else if req.Pagination.Cursor != "" {
+				return nil, errors.New("unexpected cursor")
+			}
+
+			var ledgers []map[string]interface{}
+			for seq := first; seq < first+2 && seq <= 103; seq++ {
+				ledgers = append(ledgers, map[string]interface{}{
+					"hash":            "",
+					"sequence":        seq,
+					"ledgerCloseTime": "1704067200",
+					"metadataXdr":     createEmptyLedgerMeta(t, seq),
+				})
+			}
+			return map[string]interface{}{
+				"ledgers":      ledgers,
+				"latestLedger": 103,
+				"cursor":       fmt.Sprintf("%d", first+1),
+			}, nil
+		},
+	})
+
+	p := NewWithConfig(client.NewClient(server.URL), db, logger, PollerConfig{BatchSize: 2, Mode: ModeLedgers})
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+
+	if len(requests) != 2 {
+		t.Fatalf("getLedgers calls = %d, want 2", len(requests))
+	}
+	if requests[0].StartLedger != 101 || requests[0].Pagination.Limit != 2 {
+		t.Errorf("First request = %+v, want startLedger 101 with limit 2", requests[0])
+	}
+	if requests[1].StartLedger != 0 || requests[1].Pagination.Cursor != "102" {
+		t.Errorf("Second request = %+v, want cursor 102 without startLedger", requests[1])
+	}
+
+	cursor, err := models.GetCursor(db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursor() error = %v", err)
+	}
+	if cursor != 103 {
+		t.Errorf("Cursor = %d, want 103", cursor)
+	}
+
+	// Each ingested ledger gets a header row, linked to its predecessor
+	ledgers, err := models.GetLedgerRange(db, 101, 103)
+	if err != nil {
+		t.Fatalf("GetLedgerRange() error = %v", err)
+	}
+	if len(ledgers) != 3 {
+		t.Fatalf("Stored ledgers = %d, want 3", len(ledgers))
+	}
+	if breaks, _ := models.FindLedgerChainBreaks(db, 101, 103); len(breaks) != 0 {
+		t.Errorf("Chain breaks = %v, want none", breaks)
+	}
+	if gaps, _ := models.FindCoverageGaps(db, 101, 103); len(gaps) != 0 {
+		t.Errorf("Coverage gaps = %v, want none", gaps)
+	}
+}
+
+func TestParseIngestMode(t *testing.T) {
+	tests := []struct {
+		input   string
+		want    IngestMode
+		wantErr bool
+	}{
+		{"", ModeEvents, false},
+		{"events", ModeEvents, false},
+		{"ledgers", ModeLedgers, false},
+		{"blocks", "", true},
+	}
+
+	for _, tt := range tests {
+		got, err := ParseIngestMode(tt.input)
+		if (err != nil) != tt.wantErr || got != tt.want {
+			t.Errorf("ParseIngestMode(%q) = %v, %v; want %v, err %v", tt.input, got, err, tt.want, tt.wantErr)
+		}
+	}
+}
+
+// TestPoll_IngestsAllTransactions verifies events mode stores every transaction in the new ledgers
+// through getTransactions and never falls back to per-hash getTransaction lookups for them
+func TestPoll_IngestsAllTransactions(t *testing.T) {
+	db := setupPollerTestDB(t)
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+
+	if err := models.UpdateCursor(db, models.LiveCursor, 100); err != nil {
+		t.Fatalf("UpdateCursor() error = %v", err)
+	}
+
+	envelope := createTestTransactionEnvelope("GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H")
+	var txRequests []client.GetTransactionsRequest
+	getTransactionCalls := 0
+
+	server := newTestRPCServer(t, map[string]rpcHandler{
+		"getHealth": func(json.RawMessage) (interface{}, error) {
+			return map[string]interface{}{"status": "healthy", "latestLedger": 102}, nil
+		},
+		"getTransactions": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetTransactionsRequest
+			if err := json.Unmarshal(params, &req); err != nil {
+				return nil, err
+			}
+			txRequests = append(txRequests, req)
+
+			if req.Pagination.Cursor == "" {
+				return client.GetTransactionsResponse{
+					Transactions: []client.TransactionInfo{
+						{TxHash: "hash-success", Status: "SUCCESS", Ledger: 101, ApplicationOrder: 1, EnvelopeXdr: envelope},
+					},
+					Cursor: "page-2",
+				}, nil
+			}
+			return client.GetTransactionsResponse{
+				Transactions: []client.TransactionInfo{
+					{TxHash: "hash-failed", Status: "FAILED", Ledger: 102, ApplicationOrder: 1, EnvelopeXdr: envelope},
+					{TxHash: "hash-future", Status: "SUCCESS", Ledger: 103, ApplicationOrder: 1, EnvelopeXdr: envelope},
+				},
+				Cursor: "page-3",
+			}, nil
+		},
+		"getTransaction": func(json.RawMessage) (interface{}, error) {
+			getTransactionCalls++
+			return nil, errors.New("unexpected getTransaction call")
+		},
+		"getEvents": func(params json.RawMessage) (interface{}, error) {
+			var req client.GetEventsRequest
+			json.Unmarshal(params, &req)
+			if req.Pagination.Cursor != "" {
+				return client.GetEventsResponse{LatestLedger: 102}, nil
+			}
+			return client.GetEventsResponse{
+				Cursor: "0000000433791700992-0000000000",
+				Events: []client.Event{
+					{ID: "0000000433791700992-0000000000", Ledger: 101, PagingToken: "0000000433791700992-0000000000", Topic: []string{}, TxHash: "hash-success"},
+				},
+				LatestLedger: 102,
+			}, nil
+		},
+	})
+
+	p := NewWithConfig(client.NewClient(server.URL), db, logger, PollerConfig{BatchSize: 1})
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+
+	if len(txRequests) != 2 {
+		t.Fatalf("getTransactions calls = %d, want 2", len(txRequests))
+	}
+	if txRequests[0].StartLedger != 101 {
+		t.Errorf("First request startLedger = %d, want 101", txRequests[0].StartLedger)
+	}
+	if txRequests[1].StartLedger != 0 || txRequests[1].Pagination.Cursor != "page-2" {
+		t.Errorf("Second request = %+v, want cursor page-2 without startLedger", txRequests[1])
+	}
+	if getTransactionCalls != 0 {
+		t.Errorf("getTransaction calls = %d, want 0", getTransactionCalls)
+	}
+
+	var hashes []string
+	db.Model(&models.Transaction{}).Order("id").Pluck("id", &hashes)
+	if len(hashes) != 2 || hashes[0] != "hash-failed" || hashes[1] != "hash-success" {
+		t.Errorf("Stored transactions = %v, want [hash-failed hash-success]", hashes)
+	}
+}
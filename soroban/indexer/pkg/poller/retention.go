@@ -0,0 +1,127 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+// ErrBeyondRetention is returned by a poll whose next ledger the RPC no longer retains
+var ErrBeyondRetention = errors.New("cursor is behind the RPC retention window")
+
+// RetentionAlert describes a cursor that fell out of the RPC's retention window
+// Polling can't continue without skipping ledgers until a history source covers the gap
+// or the cursor is moved
+type RetentionAlert struct {
+	Cursor       string    `json:"cursor"`
+	NextLedger   uint32    `json:"nextLedger"`   // First ledger the poller still needs
+	OldestLedger uint32    `json:"oldestLedger"` // Oldest ledger the RPC retains
+	Error        string    `json:"error"`
+	Since        time.Time `json:"since"`
+}
+
+// RetentionAlert returns the current alert, or nil while the cursor is within the RPC window
+func (p *Poller) RetentionAlert() *RetentionAlert {
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+	if p.retentionAlert == nil {
+		return nil
+	}
+	alert := *p.retentionAlert
+	return &alert
+}
+
+// checkRetention compares the cursor with the oldest ledger the RPC retains (0 = unknown)
+// When ledgers after the cursor have left the window they are read from the history source,
+// which returns caughtUp so the caller starts over from the new cursor; without a source
+// the alert is raised and ErrBeyondRetention returned, and the cursor never skips ahead
+func (p *Poller) checkRetention(ctx context.Context, cursor, oldestLedger uint32) (caughtUp bool, err error) {
+	if cursor == 0 || oldestLedger == 0 || cursor+1 >= oldestLedger {
+		p.clearRetentionAlert()
+		return false, nil
+	}
+
+	nextLedger := cursor + 1
+	if p.historySource == nil {
+		err := fmt.Errorf("%w: next ledger %d, oldest retained %d", ErrBeyondRetention, nextLedger, oldestLedger)
+		p.raiseRetentionAlert(nextLedger, oldestLedger, err)
+		return false, err
+	}
+
+	p.logger.WithFields(logrus.Fields{
+		"startLedger":  nextLedger,
+		"endLedger":    oldestLedger - 1,
+		"oldestLedger": oldestLedger,
+	}).Warn("Cursor is behind the RPC retention window, reading ledgers from history source")
+
+	if err := p.ingestFromHistory(ctx, nextLedger, oldestLedger-1); err != nil {
+		err = fmt.Errorf("%w: history source: %w", ErrBeyondRetention, err)
+		p.raiseRetentionAlert(nextLedger, oldestLedger, err)
+		return false, err
+	}
+
+	p.clearRetentionAlert()
+	return true, nil
+}
+
+// ingestFromHistory runs [startLedger, endLedger] from the history source through the pipeline,
+// advancing the cursor after each ledger like pollLedgers
+func (p *Poller) ingestFromHistory(ctx context.Context, startLedger, endLedger uint32) error {
+	start := time.Now()
+	ledgers := 0
+
+	err := p.historySource.Ledgers(ctx, startLedger, endLedger, func(lcm xdr.LedgerCloseMeta) error {
+		ledger := lcm.LedgerSequence()
+		if _, err := p.pipeline.ProcessLedger(ctx, p.db, lcm); err != nil {
+			return fmt.Errorf("ledger %d: %w", ledger, err)
+		}
+		if err := models.UpdateCursor(p.db, p.cursorName, ledger); err != nil {
+			return fmt.Errorf("update cursor: %w", err)
+		}
+		p.recordCoverage(p.cursorName, ledger, ledger)
+		ledgers++
+		return nil
+	})
+
+	p.logger.WithFields(logrus.Fields{
+		"startLedger": startLedger,
+		"endLedger":   endLedger,
+		"ledgers":     ledgers,
+		"duration":    time.Since(start),
+	}).Info("Ingested ledgers from history source")
+
+	return err
+}
+
+func (p *Poller) raiseRetentionAlert(nextLedger, oldestLedger uint32, err error) {
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+
+	since := time.Now()
+	if p.retentionAlert != nil {
+		since = p.retentionAlert.Since
+	}
+	p.retentionAlert = &RetentionAlert{
+		Cursor:       p.cursorName,
+		NextLedger:   nextLedger,
+		OldestLedger: oldestLedger,
+		Error:        err.Error(),
+		Since:        since,
+	}
+}
+
+func (p *Poller) clearRetentionAlert() {
+	p.retentionMu.Lock()
+	defer p.retentionMu.Unlock()
+
+	if p.retentionAlert != nil {
+		p.logger.WithField("cursor", p.cursorName).Info("Cursor is back within the RPC retention window")
+		p.retentionAlert = nil
+	}
+}
@@ -0,0 +1,187 @@
+package poller
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/source"
+)
+
+// historyLedgers is a fake history source holding empty ledgers [first, last]
+type historyLedgers struct {
+	t           *testing.T
+	first, last uint32
+}
+
+func (h *historyLedgers) Ledgers(ctx context.Context, start, end uint32, fn func(lcm xdr.LedgerCloseMeta) error) error {
+	for seq := start; seq <= end; seq++ {
+		if seq < h.first || seq > h.last {
+			return source.ErrLedgerNotFound
+		}
+		var lcm xdr.LedgerCloseMeta
+		if err := xdr.SafeUnmarshalBase64(createEmptyLedgerMeta(h.t, seq), &lcm); err != nil {
+			return err
+		}
+		if err := fn(lcm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func newRetentionTestPoller(t *testing.T, rpc *ledgerRPC, history source.Source) *Poller {
+	return newRetentionTestPollerMode(t, rpc, history, ModeLedgers)
+}
+
+func newRetentionTestPollerMode(t *testing.T, rpc *ledgerRPC, history source.Source, mode IngestMode) *Poller {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	p := NewWithConfig(rpc.server(t), setupPollerTestDB(t), logger, PollerConfig{
+		BatchSize:     10,
+		Mode:          mode,
+		HistorySource: history,
+	})
+	if err := models.UpdateCursor(p.db, models.LiveCursor, 100); err != nil {
+		t.Fatalf("Failed to set cursor: %v", err)
+	}
+	return p
+}
+
+func assertCursor(t *testing.T, p *Poller, want uint32) {
+	t.Helper()
+	got, err := models.GetCursor(p.db, models.LiveCursor)
+	if err != nil {
+		t.Fatalf("GetCursor() error = %v", err)
+	}
+	if got != want {
+		t.Errorf("cursor = %d, want %d", got, want)
+	}
+}
+
+// TestPoll_RetentionAlert verifies a cursor behind the RPC window raises the alert and never skips ahead
+func TestPoll_RetentionAlert(t *testing.T) {
+	rpc := &ledgerRPC{oldest: 110, latest: 115}
+	p := newRetentionTestPoller(t, rpc, nil)
+
+	for i := 0; i < 2; i++ {
+		if err := p.poll(context.Background()); !errors.Is(err, ErrBeyondRetention) {
+			t.Fatalf("poll() error = %v, want ErrBeyondRetention", err)
+		}
+	}
+	assertCursor(t, p, 100)
+	if len(rpc.requests) != 0 {
+		t.Errorf("getLedgers requests = %v, want none", rpc.requests)
+	}
+
+	alert := p.RetentionAlert()
+	if alert == nil {
+		t.Fatal("RetentionAlert() = nil, want alert")
+	}
+	if alert.Cursor != models.LiveCursor || alert.NextLedger != 101 || alert.OldestLedger != 110 {
+		t.Errorf("RetentionAlert() = %+v", alert)
+	}
+
+	stats, err := p.GetStats()
+	if err != nil {
+		t.Fatalf("GetStats() error = %v", err)
+	}
+	if stats["retentionAlert"] == (*RetentionAlert)(nil) {
+		t.Error("stats retentionAlert is nil")
+	}
+
+	// Moving the cursor into the window clears the alert
+	if err := models.ResetCursor(p.db, models.LiveCursor, 111); err != nil {
+		t.Fatalf("ResetCursor() error = %v", err)
+	}
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	assertCursor(t, p, 115)
+	if alert := p.RetentionAlert(); alert != nil {
+		t.Errorf("RetentionAlert() = %+v, want nil", alert)
+	}
+}
+
+// TestPoll_HistorySourceCoversRetentionGap verifies ledgers outside the RPC window are read from the history source
+func TestPoll_HistorySourceCoversRetentionGap(t *testing.T) {
+	rpc := &ledgerRPC{oldest: 110, latest: 115}
+	p := newRetentionTestPoller(t, rpc, &historyLedgers{t: t, first: 1, last: 120})
+
+	// The first poll catches up to the window from history, the next one continues from the RPC
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	assertCursor(t, p, 109)
+	if len(rpc.requests) != 0 {
+		t.Errorf("getLedgers requests = %v, want none", rpc.requests)
+	}
+
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	assertCursor(t, p, 115)
+	if !rpc.requested(110) {
+		t.Errorf("getLedgers requests = %v, want one from 110", rpc.requests)
+	}
+	if p.RetentionAlert() != nil {
+		t.Errorf("RetentionAlert() = %+v, want nil", p.RetentionAlert())
+	}
+
+	gaps, err := models.FindCoverageGaps(p.db, 101, 115)
+	if err != nil {
+		t.Fatalf("FindCoverageGaps() error = %v", err)
+	}
+	if len(gaps) != 0 {
+		t.Errorf("coverage gaps = %v, want none", gaps)
+	}
+}
+
+// TestPoll_HistorySourceCoversRetentionGapEvents verifies events mode resumes inside the RPC window
+// after catching up from the history source
+func TestPoll_HistorySourceCoversRetentionGapEvents(t *testing.T) {
+	rpc := &ledgerRPC{oldest: 110, latest: 115}
+	p := newRetentionTestPollerMode(t, rpc, &historyLedgers{t: t, first: 1, last: 120}, ModeEvents)
+
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	assertCursor(t, p, 109)
+
+	// getEvents starts after the caught up cursor, not at it (109 left the window)
+	if err := p.poll(context.Background()); err != nil {
+		t.Fatalf("poll() error = %v", err)
+	}
+	assertCursor(t, p, 115)
+	if len(rpc.eventRequests) != 1 || rpc.eventRequests[0] != 110 {
+		t.Errorf("getEvents start ledgers = %v, want [110]", rpc.eventRequests)
+	}
+
+	gaps, err := models.FindCoverageGaps(p.db, 101, 115)
+	if err != nil {
+		t.Fatalf("FindCoverageGaps() error = %v", err)
+	}
+	if len(gaps) != 0 {
+		t.Errorf("coverage gaps = %v, want none", gaps)
+	}
+}
+
+// TestPoll_HistorySourceIncomplete verifies the alert stays up when the history source runs out
+func TestPoll_HistorySourceIncomplete(t *testing.T) {
+	rpc := &ledgerRPC{oldest: 110, latest: 115}
+	p := newRetentionTestPoller(t, rpc, &historyLedgers{t: t, first: 101, last: 104})
+
+	err := p.poll(context.Background())
+	if !errors.Is(err, ErrBeyondRetention) || !errors.Is(err, source.ErrLedgerNotFound) {
+		t.Fatalf("poll() error = %v, want ErrBeyondRetention wrapping ErrLedgerNotFound", err)
+	}
+	// The ledgers the source had are kept
+	assertCursor(t, p, 104)
+	if p.RetentionAlert() == nil {
+		t.Error("RetentionAlert() = nil, want alert")
+	}
+}
@@ -0,0 +1,63 @@
+package poller
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+)
+
+// maxTransactionsPerRequest is the largest page getTransactions accepts
+const maxTransactionsPerRequest = 200
+
+// ingestTransactions stores every transaction (successful and failed) in [startLedger, endLedger]
+// using getTransactions, one pipeline batch per page
+// Event batches processed afterwards find these transactions stored and skip per-hash lookups
+func (p *Poller) ingestTransactions(ctx context.Context, startLedger, endLedger uint32) (pipeline.Stats, error) {
+	var total pipeline.Stats
+
+	limit := p.batchSize
+	if limit > maxTransactionsPerRequest {
+		limit = maxTransactionsPerRequest
+	}
+
+	cursor := ""
+	for {
+		select {
+		case <-ctx.Done():
+			return total, ctx.Err()
+		default:
+		}
+
+		resp, err := p.rpcClient.GetTransactions(ctx, startLedger, cursor, limit)
+		if err != nil {
+			return total, fmt.Errorf("get transactions: %w", err)
+		}
+
+		// Only keep transactions inside the requested range
+		page := make([]client.Transaction, 0, len(resp.Transactions))
+		pastEnd := false
+		for _, info := range resp.Transactions {
+			if info.Ledger > endLedger {
+				pastEnd = true
+				break
+			}
+			page = append(page, info.Transaction())
+		}
+
+		if len(page) > 0 {
+			stats, err := p.pipeline.ProcessTransactions(ctx, p.db, page)
+			if err != nil {
+				return total, err
+			}
+			total.Add(stats)
+		}
+
+		// Done once we've gone past the end ledger or the RPC has no more pages
+		if pastEnd || uint(len(resp.Transactions)) < limit || resp.Cursor == "" {
+			return total, nil
+		}
+		cursor = resp.Cursor
+	}
+}
@@ -0,0 +1,44 @@
+package reindex
+
+import (
+	"context"
+	"flag"
+	"fmt"
+	"io"
+	"strings"
+)
+
+// Command implements the `reindex` CLI subcommand
+//
+//	indexer reindex [-table all|token_operations|token_balances|...[,...]] [-batch N] [-restart]
+//
+// It truncates and rebuilds the selected derived tables from stored events and contract data,
+// resuming an interrupted run unless -restart is set, and prints a summary per table
+func Command(ctx context.Context, r *Reindexer, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("reindex", flag.ContinueOnError)
+	flags.SetOutput(out)
+	table := flags.String("table", "all", "Tables to rebuild: all, or a comma separated list of "+strings.Join(Tables, ", "))
+	batchSize := flags.Int("batch", 1000, "Source rows per database transaction")
+	restart := flags.Bool("restart", false, "Truncate and start over instead of resuming an interrupted run")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	config := Config{BatchSize: *batchSize, Restart: *restart}
+	if *table != "all" {
+		for _, name := range strings.Split(*table, ",") {
+			config.Tables = append(config.Tables, strings.TrimSpace(name))
+		}
+	}
+
+	results, err := r.Reindex(ctx, config)
+	for _, result := range results {
+		resumed := ""
+		if result.Resumed {
+			resumed = " (resumed)"
+		}
+		fmt.Fprintf(out, "Reindexed %s%s: %d rows read, %d written in %s\n",
+			result.Table, resumed, result.Read, result.Written, result.Duration)
+	}
+	return err
+}
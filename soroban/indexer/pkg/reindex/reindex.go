@@ -0,0 +1,482 @@
+// Package reindex rebuilds derived token tables from stored rows with the current parser,
+// without RPC access
+package reindex
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+)
+
+// Derived tables reindex can rebuild
+const (
+	TableTokenOperations     = "token_operations"      // From events
+	TableTokenBalances       = "token_balances"        // From contract_data_entries
+	TableTokenMetadata       = "token_metadata"        // From contract_data_entries
+	TableTokenBalanceChanges = "token_balance_changes" // From events, then contract_data_history
+	TableTokenSupply         = "token_supply"          // From events
+	TableTokenAllowances     = "token_allowances"      // From events, then contract_data_history
+)
+
+// Tables lists every derived table, in the order they are rebuilt
+var Tables = []string{
+	TableTokenOperations, TableTokenBalances, TableTokenMetadata,
+	TableTokenBalanceChanges, TableTokenSupply, TableTokenAllowances,
+}
+
+// Stored rows derived tables are rebuilt from
+const (
+	sourceEvents  = "events"
+	sourceEntries = "contract_data_entries"
+	sourceHistory = "contract_data_history"
+)
+
+// sources lists the rows each table is rebuilt from, in order
+var sources = map[string][]string{
+	TableTokenOperations:     {sourceEvents},
+	TableTokenBalances:       {sourceEntries},
+	TableTokenMetadata:       {sourceEntries},
+	TableTokenBalanceChanges: {sourceEvents, sourceHistory},
+	TableTokenSupply:         {sourceEvents},
+	TableTokenAllowances:     {sourceEvents, sourceHistory},
+}
+
+// CursorPrefix prefixes the named cursor holding a table's reindex progress
+const CursorPrefix = "reindex-"
+
+// progressInterval is how often reindex progress is logged
+const progressInterval = 10 * time.Second
+
+// Config selects what to rebuild
+type Config struct {
+	Tables    []string // Default: all
+	BatchSize int      // Source rows per database transaction (default: 1000)
+	Restart   bool     // Start over even if an interrupted run can be resumed
+}
+
+// Result summarizes the rebuild of one table
+type Result struct {
+	Table    string        `json:"table"`
+	Resumed  bool          `json:"resumed"`
+	Read     int64         `json:"read"`    // Source rows read
+	Written  int64         `json:"written"` // Rows written to the table
+	Duration time.Duration `json:"duration"`
+}
+
+// Reindexer rebuilds derived tables
+type Reindexer struct {
+	db     *gorm.DB
+	logger *logrus.Logger
+}
+
+// New creates a reindexer for db
+func New(db *gorm.DB, logger *logrus.Logger) *Reindexer {
+	return &Reindexer{db: db, logger: logger}
+}
+
+// Reindex rebuilds each configured table in turn
+// A table is truncated when its rebuild starts, then every source row is run through the current
+// parser in ledger order. Progress is checkpointed with each batch in the reindex-<table> cursor,
+// so an interrupted rebuild resumes where it stopped; the cursor is removed once the table is done
+// Tables with two sources are rebuilt from the first, then the second; positions in the second
+// are prefixed with its name
+func (r *Reindexer) Reindex(ctx context.Context, config Config) ([]*Result, error) {
+	tables := config.Tables
+	if len(tables) == 0 {
+		tables = Tables
+	}
+	if config.BatchSize <= 0 {
+		config.BatchSize = 1000
+	}
+
+	for _, table := range tables {
+		if !knownTable(table) {
+			return nil, fmt.Errorf("unknown table %q (want one of %v)", table, Tables)
+		}
+	}
+
+	var results []*Result
+	for _, table := range tables {
+		result, err := r.reindexTable(ctx, table, config)
+		if result != nil {
+			results = append(results, result)
+		}
+		if err != nil {
+			return results, fmt.Errorf("reindex %s: %w", table, err)
+		}
+	}
+	return results, nil
+}
+
+func knownTable(table string) bool {
+	for _, known := range Tables {
+		if table == known {
+			return true
+		}
+	}
+	return false
+}
+
+// reindexTable rebuilds a single table, resuming from its cursor when one is stored
+func (r *Reindexer) reindexTable(ctx context.Context, table string, config Config) (*Result, error) {
+	start := time.Now()
+	name := CursorPrefix + table
+
+	cursor, err := models.GetCursorState(r.db, name)
+	if err != nil {
+		return nil, fmt.Errorf("get cursor: %w", err)
+	}
+
+	// A stored cursor means an earlier run was interrupted
+	result := &Result{Table: table, Resumed: !cursor.UpdatedAt.IsZero() && !config.Restart}
+	if !result.Resumed {
+		cursor = &models.Cursor{Name: name}
+		err := r.db.Transaction(func(tx *gorm.DB) error {
+			if err := truncate(tx, table); err != nil {
+				return err
+			}
+			return models.UpdateCursor(tx, name, 0)
+		})
+		if err != nil {
+			return result, fmt.Errorf("truncate: %w", err)
+		}
+	}
+
+	total, err := r.countSource(table)
+	if err != nil {
+		return result, err
+	}
+
+	r.logger.WithFields(logrus.Fields{
+		"table":   table,
+		"rows":    total,
+		"resumed": result.Resumed,
+		"ledger":  cursor.LastLedger,
+	}).Info("Starting reindex")
+
+	lastLog := time.Now()
+	tableSources := sources[table]
+	for i, source := range tableSources {
+		prefix := ""
+		if i > 0 {
+			prefix = source + ":"
+			// Start from the beginning unless a resumed run stopped inside this source
+			if !strings.HasPrefix(cursor.PagingToken, prefix) {
+				cursor = &models.Cursor{Name: name, PagingToken: prefix}
+			}
+		} else if len(tableSources) > 1 && strings.HasPrefix(cursor.PagingToken, tableSources[1]+":") {
+			// A resumed run already finished the first source
+			continue
+		}
+
+		for {
+			if err := ctx.Err(); err != nil {
+				return result, err
+			}
+
+			var read, written int
+			err := r.db.Transaction(func(tx *gorm.DB) error {
+				var err error
+				switch source {
+				case sourceEvents:
+					read, written, err = r.reindexEvents(tx, table, cursor, config.BatchSize)
+				case sourceEntries:
+					read, written, err = r.reindexEntries(tx, table, cursor, config.BatchSize)
+				case sourceHistory:
+					read, written, err = r.reindexHistory(tx, table, cursor, prefix, config.BatchSize)
+				}
+				if err != nil {
+					return err
+				}
+				if read == 0 {
+					return nil
+				}
+				return models.UpdateCursorWithToken(tx, name, cursor.LastLedger, cursor.PagingToken)
+			})
+			if err != nil {
+				return result, err
+			}
+			if read == 0 {
+				break
+			}
+			result.Read += int64(read)
+			result.Written += int64(written)
+
+			if time.Since(lastLog) >= progressInterval {
+				lastLog = time.Now()
+				r.logger.WithFields(logrus.Fields{
+					"table":   table,
+					"source":  source,
+					"read":    result.Read,
+					"total":   total,
+					"written": result.Written,
+					"ledger":  cursor.LastLedger,
+				}).Info("Reindex progress")
+			}
+		}
+	}
+
+	if err := models.ResetCursor(r.db, name, 0); err != nil {
+		return result, fmt.Errorf("clear cursor: %w", err)
+	}
+
+	result.Duration = time.Since(start)
+	r.logger.WithFields(logrus.Fields{
+		"table":    table,
+		"read":     result.Read,
+		"written":  result.Written,
+		"duration": result.Duration,
+	}).Info("Reindex completed")
+
+	return result, nil
+}
+
+// reindexEvents derives a table's rows from the next page of events after the cursor
+// (ledger, then event ID) and advances the cursor to the last event read
+func (r *Reindexer) reindexEvents(tx *gorm.DB, table string, cursor *models.Cursor, batchSize int) (read, written int, err error) {
+	query := tx.Order("ledger ASC, id ASC").Limit(batchSize)
+	if cursor.PagingToken != "" {
+		query = query.Where("ledger > ? OR (ledger = ? AND id > ?)", cursor.LastLedger, cursor.LastLedger, cursor.PagingToken)
+	}
+
+	var events []models.Event
+	if err := query.Find(&events).Error; err != nil {
+		return 0, 0, fmt.Errorf("read events: %w", err)
+	}
+
+	for i := range events {
+		n, err := deriveFromEvent(tx, table, &events[i])
+		if err != nil {
+			return 0, 0, err
+		}
+		written += n
+	}
+
+	if len(events) > 0 {
+		last := events[len(events)-1]
+		cursor.LastLedger = uint32(last.Ledger)
+		cursor.PagingToken = last.ID
+	}
+	return len(events), written, nil
+}
+
+// deriveFromEvent writes the rows of table the token operation of an event produces, like the
+// pipeline's token operations stage, and returns how many it wrote
+func deriveFromEvent(tx *gorm.DB, table string, event *models.Event) (int, error) {
+	tokenOp := pipeline.TokenOperationFromEvent(event)
+	if tokenOp == nil {
+		return 0, nil
+	}
+	if table == TableTokenOperations {
+		if err := models.UpsertTokenOperation(tx, tokenOp); err != nil {
+			return 0, fmt.Errorf("upsert token operation: %w", err)
+		}
+		return 1, nil
+	}
+
+	// Events of failed contract calls were rolled back and moved nothing
+	if !event.InSuccessfulContractCall {
+		return 0, nil
+	}
+	switch table {
+	case TableTokenBalanceChanges:
+		inserted, err := models.InsertTokenBalanceChanges(tx, pipeline.TokenBalanceChangesFromOperation(tokenOp))
+		if err != nil {
+			return 0, fmt.Errorf("insert token balance changes: %w", err)
+		}
+		return int(inserted), nil
+	case TableTokenSupply:
+		delta := pipeline.TokenSupplyDelta(tokenOp)
+		if delta == nil {
+			return 0, nil
+		}
+		if err := models.ApplyTokenSupplyChange(tx, tokenOp.ContractID, uint32(tokenOp.Ledger), delta); err != nil {
+			return 0, fmt.Errorf("apply token supply change: %w", err)
+		}
+		return 1, nil
+	case TableTokenAllowances:
+		allowance := pipeline.TokenAllowanceFromOperation(tokenOp)
+		if allowance == nil {
+			return 0, nil
+		}
+		if err := models.UpsertTokenAllowance(tx, allowance); err != nil {
+			return 0, fmt.Errorf("upsert token allowance: %w", err)
+		}
+		return 1, nil
+	}
+	return 0, nil
+}
+
+// reindexHistory derives a table's rows from the next page of contract data versions after the
+// cursor, in the order they were applied, and advances the cursor to the last version read
+// The cursor holds the ledger, and the transaction index, change index and key hash after prefix
+func (r *Reindexer) reindexHistory(tx *gorm.DB, table string, cursor *models.Cursor, prefix string, batchSize int) (read, written int, err error) {
+	query := tx.Order("ledger ASC, tx_index ASC, change_index ASC, key_hash ASC").Limit(batchSize)
+	if position := strings.TrimPrefix(cursor.PagingToken, prefix); position != "" {
+		var txIndex, changeIndex int32
+		var keyHash string
+		if _, err := fmt.Sscanf(position, "%d-%d-%s", &txIndex, &changeIndex, &keyHash); err != nil {
+			return 0, 0, fmt.Errorf("parse cursor %q: %w", cursor.PagingToken, err)
+		}
+		query = query.Where("ledger > ? OR (ledger = ? AND (tx_index > ? OR (tx_index = ? AND (change_index > ? OR (change_index = ? AND key_hash > ?)))))",
+			cursor.LastLedger, cursor.LastLedger, txIndex, txIndex, changeIndex, changeIndex, keyHash)
+	}
+
+	var rows []models.ContractDataHistory
+	if err := query.Find(&rows).Error; err != nil {
+		return 0, 0, fmt.Errorf("read contract data history: %w", err)
+	}
+
+	closeTimes, err := ledgerCloseTimes(tx, rows)
+	if err != nil {
+		return 0, 0, err
+	}
+
+	for i := range rows {
+		row := &rows[i]
+		change := historyChange(row)
+		switch table {
+		case TableTokenBalanceChanges:
+			// Versions are rebuilt in order, so the previous storage change is the entry's prior state
+			balanceChange := pipeline.StorageBalanceChange(change, row.Ledger, row.TxIndex, row.ChangeIndex, closeTimes[row.Ledger])
+			if balanceChange == nil {
+				continue
+			}
+			if err := models.SetDeltaFromPreviousStorage(tx, balanceChange); err != nil {
+				return 0, 0, fmt.Errorf("get previous storage balance: %w", err)
+			}
+			inserted, err := models.InsertTokenBalanceChanges(tx, []*models.TokenBalanceChange{balanceChange})
+			if err != nil {
+				return 0, 0, fmt.Errorf("insert token balance change: %w", err)
+			}
+			written += int(inserted)
+		case TableTokenAllowances:
+			applied, err := pipeline.StoreStorageAllowance(tx, row.Ledger, change)
+			if err != nil {
+				return 0, 0, err
+			}
+			if applied {
+				written++
+			}
+		}
+	}
+
+	if len(rows) > 0 {
+		last := rows[len(rows)-1]
+		cursor.LastLedger = last.Ledger
+		cursor.PagingToken = fmt.Sprintf("%s%d-%d-%s", prefix, last.TxIndex, last.ChangeIndex, last.KeyHash)
+	}
+	return len(rows), written, nil
+}
+
+// historyChange rebuilds the contract data change a stored version records
+func historyChange(row *models.ContractDataHistory) parser.ContractDataChange {
+	entry := &models.ContractDataEntry{
+		KeyHash:            row.KeyHash,
+		ContractID:         row.ContractID,
+		KeyXdr:             row.KeyXdr,
+		Key:                row.Key,
+		Durability:         row.Durability,
+		ValXdr:             row.ValXdr,
+		Val:                row.Val,
+		LastModifiedLedger: row.Ledger,
+	}
+	if row.ChangeType == models.ContractDataRemoved {
+		ledger := row.Ledger
+		entry.RemovedLedger = &ledger
+	}
+	return parser.ContractDataChange{Type: row.ChangeType, OpIndex: row.OpIndex, Entry: entry}
+}
+
+// ledgerCloseTimes returns the close time of the ledgers of rows, from the ledgers table
+// Ledgers without a stored header are left out
+func ledgerCloseTimes(tx *gorm.DB, rows []models.ContractDataHistory) (map[uint32]time.Time, error) {
+	sequences := make([]uint32, 0, len(rows))
+	for _, row := range rows {
+		sequences = append(sequences, row.Ledger)
+	}
+
+	closeTimes := make(map[uint32]time.Time)
+	if len(sequences) == 0 {
+		return closeTimes, nil
+	}
+	var ledgers []models.Ledger
+	if err := tx.Select("sequence", "closed_at").Where("sequence IN ?", sequences).Find(&ledgers).Error; err != nil {
+		return nil, fmt.Errorf("read ledger close times: %w", err)
+	}
+	for _, ledger := range ledgers {
+		closeTimes[ledger.Sequence] = ledger.ClosedAt
+	}
+	return closeTimes, nil
+}
+
+// reindexEntries derives token balances or metadata from the next page of contract data entries
+// Entries only hold the latest value of each key, so they are read in key hash order
+func (r *Reindexer) reindexEntries(tx *gorm.DB, table string, cursor *models.Cursor, batchSize int) (read, written int, err error) {
+	var entries []models.ContractDataEntry
+	if err := tx.Where("key_hash > ?", cursor.PagingToken).Order("key_hash ASC").Limit(batchSize).Find(&entries).Error; err != nil {
+		return 0, 0, fmt.Errorf("read contract data: %w", err)
+	}
+
+	for i := range entries {
+		metadata, balance := pipeline.TokenStateFromEntry(&entries[i])
+		switch {
+		case table == TableTokenMetadata && metadata != nil:
+			if err := models.UpsertTokenMetadata(tx, metadata); err != nil {
+				return 0, 0, fmt.Errorf("upsert token metadata: %w", err)
+			}
+			written++
+		case table == TableTokenBalances && balance != nil:
+			if err := models.UpsertTokenBalance(tx, balance); err != nil {
+				return 0, 0, fmt.Errorf("upsert token balance: %w", err)
+			}
+			written++
+		}
+	}
+
+	if len(entries) > 0 {
+		cursor.PagingToken = entries[len(entries)-1].KeyHash
+	}
+	return len(entries), written, nil
+}
+
+// countSource counts the rows a table is rebuilt from, for progress reporting
+func (r *Reindexer) countSource(table string) (int64, error) {
+	var total int64
+	for _, source := range sources[table] {
+		var count int64
+		if err := r.db.Table(source).Count(&count).Error; err != nil {
+			return 0, fmt.Errorf("count source rows: %w", err)
+		}
+		total += count
+	}
+	return total, nil
+}
+
+// truncate deletes every row of a derived table
+func truncate(tx *gorm.DB, table string) error {
+	var model interface{}
+	switch table {
+	case TableTokenOperations:
+		model = &models.TokenOperation{}
+	case TableTokenBalances:
+		model = &models.TokenBalance{}
+	case TableTokenMetadata:
+		model = &models.TokenMetadata{}
+	case TableTokenBalanceChanges:
+		model = &models.TokenBalanceChange{}
+	case TableTokenSupply:
+		model = &models.TokenSupply{}
+	case TableTokenAllowances:
+		model = &models.TokenAllowance{}
+	}
+	return tx.Session(&gorm.Session{AllowGlobalUpdate: true}).Delete(model).Error
+}
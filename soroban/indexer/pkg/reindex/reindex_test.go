@@ -0,0 +1,270 @@
+package reindex
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"math/big"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/models"
+)
+
+const (
+	testContract = "CCW67TSZV3SSS2HXMBQ5JFGCKJNXKZM7UQUWUZPUTHXSTZLEO7SJMI75"
+	testFrom     = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+	testTo       = "GBVFTZL5HIPT4PFQVTZVIWR77V7LWYCXU4CLYWWHHOEXB64XPG5LDMTU"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Event{},
+		&models.TokenMetadata{},
+		&models.TokenOperation{},
+		&models.TokenBalance{},
+		&models.TokenBalanceChange{},
+		&models.TokenSupply{},
+		&models.TokenAllowance{},
+		&models.ContractDataEntry{},
+		&models.ContractDataHistory{},
+		&models.Ledger{},
+		&models.Cursor{},
+	))
+	return db
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+// storeRows stores a transfer event per ledger plus one non-token event, a balance entry per
+// ledger and the token's instance entry
+func storeRows(t *testing.T, db *gorm.DB, ledgers int) {
+	for i := 1; i <= ledgers; i++ {
+		require.NoError(t, models.UpsertEvent(db, &models.Event{
+			ID:             fmt.Sprintf("%019d-0000000001", i),
+			EventType:      "contract",
+			Ledger:         int32(i),
+			LedgerClosedAt: "2024-01-01T00:00:00Z",
+			ContractID:     testContract,
+			Topic:          fmt.Sprintf(`["transfer","%s","%s"]`, testFrom, testTo),
+			Value:          fmt.Sprintf(`"%d"`, i*100),
+		}))
+		_, err := models.UpsertContractDataEntry(db, &models.ContractDataEntry{
+			KeyHash:    fmt.Sprintf("balance-%03d", i),
+			ContractID: testContract,
+			Key:        models.JSONB(fmt.Sprintf(`["Balance","G%03d"]`, i)),
+			Val:        models.JSONB(fmt.Sprintf(`"%d"`, i*10)),
+		})
+		require.NoError(t, err)
+	}
+	require.NoError(t, models.UpsertEvent(db, &models.Event{
+		ID:         "0000000000000000001-0000000002",
+		EventType:  "contract",
+		Ledger:     1,
+		ContractID: testContract,
+		Topic:      `["increment"]`,
+		Value:      `"1"`,
+	}))
+	_, err := models.UpsertContractDataEntry(db, &models.ContractDataEntry{
+		KeyHash:    "instance",
+		ContractID: testContract,
+		Key:        models.JSONB(`{"type":"LedgerKeyContractInstance"}`),
+		Val:        models.JSONB(`{"storage":[{"key":"METADATA","value":[{"key":"name","value":"Test"},{"key":"symbol","value":"TST"},{"key":"decimal","value":7}]}]}`),
+	})
+	require.NoError(t, err)
+}
+
+func count(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	var n int64
+	require.NoError(t, db.Model(model).Count(&n).Error)
+	return n
+}
+
+func TestReindex_RebuildsTables(t *testing.T) {
+	db := setupTestDB(t)
+	storeRows(t, db, 5)
+
+	// Stale rows the current parser would not produce are dropped
+	stale := &models.TokenBalance{ContractID: testContract, Address: "stale"}
+	stale.Balance.SetInt64(1)
+	require.NoError(t, models.UpsertTokenBalance(db, stale))
+
+	results, err := New(db, testLogger()).Reindex(context.Background(), Config{BatchSize: 2})
+	require.NoError(t, err)
+	require.Len(t, results, len(Tables))
+
+	assert.Equal(t, TableTokenOperations, results[0].Table)
+	assert.Equal(t, int64(6), results[0].Read)
+	assert.Equal(t, int64(5), results[0].Written)
+	assert.Equal(t, int64(5), results[1].Written)
+	assert.Equal(t, int64(1), results[2].Written)
+
+	assert.Equal(t, int64(5), count(t, db, &models.TokenOperation{}))
+	assert.Equal(t, int64(5), count(t, db, &models.TokenBalance{}))
+
+	var metadata models.TokenMetadata
+	require.NoError(t, db.First(&metadata, "contract_id = ?", testContract).Error)
+	assert.Equal(t, "TST", metadata.Symbol)
+	assert.Equal(t, uint32(7), metadata.Decimal)
+
+	// Cursors are removed once every table is rebuilt
+	assert.Equal(t, int64(0), count(t, db, &models.Cursor{}))
+}
+
+func TestReindex_ResumesInterruptedRun(t *testing.T) {
+	db := setupTestDB(t)
+	storeRows(t, db, 5)
+
+	// An interrupted run rebuilt the operations of ledgers 1-3
+	r := New(db, testLogger())
+	_, err := r.Reindex(context.Background(), Config{Tables: []string{TableTokenOperations}})
+	require.NoError(t, err)
+	require.NoError(t, db.Where("ledger > ?", 3).Delete(&models.TokenOperation{}).Error)
+	require.NoError(t, models.UpdateCursorWithToken(db, CursorPrefix+TableTokenOperations, 3, fmt.Sprintf("%019d-0000000001", 3)))
+
+	results, err := r.Reindex(context.Background(), Config{Tables: []string{TableTokenOperations}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Resumed)
+	assert.Equal(t, int64(2), results[0].Read)
+	assert.Equal(t, int64(5), count(t, db, &models.TokenOperation{}))
+
+	// -restart truncates and reads everything again
+	require.NoError(t, models.UpdateCursorWithToken(db, CursorPrefix+TableTokenOperations, 3, fmt.Sprintf("%019d-0000000001", 3)))
+	results, err = r.Reindex(context.Background(), Config{Tables: []string{TableTokenOperations}, Restart: true})
+	require.NoError(t, err)
+	assert.False(t, results[0].Resumed)
+	assert.Equal(t, int64(6), results[0].Read)
+	assert.Equal(t, int64(5), count(t, db, &models.TokenOperation{}))
+}
+
+// storeTokenHistory stores a mint and an approve event at ledger 10, then the storage history of
+// the minted balance (written at 10, 20, removed at 30) and of the allowance (written at 10)
+func storeTokenHistory(t *testing.T, db *gorm.DB) {
+	event := func(id, topic, value string, successful bool) *models.Event {
+		return &models.Event{
+			ID:                       id,
+			EventType:                "contract",
+			Ledger:                   10,
+			LedgerClosedAt:           "2024-01-01T00:00:00Z",
+			ContractID:               testContract,
+			Topic:                    topic,
+			Value:                    value,
+			InSuccessfulContractCall: successful,
+		}
+	}
+	require.NoError(t, models.UpsertEvent(db, event(fmt.Sprintf("%019d-0000000001", 10), fmt.Sprintf(`["mint","%s","%s"]`, testFrom, testTo), `"100"`, true)))
+	require.NoError(t, models.UpsertEvent(db, event(fmt.Sprintf("%019d-0000000002", 10), fmt.Sprintf(`["approve","%s","%s"]`, testTo, testFrom), `["40",500]`, true)))
+	// Rolled back: moves nothing
+	require.NoError(t, models.UpsertEvent(db, event(fmt.Sprintf("%019d-0000000003", 10), fmt.Sprintf(`["mint","%s","%s"]`, testFrom, testTo), `"5"`, false)))
+
+	balanceKey := models.JSONB(fmt.Sprintf(`["Balance","%s"]`, testTo))
+	allowanceKey := models.JSONB(fmt.Sprintf(`["Allowance",[{"key":"from","value":"%s"},{"key":"spender","value":"%s"}]]`, testTo, testFrom))
+	require.NoError(t, models.InsertContractDataHistory(db, []*models.ContractDataHistory{
+		{Ledger: 10, TxIndex: 1, ChangeIndex: 0, ChangeType: models.ContractDataCreated, KeyHash: "balance", ContractID: testContract, Key: balanceKey, Val: models.JSONB(`"100"`)},
+		{Ledger: 10, TxIndex: 1, ChangeIndex: 1, ChangeType: models.ContractDataCreated, KeyHash: "allowance", ContractID: testContract, Key: allowanceKey,
+			Val: models.JSONB(`[{"key":"amount","value":"40"},{"key":"expiration_ledger","value":500}]`)},
+		{Ledger: 20, TxIndex: 1, ChangeIndex: 0, ChangeType: models.ContractDataUpdated, KeyHash: "balance", ContractID: testContract, Key: balanceKey, Val: models.JSONB(`"70"`)},
+		{Ledger: 30, TxIndex: 1, ChangeIndex: 0, ChangeType: models.ContractDataRemoved, KeyHash: "balance", ContractID: testContract, Key: balanceKey},
+	}))
+}
+
+func TestReindex_RebuildsBalanceChangesSupplyAndAllowances(t *testing.T) {
+	db := setupTestDB(t)
+	storeTokenHistory(t, db)
+
+	// Rows a replay left behind are replaced, not kept
+	stale := &models.TokenBalanceChange{ID: "stale", ContractID: testContract, Address: testTo, Ledger: 10, Source: models.BalanceSourceEvent}
+	stale.Delta.SetInt64(1)
+	_, err := models.InsertTokenBalanceChanges(db, []*models.TokenBalanceChange{stale})
+	require.NoError(t, err)
+	require.NoError(t, models.ApplyTokenSupplyChange(db, testContract, 10, big.NewInt(1000)))
+
+	tables := []string{TableTokenBalanceChanges, TableTokenSupply, TableTokenAllowances}
+	results, err := New(db, testLogger()).Reindex(context.Background(), Config{Tables: tables, BatchSize: 2})
+	require.NoError(t, err)
+	require.Len(t, results, 3)
+
+	// One event row for the mint, a storage row per write of the balance
+	assert.Equal(t, int64(7), results[0].Read)
+	assert.Equal(t, int64(4), results[0].Written)
+	var changes []models.TokenBalanceChange
+	require.NoError(t, db.Order("source ASC, ledger ASC").Find(&changes).Error)
+	require.Len(t, changes, 4)
+	assert.Equal(t, models.BalanceSourceEvent, changes[0].Source)
+	assert.Equal(t, "100", changes[0].Delta.String())
+	for i, want := range [][2]string{{"100", "100"}, {"-30", "70"}, {"-70", "0"}} {
+		assert.Equal(t, want[0], changes[i+1].Delta.String(), "storage change %d delta", i)
+		assert.Equal(t, want[1], changes[i+1].Balance.String(), "storage change %d balance", i)
+	}
+
+	supply, err := models.GetTokenSupply(db, testContract)
+	require.NoError(t, err)
+	require.NotNil(t, supply)
+	assert.Equal(t, "100", supply.Supply.String())
+
+	allowances, err := models.GetActiveAllowances(db, testTo, 10)
+	require.NoError(t, err)
+	require.Len(t, allowances, 1)
+	assert.Equal(t, testFrom, allowances[0].Spender)
+	assert.Equal(t, "40", allowances[0].Amount.String())
+	assert.Equal(t, uint32(500), allowances[0].ExpirationLedger)
+
+	assert.Equal(t, int64(0), count(t, db, &models.Cursor{}))
+}
+
+func TestReindex_ResumesInSecondSource(t *testing.T) {
+	db := setupTestDB(t)
+	storeTokenHistory(t, db)
+
+	// An interrupted run finished the events and the history up to ledger 10
+	r := New(db, testLogger())
+	_, err := r.Reindex(context.Background(), Config{Tables: []string{TableTokenBalanceChanges}})
+	require.NoError(t, err)
+	require.NoError(t, db.Where("ledger > ?", 10).Delete(&models.TokenBalanceChange{}).Error)
+	require.NoError(t, models.UpdateCursorWithToken(db, CursorPrefix+TableTokenBalanceChanges, 10, "contract_data_history:1-1-allowance"))
+
+	results, err := r.Reindex(context.Background(), Config{Tables: []string{TableTokenBalanceChanges}})
+	require.NoError(t, err)
+	require.Len(t, results, 1)
+	assert.True(t, results[0].Resumed)
+	assert.Equal(t, int64(2), results[0].Read)
+	assert.Equal(t, int64(4), count(t, db, &models.TokenBalanceChange{}))
+
+	balance, err := models.GetTokenBalanceAt(db, testContract, testTo, 25)
+	require.NoError(t, err)
+	assert.Equal(t, "70", balance)
+}
+
+func TestReindex_UnknownTable(t *testing.T) {
+	db := setupTestDB(t)
+	storeRows(t, db, 1)
+
+	_, err := New(db, testLogger()).Reindex(context.Background(), Config{Tables: []string{TableTokenBalances, "events"}})
+	assert.ErrorContains(t, err, `unknown table "events"`)
+
+	// Nothing was truncated
+	assert.Equal(t, int64(0), count(t, db, &models.Cursor{}))
+}
+
+func TestCommand(t *testing.T) {
+	db := setupTestDB(t)
+	storeRows(t, db, 3)
+
+	var out bytes.Buffer
+	require.NoError(t, Command(context.Background(), New(db, testLogger()), []string{"-table", "token_balances, token_metadata"}, &out))
+	assert.Contains(t, out.String(), "Reindexed token_balances: 4 rows read, 3 written")
+	assert.Contains(t, out.String(), "Reindexed token_metadata: 4 rows read, 1 written")
+	assert.NotContains(t, out.String(), TableTokenOperations)
+}
@@ -0,0 +1,193 @@
+// Package replay rebuilds derived tables from the raw payload archive without calling the RPC
+package replay
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/xdr"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+)
+
+// progressInterval is how often replay progress is logged
+const progressInterval = 10 * time.Second
+
+// ErrNotArchived is returned for transactions replay would have to fetch from the RPC
+var ErrNotArchived = errors.New("transaction not in archive")
+
+// Config selects the archived ledgers to replay (0 = first / last archived ledger)
+type Config struct {
+	StartLedger uint32
+	EndLedger   uint32
+}
+
+// Result summarizes a replay run
+type Result struct {
+	StartLedger uint32         `json:"startLedger"`
+	EndLedger   uint32         `json:"endLedger"`
+	Ledgers     int            `json:"ledgers"`
+	Stats       pipeline.Stats `json:"stats"`
+	Duration    time.Duration  `json:"duration"`
+}
+
+// Replayer runs archived payloads through the regular pipeline stages
+type Replayer struct {
+	db     *gorm.DB
+	store  *archive.Store
+	logger *logrus.Logger
+
+	pipelines map[string]*pipeline.Pipeline // By network passphrase
+}
+
+// New creates a replayer reading from store and writing to db
+func New(db *gorm.DB, store *archive.Store, logger *logrus.Logger) *Replayer {
+	return &Replayer{
+		db:        db,
+		store:     store,
+		logger:    logger,
+		pipelines: make(map[string]*pipeline.Pipeline),
+	}
+}
+
+// offlineFetcher stands in for the RPC; everything replay needs is in the archive
+type offlineFetcher struct{}
+
+func (offlineFetcher) GetTransaction(ctx context.Context, hash string) (*client.Transaction, error) {
+	return nil, fmt.Errorf("%s: %w", hash, ErrNotArchived)
+}
+
+// Replay rebuilds every archived ledger in the configured range, in ledger order
+// Rows are upserted, so replaying over existing data overwrites it with freshly parsed values
+func (r *Replayer) Replay(ctx context.Context, config Config) (*Result, error) {
+	start := time.Now()
+
+	ledgers, err := r.store.Ledgers(config.StartLedger, config.EndLedger)
+	if err != nil {
+		return nil, fmt.Errorf("list archived ledgers: %w", err)
+	}
+	if len(ledgers) == 0 {
+		return nil, fmt.Errorf("no archived ledgers in range %d-%d", config.StartLedger, config.EndLedger)
+	}
+
+	result := &Result{StartLedger: ledgers[0], EndLedger: ledgers[len(ledgers)-1]}
+	lastLog := start
+
+	for _, ledger := range ledgers {
+		if err := ctx.Err(); err != nil {
+			return result, err
+		}
+
+		stats, err := r.replayLedger(ctx, ledger)
+		if err != nil {
+			return result, fmt.Errorf("replay ledger %d: %w", ledger, err)
+		}
+		result.Ledgers++
+		result.Stats.Add(stats)
+
+		if time.Since(lastLog) >= progressInterval {
+			lastLog = time.Now()
+			r.logger.WithFields(logrus.Fields{
+				"ledger":   ledger,
+				"replayed": result.Ledgers,
+				"total":    len(ledgers),
+			}).Info("Replay progress")
+		}
+	}
+
+	result.Duration = time.Since(start)
+	r.logger.WithFields(logrus.Fields{
+		"startLedger":  result.StartLedger,
+		"endLedger":    result.EndLedger,
+		"ledgers":      result.Ledgers,
+		"events":       result.Stats.Events,
+		"transactions": result.Stats.Transactions,
+		"duration":     result.Duration,
+	}).Info("Replay completed")
+
+	return result, nil
+}
+
+// replayLedger rebuilds one ledger from its records
+// LedgerCloseMeta holds everything, so it wins over transaction and event records
+func (r *Replayer) replayLedger(ctx context.Context, ledger uint32) (pipeline.Stats, error) {
+	records, err := r.store.Get(ledger)
+	if err != nil {
+		return pipeline.Stats{}, err
+	}
+
+	for _, record := range records {
+		if record.LedgerCloseMeta != "" {
+			var lcm xdr.LedgerCloseMeta
+			if err := xdr.SafeUnmarshalBase64(record.LedgerCloseMeta, &lcm); err != nil {
+				return pipeline.Stats{}, fmt.Errorf("decode ledger meta: %w", err)
+			}
+			return r.pipeline(record.NetworkPassphrase).ProcessLedger(ctx, r.db, lcm)
+		}
+	}
+
+	var passphrase string
+	var transactions []client.Transaction
+	var events []client.Event
+	seenTxs := make(map[string]bool)
+	seenEvents := make(map[string]bool)
+
+	for _, record := range records {
+		if record.NetworkPassphrase != "" {
+			passphrase = record.NetworkPassphrase
+		}
+		for _, tx := range record.Transactions {
+			if tx.Hash == "" || !seenTxs[tx.Hash] {
+				seenTxs[tx.Hash] = true
+				transactions = append(transactions, tx)
+			}
+		}
+		for _, event := range record.Events {
+			if !seenEvents[event.ID] {
+				seenEvents[event.ID] = true
+				events = append(events, event)
+			}
+		}
+	}
+
+	// Same order as live ingestion: transactions first, then events in paging order
+	sort.SliceStable(transactions, func(i, j int) bool {
+		return transactions[i].ApplicationOrder < transactions[j].ApplicationOrder
+	})
+	sort.SliceStable(events, func(i, j int) bool { return events[i].ID < events[j].ID })
+
+	p := r.pipeline(passphrase)
+	var total pipeline.Stats
+	if len(transactions) > 0 {
+		stats, err := p.ProcessTransactions(ctx, r.db, transactions)
+		if err != nil {
+			return total, err
+		}
+		total.Add(stats)
+	}
+	if len(events) > 0 {
+		stats, err := p.Process(ctx, r.db, events)
+		if err != nil {
+			return total, err
+		}
+		total.Add(stats)
+	}
+	return total, nil
+}
+
+// pipeline returns the (non-archiving) pipeline for a network
+func (r *Replayer) pipeline(passphrase string) *pipeline.Pipeline {
+	p, ok := r.pipelines[passphrase]
+	if !ok {
+		p = pipeline.New(offlineFetcher{}, r.logger, passphrase)
+		r.pipelines[passphrase] = p
+	}
+	return p
+}
@@ -0,0 +1,164 @@
+package replay
+
+import (
+	"bytes"
+	"context"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stellar/go/network"
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/archive"
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+	"github.com/blockroma/soroban-indexer/pkg/pipeline"
+)
+
+const testSourceAccount = "GBRPYHIL2CI3FNQ4BXLFMNDLFJUNPU2HY3ZMFSHONUCEOASW7QC7OX2H"
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.AutoMigrate(
+		&models.Event{},
+		&models.Transaction{},
+		&models.Operation{},
+		&models.TokenMetadata{},
+		&models.TokenOperation{},
+		&models.TokenBalance{},
+		&models.TokenBalanceChange{},
+		&models.TokenSupply{},
+		&models.TokenAllowance{},
+		&models.DiagnosticEvent{},
+		&models.ContractDataEntry{},
+		&models.ContractDataHistory{},
+		&models.ContractCode{},
+		&models.Ledger{},
+	))
+	return db
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+// createEnvelope creates a V1 envelope with a single BumpSequence operation
+func createEnvelope(t *testing.T) string {
+	source := xdr.MustAddress(testSourceAccount)
+	encoded, err := xdr.MarshalBase64(xdr.TransactionEnvelope{
+		Type: xdr.EnvelopeTypeEnvelopeTypeTx,
+		V1: &xdr.TransactionV1Envelope{
+			Tx: xdr.Transaction{
+				SourceAccount: source.ToMuxedAccount(),
+				Fee:           100,
+				SeqNum:        1,
+				Cond:          xdr.Preconditions{Type: xdr.PreconditionTypePrecondNone},
+				Operations: []xdr.Operation{
+					{Body: xdr.OperationBody{Type: xdr.OperationTypeBumpSequence, BumpSequenceOp: &xdr.BumpSequenceOp{BumpTo: 2}}},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	return encoded
+}
+
+func createEvent(t *testing.T, id, txHash string, ledger uint32) client.Event {
+	sym := xdr.ScSymbol("hello")
+	topic, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvSymbol, Sym: &sym})
+	require.NoError(t, err)
+	value, err := xdr.MarshalBase64(xdr.ScVal{Type: xdr.ScValTypeScvVoid})
+	require.NoError(t, err)
+	return client.Event{
+		ID:          id,
+		Type:        "contract",
+		Ledger:      ledger,
+		PagingToken: id,
+		Topic:       []string{topic},
+		Value:       value,
+		TxHash:      txHash,
+	}
+}
+
+// ingest stores ledger 100 from LedgerCloseMeta and ledger 101 as events-mode payloads, archiving both
+func ingest(t *testing.T, db *gorm.DB, store *archive.Store) {
+	p := pipeline.New(offlineFetcher{}, testLogger(), network.TestNetworkPassphrase)
+	p.SetArchive(store)
+	ctx := context.Background()
+
+	envelope := createEnvelope(t)
+	txHash, err := parser.ComputeTransactionHash(envelope, network.TestNetworkPassphrase)
+	require.NoError(t, err)
+
+	_, err = p.ProcessLedger(ctx, db, xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{Header: xdr.LedgerHeader{LedgerSeq: 100}},
+			TxSet:        xdr.GeneralizedTransactionSet{V: 1, V1TxSet: &xdr.TransactionSetV1{}},
+		},
+	})
+	require.NoError(t, err)
+
+	_, err = p.ProcessTransactions(ctx, db, []client.Transaction{
+		{Hash: txHash, Status: "SUCCESS", Ledger: 101, ApplicationOrder: 1, LedgerCloseTime: 1704067200, EnvelopeXdr: envelope},
+	})
+	require.NoError(t, err)
+	_, err = p.Process(ctx, db, []client.Event{
+		createEvent(t, "0000000433791696897-0000000001", txHash, 101),
+		createEvent(t, "0000000433791696897-0000000002", txHash, 101),
+	})
+	require.NoError(t, err)
+}
+
+func countRows(t *testing.T, db *gorm.DB, model interface{}) int64 {
+	var count int64
+	require.NoError(t, db.Model(model).Count(&count).Error)
+	return count
+}
+
+func TestReplay_RebuildsFromArchive(t *testing.T) {
+	store, err := archive.Open(t.TempDir())
+	require.NoError(t, err)
+	original := setupTestDB(t)
+	ingest(t, original, store)
+
+	// Replay into an empty database: no RPC is available, everything comes from the archive
+	rebuilt := setupTestDB(t)
+	result, err := New(rebuilt, store, testLogger()).Replay(context.Background(), Config{})
+	require.NoError(t, err)
+	assert.Equal(t, 2, result.Ledgers)
+	assert.Equal(t, uint32(100), result.StartLedger)
+	assert.Equal(t, uint32(101), result.EndLedger)
+
+	for _, model := range []interface{}{&models.Ledger{}, &models.Transaction{}, &models.Operation{}, &models.Event{}} {
+		assert.Equal(t, countRows(t, original, model), countRows(t, rebuilt, model), "%T", model)
+	}
+	assert.Equal(t, int64(2), countRows(t, rebuilt, &models.Event{}))
+	assert.Equal(t, int64(1), countRows(t, rebuilt, &models.Transaction{}))
+
+	// Replaying again only overwrites
+	_, err = New(rebuilt, store, testLogger()).Replay(context.Background(), Config{StartLedger: 101})
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), countRows(t, rebuilt, &models.Event{}))
+
+	_, err = New(rebuilt, store, testLogger()).Replay(context.Background(), Config{StartLedger: 200})
+	assert.Error(t, err)
+}
+
+func TestCommand(t *testing.T) {
+	store, err := archive.Open(t.TempDir())
+	require.NoError(t, err)
+	ingest(t, setupTestDB(t), store)
+
+	var out bytes.Buffer
+	require.NoError(t, Command(context.Background(), New(setupTestDB(t), store, testLogger()), []string{"-end-ledger", "100"}, &out))
+	assert.Contains(t, out.String(), "Replayed 1 ledgers (100-100)")
+}
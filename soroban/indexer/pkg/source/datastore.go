@@ -0,0 +1,194 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/fs"
+	"math"
+	"os"
+	"path/filepath"
+
+	"github.com/stellar/go/support/compressxdr"
+	"github.com/stellar/go/xdr"
+)
+
+// datastoreManifest is the .config.json Galexie writes at the root of a datastore
+const datastoreManifest = ".config.json"
+
+// Galexie defaults, used when a datastore has no manifest
+const (
+	defaultLedgersPerFile    = 1
+	defaultFilesPerPartition = 64000
+)
+
+// DatastoreSchema describes how ledgers are grouped into files and partitions
+type DatastoreSchema struct {
+	LedgersPerFile    uint32 `json:"ledgersPerBatch"`
+	FilesPerPartition uint32 `json:"batchesPerPartition"`
+}
+
+// Datastore reads a local copy of a Stellar datastore (Galexie export): zstd compressed
+// LedgerCloseMetaBatch XDR files laid out as
+//
+//	<root>/FCD285FF--53312000-53375999/FCD285FF--53312000.xdr.zst
+//
+// where the hex prefix is MaxUint32 minus the first ledger, so newer ledgers sort first
+type Datastore struct {
+	root              string
+	schema            DatastoreSchema
+	networkPassphrase string
+}
+
+// NewDatastore opens the datastore at root
+// The schema is read from the datastore manifest when there is one; schema only fills in what
+// the manifest doesn't provide (zero fields = Galexie defaults)
+func NewDatastore(root string, schema DatastoreSchema) (*Datastore, error) {
+	info, err := os.Stat(root)
+	if err != nil {
+		return nil, fmt.Errorf("open datastore: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("datastore %s is not a directory", root)
+	}
+
+	d := &Datastore{root: root, schema: schema}
+
+	data, err := os.ReadFile(filepath.Join(root, datastoreManifest))
+	switch {
+	case errors.Is(err, fs.ErrNotExist):
+	case err != nil:
+		return nil, fmt.Errorf("read datastore manifest: %w", err)
+	default:
+		var manifest struct {
+			DatastoreSchema
+			NetworkPassphrase string `json:"networkPassphrase"`
+			Compression       string `json:"compression"`
+		}
+		if err := json.Unmarshal(data, &manifest); err != nil {
+			return nil, fmt.Errorf("decode datastore manifest: %w", err)
+		}
+		if manifest.Compression != "" && manifest.Compression != "zstd" {
+			return nil, fmt.Errorf("unsupported datastore compression %q", manifest.Compression)
+		}
+		if manifest.LedgersPerFile != 0 {
+			d.schema.LedgersPerFile = manifest.LedgersPerFile
+		}
+		if manifest.FilesPerPartition != 0 {
+			d.schema.FilesPerPartition = manifest.FilesPerPartition
+		}
+		d.networkPassphrase = manifest.NetworkPassphrase
+	}
+
+	if d.schema.LedgersPerFile == 0 {
+		d.schema.LedgersPerFile = defaultLedgersPerFile
+	}
+	if d.schema.FilesPerPartition == 0 {
+		d.schema.FilesPerPartition = defaultFilesPerPartition
+	}
+
+	return d, nil
+}
+
+// NetworkPassphrase returns the passphrase recorded in the manifest (empty when unknown)
+func (d *Datastore) NetworkPassphrase() string {
+	return d.networkPassphrase
+}
+
+// Ledgers reads [start, end] file by file; a missing file is ErrLedgerNotFound
+func (d *Datastore) Ledgers(ctx context.Context, start, end uint32, fn func(lcm xdr.LedgerCloseMeta) error) error {
+	for seq := start; seq <= end; {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		batch, err := d.readBatch(seq)
+		if err != nil {
+			return err
+		}
+
+		// Last ledger wanted from this file
+		last := d.fileStart(seq) + d.schema.LedgersPerFile - 1
+		if last > end {
+			last = end
+		}
+
+		for _, lcm := range batch.LedgerCloseMetas {
+			ledger := lcm.LedgerSequence()
+			if ledger < seq {
+				continue
+			}
+			if ledger > last || ledger != seq {
+				break
+			}
+			if err := fn(lcm); err != nil {
+				return err
+			}
+			seq++
+		}
+
+		// A file without every ledger of its range is incomplete
+		if seq <= last {
+			return fmt.Errorf("ledger %d: %w", seq, ErrLedgerNotFound)
+		}
+	}
+	return nil
+}
+
+// readBatch decodes the file holding seq
+func (d *Datastore) readBatch(seq uint32) (*xdr.LedgerCloseMetaBatch, error) {
+	path, err := d.findFile(seq)
+	if err != nil {
+		return nil, err
+	}
+
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, fmt.Errorf("open ledger file: %w", err)
+	}
+	defer f.Close()
+
+	var batch xdr.LedgerCloseMetaBatch
+	if _, err := compressxdr.NewXDRDecoder(compressxdr.DefaultCompressor, &batch).ReadFrom(f); err != nil {
+		return nil, fmt.Errorf("decode ledger file %s: %w", path, err)
+	}
+	return &batch, nil
+}
+
+// findFile returns the path of the file holding seq
+// Galexie names zstd files .xdr.zst; older exports used .xdr.zstd
+func (d *Datastore) findFile(seq uint32) (string, error) {
+	base := filepath.Join(d.root, d.objectKey(seq))
+	for _, ext := range []string{".xdr.zst", ".xdr.zstd"} {
+		if _, err := os.Stat(base + ext); err == nil {
+			return base + ext, nil
+		}
+	}
+	return "", fmt.Errorf("ledger %d: %w", seq, ErrLedgerNotFound)
+}
+
+func (d *Datastore) fileStart(seq uint32) uint32 {
+	return seq / d.schema.LedgersPerFile * d.schema.LedgersPerFile
+}
+
+// objectKey mirrors the datastore schema's object key, without the extension
+func (d *Datastore) objectKey(seq uint32) string {
+	var key string
+	if d.schema.FilesPerPartition > 1 {
+		partitionSize := d.schema.LedgersPerFile * d.schema.FilesPerPartition
+		partitionStart := seq / partitionSize * partitionSize
+		partitionEnd := partitionStart + partitionSize - 1
+		key = fmt.Sprintf("%08X--%d-%d/", math.MaxUint32-partitionStart, partitionStart, partitionEnd)
+	}
+
+	fileStart := d.fileStart(seq)
+	fileEnd := fileStart + d.schema.LedgersPerFile - 1
+	key += fmt.Sprintf("%08X--%d", math.MaxUint32-fileStart, fileStart)
+	if fileStart != fileEnd {
+		key += fmt.Sprintf("-%d", fileEnd)
+	}
+	return key
+}
@@ -0,0 +1,100 @@
+package source
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go/support/compressxdr"
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blockroma/soroban-indexer/pkg/parser"
+)
+
+// writeBatch exports ledgers [start, end] as one compressed batch file of d
+func writeBatch(t *testing.T, d *Datastore, start, end uint32, skip ...uint32) {
+	batch := xdr.LedgerCloseMetaBatch{StartSequence: xdr.Uint32(start), EndSequence: xdr.Uint32(end)}
+	for seq := start; seq <= end; seq++ {
+		if len(skip) > 0 && skip[0] == seq {
+			continue
+		}
+		batch.LedgerCloseMetas = append(batch.LedgerCloseMetas, ledgerMeta(seq))
+	}
+
+	path := filepath.Join(d.root, d.objectKey(start)+".xdr.zst")
+	require.NoError(t, os.MkdirAll(filepath.Dir(path), 0o755))
+	f, err := os.Create(path)
+	require.NoError(t, err)
+	defer f.Close()
+	_, err = compressxdr.NewXDREncoder(compressxdr.DefaultCompressor, &batch).WriteTo(f)
+	require.NoError(t, err)
+}
+
+func collectLedgers(d *Datastore, start, end uint32) ([]uint32, error) {
+	var seen []uint32
+	err := d.Ledgers(context.Background(), start, end, func(lcm xdr.LedgerCloseMeta) error {
+		seen = append(seen, lcm.LedgerSequence())
+		return nil
+	})
+	return seen, err
+}
+
+// TestDatastore_Fixture reads a pubnet ledger exported by Galexie and parses it with the regular parser
+func TestDatastore_Fixture(t *testing.T) {
+	d, err := NewDatastore("testdata/datastore", DatastoreSchema{})
+	require.NoError(t, err)
+	assert.Equal(t, "Public Global Stellar Network ; September 2015", d.NetworkPassphrase())
+
+	var ledgers []xdr.LedgerCloseMeta
+	err = d.Ledgers(context.Background(), 53312000, 53312000, func(lcm xdr.LedgerCloseMeta) error {
+		ledgers = append(ledgers, lcm)
+		return nil
+	})
+	require.NoError(t, err)
+	require.Len(t, ledgers, 1)
+
+	ledger, err := parser.ParseLedger(ledgers[0])
+	require.NoError(t, err)
+	assert.Equal(t, uint32(53312000), ledger.Sequence)
+	assert.Equal(t, 163, ledger.TransactionCount)
+
+	_, err = collectLedgers(d, 53312001, 53312001)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+}
+
+func TestDatastore_Batches(t *testing.T) {
+	d, err := NewDatastore(t.TempDir(), DatastoreSchema{LedgersPerFile: 4, FilesPerPartition: 2})
+	require.NoError(t, err)
+	assert.Empty(t, d.NetworkPassphrase())
+
+	writeBatch(t, d, 0, 3)
+	writeBatch(t, d, 4, 7)
+	writeBatch(t, d, 8, 11)
+	writeBatch(t, d, 16, 19, 18)
+
+	// Across files and partitions
+	seen, err := collectLedgers(d, 2, 9)
+	require.NoError(t, err)
+	assert.Equal(t, []uint32{2, 3, 4, 5, 6, 7, 8, 9}, seen)
+
+	// Missing file
+	seen, err = collectLedgers(d, 10, 13)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+	assert.Equal(t, []uint32{10, 11}, seen)
+
+	// Ledger missing inside a file
+	seen, err = collectLedgers(d, 16, 19)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+	assert.Equal(t, []uint32{16, 17}, seen)
+}
+
+func TestDatastore_ObjectKey(t *testing.T) {
+	d := &Datastore{schema: DatastoreSchema{LedgersPerFile: 1, FilesPerPartition: 64000}}
+	assert.Equal(t, "FCD285FF--53312000-53375999/FCD285FF--53312000", d.objectKey(53312000))
+
+	d = &Datastore{schema: DatastoreSchema{LedgersPerFile: 10, FilesPerPartition: 1}}
+	assert.Equal(t, "FFFFFFF5--10-19", d.objectKey(15))
+}
@@ -0,0 +1,66 @@
+package source
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strconv"
+
+	"github.com/stellar/go/xdr"
+)
+
+// Dir reads exported ledger meta from a local directory holding one XDR-encoded
+// LedgerCloseMeta per ledger, named by sequence (e.g. 123456.xdr)
+type Dir struct {
+	path string
+}
+
+// NewDir returns a source reading from the directory at path
+func NewDir(path string) (*Dir, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, fmt.Errorf("open history dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("history dir %s is not a directory", path)
+	}
+	return &Dir{path: path}, nil
+}
+
+// Ledgers reads [start, end] from the directory; a missing file is ErrLedgerNotFound
+func (d *Dir) Ledgers(ctx context.Context, start, end uint32, fn func(lcm xdr.LedgerCloseMeta) error) error {
+	for seq := start; seq <= end; seq++ {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		data, err := os.ReadFile(d.file(seq))
+		if errors.Is(err, fs.ErrNotExist) {
+			return fmt.Errorf("ledger %d: %w", seq, ErrLedgerNotFound)
+		}
+		if err != nil {
+			return fmt.Errorf("read ledger %d: %w", seq, err)
+		}
+
+		var lcm xdr.LedgerCloseMeta
+		if err := xdr.SafeUnmarshal(data, &lcm); err != nil {
+			return fmt.Errorf("decode ledger %d meta: %w", seq, err)
+		}
+		if lcm.LedgerSequence() != seq {
+			return fmt.Errorf("ledger file %s holds ledger %d", d.file(seq), lcm.LedgerSequence())
+		}
+		if err := fn(lcm); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (d *Dir) file(seq uint32) string {
+	return filepath.Join(d.path, strconv.FormatUint(uint64(seq), 10)+".xdr")
+}
@@ -0,0 +1,77 @@
+package source
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func ledgerMeta(seq uint32) xdr.LedgerCloseMeta {
+	return xdr.LedgerCloseMeta{
+		V: 1,
+		V1: &xdr.LedgerCloseMetaV1{
+			LedgerHeader: xdr.LedgerHeaderHistoryEntry{
+				Header: xdr.LedgerHeader{LedgerSeq: xdr.Uint32(seq)},
+			},
+			TxSet: xdr.GeneralizedTransactionSet{
+				V:       1,
+				V1TxSet: &xdr.TransactionSetV1{},
+			},
+		},
+	}
+}
+
+// writeLedgers exports the given ledgers into dir
+func writeLedgers(t *testing.T, dir string, seqs ...uint32) {
+	for _, seq := range seqs {
+		data, err := ledgerMeta(seq).MarshalBinary()
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(dir, fmt.Sprintf("%d.xdr", seq)), data, 0o644))
+	}
+}
+
+func TestDir_Ledgers(t *testing.T) {
+	path := t.TempDir()
+	writeLedgers(t, path, 100, 101, 102, 104)
+
+	dir, err := NewDir(path)
+	require.NoError(t, err)
+
+	var seen []uint32
+	collect := func(lcm xdr.LedgerCloseMeta) error {
+		seen = append(seen, lcm.LedgerSequence())
+		return nil
+	}
+
+	require.NoError(t, dir.Ledgers(context.Background(), 100, 102, collect))
+	assert.Equal(t, []uint32{100, 101, 102}, seen)
+
+	// Ledgers before the missing one are still delivered
+	seen = nil
+	err = dir.Ledgers(context.Background(), 102, 104, collect)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+	assert.Equal(t, []uint32{102}, seen)
+}
+
+func TestDir_WrongLedger(t *testing.T) {
+	path := t.TempDir()
+	data, err := ledgerMeta(7).MarshalBinary()
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(filepath.Join(path, "8.xdr"), data, 0o644))
+
+	dir, err := NewDir(path)
+	require.NoError(t, err)
+	err = dir.Ledgers(context.Background(), 8, 8, func(xdr.LedgerCloseMeta) error { return nil })
+	assert.ErrorContains(t, err, "holds ledger 7")
+}
+
+func TestNewDir_Missing(t *testing.T) {
+	_, err := NewDir(filepath.Join(t.TempDir(), "missing"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,78 @@
+package source
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/stellar/go/xdr"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+)
+
+// maxLedgersPerRequest is the largest page getLedgers accepts
+const maxLedgersPerRequest = 200
+
+// RPC reads ledgers from a Stellar RPC through getLedgers
+type RPC struct {
+	client   *client.Client
+	pageSize uint
+}
+
+// NewRPC returns a source paging through getLedgers with up to pageSize ledgers per request
+func NewRPC(rpcClient *client.Client, pageSize uint) *RPC {
+	if pageSize == 0 || pageSize > maxLedgersPerRequest {
+		pageSize = maxLedgersPerRequest
+	}
+	return &RPC{client: rpcClient, pageSize: pageSize}
+}
+
+// Ledgers pages through getLedgers from start and calls fn for every ledger up to end
+// A response that stops short of end is ErrLedgerNotFound for the first ledger it lacks
+func (r *RPC) Ledgers(ctx context.Context, start, end uint32, fn func(lcm xdr.LedgerCloseMeta) error) error {
+	req := client.GetLedgersRequest{
+		StartLedger: start,
+		Pagination: &client.LedgerPaginationParams{
+			Limit: r.pageSize,
+		},
+	}
+
+	next := start
+	for {
+		select {
+		case <-ctx.Done():
+			return ctx.Err()
+		default:
+		}
+
+		resp, err := r.client.GetLedgers(ctx, req)
+		if err != nil {
+			return fmt.Errorf("get ledgers: %w", err)
+		}
+
+		for _, info := range resp.Ledgers {
+			if info.Sequence > end {
+				return nil
+			}
+
+			var lcm xdr.LedgerCloseMeta
+			if err := xdr.SafeUnmarshalBase64(info.MetadataXdr, &lcm); err != nil {
+				return fmt.Errorf("decode ledger %d meta: %w", info.Sequence, err)
+			}
+			if err := fn(lcm); err != nil {
+				return err
+			}
+			next = info.Sequence + 1
+		}
+
+		if next > end {
+			return nil
+		}
+		if len(resp.Ledgers) == 0 || resp.Cursor == "" {
+			return fmt.Errorf("ledger %d: %w", next, ErrLedgerNotFound)
+		}
+
+		// Next page: the RPC rejects requests that set both startLedger and cursor
+		req.StartLedger = 0
+		req.Pagination.Cursor = resp.Cursor
+	}
+}
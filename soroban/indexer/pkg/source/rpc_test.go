@@ -0,0 +1,95 @@
+package source
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stellar/go/xdr"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+)
+
+// rpcServer serves getLedgers from the given ledgers, two per page
+func rpcServer(t *testing.T, seqs ...uint32) *httptest.Server {
+	return httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     interface{}              `json:"id"`
+			Params client.GetLedgersRequest `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		from := req.Params.StartLedger
+		if req.Params.Pagination != nil && req.Params.Pagination.Cursor != "" {
+			var cursor uint32
+			require.NoError(t, json.Unmarshal([]byte(req.Params.Pagination.Cursor), &cursor))
+			from = cursor + 1
+		}
+
+		resp := client.GetLedgersResponse{}
+		for _, seq := range seqs {
+			if seq < from || len(resp.Ledgers) == 2 {
+				continue
+			}
+			meta, err := xdr.MarshalBase64(ledgerMeta(seq))
+			require.NoError(t, err)
+			resp.Ledgers = append(resp.Ledgers, client.LedgerInfo{Sequence: seq, MetadataXdr: meta})
+		}
+		if n := len(resp.Ledgers); n == 2 {
+			cursor, _ := json.Marshal(resp.Ledgers[n-1].Sequence)
+			resp.Cursor = string(cursor)
+		}
+
+		result, err := json.Marshal(resp)
+		require.NoError(t, err)
+		require.NoError(t, json.NewEncoder(w).Encode(map[string]interface{}{
+			"jsonrpc": "2.0",
+			"id":      req.ID,
+			"result":  json.RawMessage(result),
+		}))
+	}))
+}
+
+func TestRPC_Ledgers(t *testing.T) {
+	server := rpcServer(t, 100, 101, 102, 103, 104)
+	defer server.Close()
+	rpc := NewRPC(client.NewClient(server.URL), 2)
+
+	var seen []uint32
+	collect := func(lcm xdr.LedgerCloseMeta) error {
+		seen = append(seen, lcm.LedgerSequence())
+		return nil
+	}
+
+	require.NoError(t, rpc.Ledgers(context.Background(), 100, 103, collect))
+	assert.Equal(t, []uint32{100, 101, 102, 103}, seen)
+}
+
+func TestRPC_LedgersShortResponse(t *testing.T) {
+	server := rpcServer(t, 100, 101, 102)
+	defer server.Close()
+	rpc := NewRPC(client.NewClient(server.URL), 2)
+
+	var seen []uint32
+	collect := func(lcm xdr.LedgerCloseMeta) error {
+		seen = append(seen, lcm.LedgerSequence())
+		return nil
+	}
+
+	// Ledgers the RPC returned are still delivered, the first missing one is an error
+	err := rpc.Ledgers(context.Background(), 100, 104, collect)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+	assert.ErrorContains(t, err, "ledger 103")
+	assert.Equal(t, []uint32{100, 101, 102}, seen)
+
+	// Nothing at all from start
+	seen = nil
+	err = rpc.Ledgers(context.Background(), 110, 112, collect)
+	assert.ErrorIs(t, err, ErrLedgerNotFound)
+	assert.ErrorContains(t, err, "ledger 110")
+	assert.Empty(t, seen)
+}
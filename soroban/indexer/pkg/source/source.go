@@ -0,0 +1,18 @@
+// Package source provides ledger history that no longer lives in the RPC's retention window
+package source
+
+import (
+	"context"
+	"errors"
+
+	"github.com/stellar/go/xdr"
+)
+
+// ErrLedgerNotFound is returned when a source doesn't hold a requested ledger
+var ErrLedgerNotFound = errors.New("ledger not found in history source")
+
+// Source yields LedgerCloseMeta for a range of ledgers
+type Source interface {
+	// Ledgers calls fn for every ledger in [start, end], in order, stopping at the first error
+	Ledgers(ctx context.Context, start, end uint32, fn func(lcm xdr.LedgerCloseMeta) error) error
+}
@@ -0,0 +1,105 @@
+package verify
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+)
+
+// ErrGapsFound is returned by Command when the verified range needs repair
+var ErrGapsFound = errors.New("verification found missing ledgers")
+
+// Command implements the `verify` CLI subcommand
+//
+//	indexer verify [-start-ledger N] [-end-ledger N] [-sample N] [-repair] [-run-repairs] [-json]
+//
+// It prints the report to out and returns ErrGapsFound when anything is missing, so the exit
+// status can drive alerts. -run-repairs runs every scheduled repair job after verifying.
+func Command(ctx context.Context, v *Verifier, backfiller Backfiller, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("verify", flag.ContinueOnError)
+	flags.SetOutput(out)
+	startLedger := flags.Uint("start-ledger", 0, "First ledger to verify (0 = first covered ledger)")
+	endLedger := flags.Uint("end-ledger", 0, "Last ledger to verify (0 = last covered ledger)")
+	sampleSize := flags.Int("sample", 5, "Ledgers to cross-check against the RPC")
+	repair := flags.Bool("repair", false, "Schedule repair backfills for missing ranges")
+	runRepairs := flags.Bool("run-repairs", false, "Run scheduled repair backfills after verifying (implies -repair)")
+	asJSON := flags.Bool("json", false, "Print the report as JSON")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+
+	report, err := v.Verify(ctx, Config{
+		StartLedger: uint32(*startLedger),
+		EndLedger:   uint32(*endLedger),
+		SampleSize:  *sampleSize,
+		Repair:      *repair || *runRepairs,
+	})
+	if err != nil {
+		return err
+	}
+
+	if *asJSON {
+		encoder := json.NewEncoder(out)
+		encoder.SetIndent("", "  ")
+		if err := encoder.Encode(report); err != nil {
+			return err
+		}
+	} else {
+		writeReport(out, report)
+	}
+
+	if *runRepairs {
+		if err := v.RunRepairs(ctx, backfiller); err != nil {
+			return err
+		}
+	}
+
+	if !report.OK() {
+		return ErrGapsFound
+	}
+	return nil
+}
+
+// writeReport prints a human readable report
+func writeReport(out io.Writer, report *Report) {
+	fmt.Fprintf(out, "Verified ledgers %d-%d\n", report.StartLedger, report.EndLedger)
+
+	fmt.Fprintf(out, "Coverage gaps: %d\n", len(report.CoverageGaps))
+	for _, gap := range report.CoverageGaps {
+		fmt.Fprintf(out, "  %d-%d (%d ledgers)\n", gap.Start, gap.End, gap.End-gap.Start+1)
+	}
+	fmt.Fprintf(out, "Event holes: %d\n", len(report.EventHoles))
+	fmt.Fprintf(out, "Transaction holes: %d\n", len(report.TransactionHoles))
+	fmt.Fprintf(out, "Chain breaks: %d\n", len(report.ChainBreaks))
+	for _, ledger := range report.ChainBreaks {
+		fmt.Fprintf(out, "  %d\n", ledger)
+	}
+
+	fmt.Fprintf(out, "Sampled ledgers: %d\n", len(report.Samples))
+	for _, sample := range report.Samples {
+		status := "ok"
+		if sample.Error != "" {
+			status = "error: " + sample.Error
+		} else if !sample.Match() {
+			status = "MISMATCH"
+		}
+		fmt.Fprintf(out, "  %d events %d/%d transactions %d/%d %s\n", sample.Ledger,
+			sample.StoredEvents, sample.RPCEvents, sample.StoredTransactions, sample.RPCTransactions, status)
+	}
+
+	if len(report.Repairs) > 0 {
+		fmt.Fprintf(out, "Scheduled repairs: %d\n", len(report.Repairs))
+		for _, job := range report.Repairs {
+			fmt.Fprintf(out, "  %s\n", job)
+		}
+	}
+
+	if report.OK() {
+		fmt.Fprintln(out, "OK")
+	} else {
+		fmt.Fprintln(out, "MISSING DATA")
+	}
+}
@@ -0,0 +1,384 @@
+// Package verify finds ledgers the indexer is missing and schedules backfills to repair them
+package verify
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/sirupsen/logrus"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/poller"
+)
+
+// RepairJobPrefix prefixes the backfill jobs scheduled by verify
+const RepairJobPrefix = "repair-"
+
+// rpcPageLimit is the page size used to count a ledger's events and transactions through the RPC
+const rpcPageLimit = 200
+
+// Config selects what a verification run checks
+type Config struct {
+	StartLedger uint32 // First ledger to check (0 = first covered ledger)
+	EndLedger   uint32 // Last ledger to check (0 = last covered ledger)
+	SampleSize  int    // Ledgers whose counts are cross-checked against the RPC (default: 5)
+	Repair      bool   // Schedule a repair backfill for every missing or mismatched range
+}
+
+// CountCheck compares the stored and RPC counts of one sampled ledger
+type CountCheck struct {
+	Ledger             uint32 `json:"ledger"`
+	StoredEvents       int64  `json:"storedEvents"`
+	RPCEvents          int64  `json:"rpcEvents"`
+	StoredTransactions int64  `json:"storedTransactions"`
+	RPCTransactions    int64  `json:"rpcTransactions"`
+	Error              string `json:"error,omitempty"` // Set when the RPC could not be queried
+}
+
+// Match reports whether the stored counts equal the RPC counts
+func (c CountCheck) Match() bool {
+	return c.Error == "" && c.StoredEvents == c.RPCEvents && c.StoredTransactions == c.RPCTransactions
+}
+
+// Report is the result of a verification run
+type Report struct {
+	StartLedger uint32 `json:"startLedger"`
+	EndLedger   uint32 `json:"endLedger"`
+
+	// Ledgers never recorded as ingested
+	CoverageGaps []models.LedgerRange `json:"coverageGaps"`
+	// Ledgers without rows between ledgers with rows; normal for quiet ledgers, so informational
+	EventHoles       []models.LedgerRange `json:"eventHoles"`
+	TransactionHoles []models.LedgerRange `json:"transactionHoles"`
+	// Ledgers whose previous hash doesn't match the stored previous ledger
+	ChainBreaks []uint32 `json:"chainBreaks"`
+
+	Samples []CountCheck `json:"samples"`
+	Repairs []string     `json:"repairs"` // Backfill jobs scheduled by this run
+
+	CheckedAt time.Time     `json:"checkedAt"`
+	Duration  time.Duration `json:"duration"`
+}
+
+// OK reports whether the run found nothing to repair
+func (r *Report) OK() bool {
+	if len(r.CoverageGaps) > 0 || len(r.ChainBreaks) > 0 {
+		return false
+	}
+	for _, sample := range r.Samples {
+		if !sample.Match() {
+			return false
+		}
+	}
+	return true
+}
+
+// Backfiller runs a backfill job; implemented by *poller.Poller
+type Backfiller interface {
+	Backfill(ctx context.Context, config poller.BackfillConfig) error
+}
+
+// Verifier checks the stored ledger range for gaps and schedules repairs
+type Verifier struct {
+	db        *gorm.DB
+	rpcClient *client.Client
+	logger    *logrus.Logger
+
+	mu   sync.Mutex
+	last *Report
+}
+
+// New creates a verifier
+func New(db *gorm.DB, rpcClient *client.Client, logger *logrus.Logger) *Verifier {
+	return &Verifier{
+		db:        db,
+		rpcClient: rpcClient,
+		logger:    logger,
+	}
+}
+
+// LastReport returns the report of the most recent run (nil before the first run)
+func (v *Verifier) LastReport() *Report {
+	v.mu.Lock()
+	defer v.mu.Unlock()
+	return v.last
+}
+
+// Verify scans the configured range and returns what is missing
+func (v *Verifier) Verify(ctx context.Context, config Config) (*Report, error) {
+	start := time.Now()
+
+	if config.SampleSize <= 0 {
+		config.SampleSize = 5
+	}
+
+	if config.StartLedger == 0 || config.EndLedger == 0 {
+		first, last, err := models.GetCoveredRange(v.db)
+		if err != nil {
+			return nil, fmt.Errorf("get covered range: %w", err)
+		}
+		if config.StartLedger == 0 {
+			config.StartLedger = first
+		}
+		if config.EndLedger == 0 {
+			config.EndLedger = last
+		}
+	}
+	if config.StartLedger == 0 || config.StartLedger > config.EndLedger {
+		return nil, fmt.Errorf("nothing to verify: ledger range %d-%d", config.StartLedger, config.EndLedger)
+	}
+
+	report := &Report{
+		StartLedger: config.StartLedger,
+		EndLedger:   config.EndLedger,
+		CheckedAt:   start.UTC(),
+	}
+
+	var err error
+	if report.CoverageGaps, err = models.FindCoverageGaps(v.db, config.StartLedger, config.EndLedger); err != nil {
+		return nil, fmt.Errorf("find coverage gaps: %w", err)
+	}
+	if report.EventHoles, err = models.FindEventLedgerHoles(v.db, config.StartLedger, config.EndLedger); err != nil {
+		return nil, fmt.Errorf("find event holes: %w", err)
+	}
+	if report.TransactionHoles, err = models.FindTransactionLedgerHoles(v.db, config.StartLedger, config.EndLedger); err != nil {
+		return nil, fmt.Errorf("find transaction holes: %w", err)
+	}
+	if report.ChainBreaks, err = models.FindLedgerChainBreaks(v.db, config.StartLedger, config.EndLedger); err != nil {
+		return nil, fmt.Errorf("find chain breaks: %w", err)
+	}
+
+	for _, ledger := range sampleLedgers(report, config.SampleSize) {
+		report.Samples = append(report.Samples, v.checkCounts(ctx, ledger))
+	}
+
+	if config.Repair {
+		if report.Repairs, err = v.scheduleRepairs(report); err != nil {
+			return nil, err
+		}
+	}
+
+	report.Duration = time.Since(start)
+
+	v.mu.Lock()
+	v.last = report
+	v.mu.Unlock()
+
+	v.logger.WithFields(logrus.Fields{
+		"startLedger":  report.StartLedger,
+		"endLedger":    report.EndLedger,
+		"coverageGaps": len(report.CoverageGaps),
+		"chainBreaks":  len(report.ChainBreaks),
+		"samples":      len(report.Samples),
+		"repairs":      len(report.Repairs),
+		"ok":           report.OK(),
+		"duration":     report.Duration,
+	}).Info("Verification finished")
+
+	return report, nil
+}
+
+// sampleLedgers picks the ledgers to cross-check against the RPC
+// The first ledger of each event/transaction hole comes first, since that is where a missed
+// ledger would hide; the remaining samples are spread evenly over the covered ledgers
+func sampleLedgers(report *Report, size int) []uint32 {
+	seen := make(map[uint32]bool)
+	var samples []uint32
+	add := func(ledger uint32) {
+		if len(samples) < size && !seen[ledger] && covered(report.CoverageGaps, ledger) {
+			seen[ledger] = true
+			samples = append(samples, ledger)
+		}
+	}
+
+	for _, holes := range [][]models.LedgerRange{report.TransactionHoles, report.EventHoles} {
+		for _, hole := range holes {
+			add(hole.Start)
+		}
+	}
+
+	span := uint64(report.EndLedger) - uint64(report.StartLedger) + 1
+	remaining := size - len(samples)
+	for i := 0; i < remaining; i++ {
+		add(uint32(uint64(report.StartLedger) + span*uint64(i)/uint64(remaining)))
+	}
+
+	return samples
+}
+
+// covered reports whether ledger lies outside every gap
+func covered(gaps []models.LedgerRange, ledger uint32) bool {
+	for _, gap := range gaps {
+		if ledger >= gap.Start && ledger <= gap.End {
+			return false
+		}
+	}
+	return true
+}
+
+// checkCounts compares the stored events and transactions of a ledger with the RPC
+func (v *Verifier) checkCounts(ctx context.Context, ledger uint32) CountCheck {
+	check := CountCheck{Ledger: ledger}
+
+	var err error
+	if check.StoredEvents, err = models.CountEventsInLedger(v.db, ledger); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if check.StoredTransactions, err = models.CountTransactionsInLedger(v.db, ledger); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if check.RPCEvents, err = v.countRPCEvents(ctx, ledger); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+	if check.RPCTransactions, err = v.countRPCTransactions(ctx, ledger); err != nil {
+		check.Error = err.Error()
+		return check
+	}
+
+	return check
+}
+
+// countRPCEvents counts the events the RPC returns for a single ledger
+func (v *Verifier) countRPCEvents(ctx context.Context, ledger uint32) (int64, error) {
+	req := client.GetEventsRequest{
+		StartLedger: ledger,
+		Pagination:  &client.EventPaginationParams{Limit: rpcPageLimit},
+	}
+
+	var count int64
+	for {
+		resp, err := v.rpcClient.GetEvents(ctx, req)
+		if err != nil {
+			return 0, fmt.Errorf("get events: %w", err)
+		}
+		for _, event := range resp.Events {
+			if event.Ledger > ledger {
+				return count, nil
+			}
+			count++
+		}
+		if len(resp.Events) < rpcPageLimit || resp.Cursor == "" {
+			return count, nil
+		}
+
+		req.StartLedger = 0
+		req.Pagination.Cursor = resp.Cursor
+	}
+}
+
+// countRPCTransactions counts the transactions the RPC returns for a single ledger
+func (v *Verifier) countRPCTransactions(ctx context.Context, ledger uint32) (int64, error) {
+	var count int64
+	cursor := ""
+	for {
+		resp, err := v.rpcClient.GetTransactions(ctx, ledger, cursor, rpcPageLimit)
+		if err != nil {
+			return 0, fmt.Errorf("get transactions: %w", err)
+		}
+		for _, tx := range resp.Transactions {
+			if tx.Ledger > ledger {
+				return count, nil
+			}
+			count++
+		}
+		if len(resp.Transactions) < rpcPageLimit || resp.Cursor == "" {
+			return count, nil
+		}
+		cursor = resp.Cursor
+	}
+}
+
+// scheduleRepairs creates a backfill job for every gap, chain break and mismatched sample
+// Job names are derived from the range, so scheduling the same range twice is a no-op
+func (v *Verifier) scheduleRepairs(report *Report) ([]string, error) {
+	ranges := append([]models.LedgerRange(nil), report.CoverageGaps...)
+	for _, ledger := range report.ChainBreaks {
+		// The break may be either ledger of the pair
+		ranges = append(ranges, models.LedgerRange{Start: ledger - 1, End: ledger})
+	}
+	for _, sample := range report.Samples {
+		if sample.Error == "" && !sample.Match() {
+			ranges = append(ranges, models.LedgerRange{Start: sample.Ledger, End: sample.Ledger})
+		}
+	}
+
+	var jobs []string
+	for _, r := range ranges {
+		job := fmt.Sprintf("%s%d-%d", RepairJobPrefix, r.Start, r.End)
+		shard := models.BackfillShard{StartLedger: r.Start, EndLedger: r.End}
+		if err := models.CreateBackfillShards(v.db, job, []models.BackfillShard{shard}); err != nil {
+			return jobs, fmt.Errorf("schedule repair %s: %w", job, err)
+		}
+		jobs = append(jobs, job)
+	}
+	return jobs, nil
+}
+
+// RunRepairs runs every scheduled repair job that is not finished yet
+func (v *Verifier) RunRepairs(ctx context.Context, backfiller Backfiller) error {
+	jobs, err := models.GetPendingBackfillJobs(v.db, RepairJobPrefix)
+	if err != nil {
+		return fmt.Errorf("get pending repairs: %w", err)
+	}
+
+	var failed []string
+	for _, job := range jobs {
+		shards, err := models.GetBackfillShards(v.db, job)
+		if err != nil {
+			return fmt.Errorf("get shards of %s: %w", job, err)
+		}
+		if len(shards) == 0 {
+			continue
+		}
+
+		err = backfiller.Backfill(ctx, poller.BackfillConfig{
+			StartLedger: shards[0].StartLedger,
+			EndLedger:   shards[len(shards)-1].EndLedger,
+			Workers:     1,
+			Job:         job,
+		})
+		if ctx.Err() != nil {
+			return ctx.Err()
+		}
+		if err != nil {
+			v.logger.WithError(err).WithField("job", job).Warn("Repair backfill failed")
+			failed = append(failed, job)
+		}
+	}
+
+	if len(failed) > 0 {
+		return fmt.Errorf("%d repair jobs failed: %s", len(failed), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// Run verifies and repairs on every tick until ctx is cancelled
+func (v *Verifier) Run(ctx context.Context, interval time.Duration, config Config, backfiller Backfiller) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := v.Verify(ctx, config); err != nil {
+			v.logger.WithError(err).Warn("Verification failed")
+		}
+		if config.Repair {
+			if err := v.RunRepairs(ctx, backfiller); err != nil && ctx.Err() == nil {
+				v.logger.WithError(err).Warn("Repair failed")
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
@@ -0,0 +1,207 @@
+package verify
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/sirupsen/logrus"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+
+	"github.com/blockroma/soroban-indexer/pkg/client"
+	"github.com/blockroma/soroban-indexer/pkg/models"
+	"github.com/blockroma/soroban-indexer/pkg/poller"
+)
+
+func setupTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(":memory:"), &gorm.Config{})
+	require.NoError(t, err)
+
+	err = db.AutoMigrate(
+		&models.Event{},
+		&models.Transaction{},
+		&models.Ledger{},
+		&models.LedgerCoverage{},
+		&models.BackfillShard{},
+		&models.BackfillRetry{},
+	)
+	require.NoError(t, err)
+
+	return db
+}
+
+// newTestRPC starts a fake RPC that reports the given number of events and transactions per ledger
+func newTestRPC(t *testing.T, events, transactions map[uint32]int) *client.Client {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var req struct {
+			ID     int    `json:"id"`
+			Method string `json:"method"`
+			Params struct {
+				StartLedger uint32 `json:"startLedger"`
+			} `json:"params"`
+		}
+		require.NoError(t, json.NewDecoder(r.Body).Decode(&req))
+
+		var result interface{}
+		ledger := req.Params.StartLedger
+		switch req.Method {
+		case "getEvents":
+			resp := client.GetEventsResponse{Events: []client.Event{}}
+			// Include the next ledger's events to check they are not counted
+			for _, seq := range []uint32{ledger, ledger + 1} {
+				for i := 0; i < events[seq]; i++ {
+					resp.Events = append(resp.Events, client.Event{ID: fmt.Sprintf("%d-%d", seq, i), Ledger: seq})
+				}
+			}
+			result = resp
+		case "getTransactions":
+			resp := client.GetTransactionsResponse{Transactions: []client.TransactionInfo{}}
+			for _, seq := range []uint32{ledger, ledger + 1} {
+				for i := 0; i < transactions[seq]; i++ {
+					resp.Transactions = append(resp.Transactions, client.TransactionInfo{Ledger: seq})
+				}
+			}
+			result = resp
+		default:
+			t.Errorf("Unexpected method %s", req.Method)
+		}
+
+		json.NewEncoder(w).Encode(map[string]interface{}{"jsonrpc": "2.0", "id": req.ID, "result": result})
+	}))
+	t.Cleanup(server.Close)
+	return client.NewClient(server.URL)
+}
+
+func testLogger() *logrus.Logger {
+	logger := logrus.New()
+	logger.SetLevel(logrus.FatalLevel)
+	return logger
+}
+
+// storeLedgerRows stores count events and transactions in ledger
+func storeLedgerRows(t *testing.T, db *gorm.DB, ledger uint32, count int) {
+	for i := 0; i < count; i++ {
+		seq := ledger
+		require.NoError(t, models.UpsertEvent(db, &models.Event{ID: fmt.Sprintf("e-%d-%d", ledger, i), Ledger: int32(ledger)}))
+		require.NoError(t, models.UpsertTransaction(db, &models.Transaction{ID: fmt.Sprintf("t-%d-%d", ledger, i), Ledger: &seq}))
+	}
+}
+
+// fakeBackfiller records backfill calls and completes the job's shards
+type fakeBackfiller struct {
+	db      *gorm.DB
+	configs []poller.BackfillConfig
+}
+
+func (f *fakeBackfiller) Backfill(ctx context.Context, config poller.BackfillConfig) error {
+	f.configs = append(f.configs, config)
+	shards, err := models.GetBackfillShards(f.db, config.Job)
+	if err != nil {
+		return err
+	}
+	for _, shard := range shards {
+		if err := models.UpdateShardCheckpoint(f.db, shard.ID, shard.EndLedger, models.ShardStatusCompleted); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func TestVerify_FindsGapsAndSchedulesRepairs(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, models.RecordLedgerCoverage(db, models.LiveCursor, 100, 105))
+	require.NoError(t, models.RecordLedgerCoverage(db, models.LiveCursor, 110, 120))
+	for _, ledger := range []uint32{100, 101, 102, 110, 115, 120} {
+		storeLedgerRows(t, db, ledger, 1)
+	}
+
+	// Ledger 103 had a transaction the indexer never stored
+	rpc := newTestRPC(t,
+		map[uint32]int{100: 1, 101: 1, 102: 1, 110: 1, 115: 1, 120: 1},
+		map[uint32]int{100: 1, 101: 1, 102: 1, 103: 1, 110: 1, 115: 1, 120: 1},
+	)
+
+	v := New(db, rpc, testLogger())
+	report, err := v.Verify(context.Background(), Config{SampleSize: 3, Repair: true})
+	require.NoError(t, err)
+
+	assert.Equal(t, uint32(100), report.StartLedger)
+	assert.Equal(t, uint32(120), report.EndLedger)
+	assert.Equal(t, []models.LedgerRange{{Start: 106, End: 109}}, report.CoverageGaps)
+	assert.Equal(t, []models.LedgerRange{{Start: 103, End: 109}, {Start: 111, End: 114}, {Start: 116, End: 119}}, report.TransactionHoles)
+	assert.False(t, report.OK())
+
+	// The first covered ledger of each hole is sampled; 106 is a known gap and is skipped
+	require.Len(t, report.Samples, 3)
+	assert.Equal(t, uint32(103), report.Samples[0].Ledger)
+	assert.False(t, report.Samples[0].Match())
+	assert.Equal(t, int64(1), report.Samples[0].RPCTransactions)
+	assert.Equal(t, uint32(111), report.Samples[1].Ledger)
+	assert.True(t, report.Samples[1].Match())
+
+	assert.Equal(t, []string{"repair-106-109", "repair-103-103"}, report.Repairs)
+	assert.Same(t, report, v.LastReport())
+
+	backfiller := &fakeBackfiller{db: db}
+	require.NoError(t, v.RunRepairs(context.Background(), backfiller))
+	require.Len(t, backfiller.configs, 2)
+	assert.Equal(t, poller.BackfillConfig{StartLedger: 103, EndLedger: 103, Workers: 1, Job: "repair-103-103"}, backfiller.configs[0])
+	assert.Equal(t, "repair-106-109", backfiller.configs[1].Job)
+
+	// Finished repairs are not run again
+	require.NoError(t, v.RunRepairs(context.Background(), backfiller))
+	assert.Len(t, backfiller.configs, 2)
+}
+
+func TestVerify_NothingCovered(t *testing.T) {
+	v := New(setupTestDB(t), newTestRPC(t, nil, nil), testLogger())
+
+	_, err := v.Verify(context.Background(), Config{})
+	assert.Error(t, err)
+}
+
+func TestSampleLedgers(t *testing.T) {
+	report := &Report{
+		StartLedger:      100,
+		EndLedger:        199,
+		CoverageGaps:     []models.LedgerRange{{Start: 150, End: 159}},
+		TransactionHoles: []models.LedgerRange{{Start: 120, End: 121}, {Start: 150, End: 160}},
+		EventHoles:       []models.LedgerRange{{Start: 120, End: 130}},
+	}
+
+	// Hole starts first (120 once, 150 is inside a gap), then evenly spread ledgers
+	assert.Equal(t, []uint32{120, 100, 125, 175}, sampleLedgers(report, 5)[:4])
+	assert.Equal(t, []uint32{120}, sampleLedgers(report, 1))
+}
+
+func TestCommand(t *testing.T) {
+	db := setupTestDB(t)
+	require.NoError(t, models.RecordLedgerCoverage(db, models.LiveCursor, 100, 101))
+	storeLedgerRows(t, db, 100, 2)
+	storeLedgerRows(t, db, 101, 1)
+
+	v := New(db, newTestRPC(t, map[uint32]int{100: 2, 101: 1}, map[uint32]int{100: 2, 101: 1}), testLogger())
+
+	var out bytes.Buffer
+	require.NoError(t, Command(context.Background(), v, nil, []string{"-sample", "2"}, &out))
+	assert.Contains(t, out.String(), "Verified ledgers 100-101")
+	assert.True(t, strings.HasSuffix(out.String(), "OK\n"))
+
+	// A range past the covered ledgers is reported as missing
+	out.Reset()
+	err := Command(context.Background(), v, nil, []string{"-end-ledger", "105", "-json"}, &out)
+	assert.ErrorIs(t, err, ErrGapsFound)
+
+	var report Report
+	require.NoError(t, json.Unmarshal(out.Bytes(), &report))
+	assert.Equal(t, []models.LedgerRange{{Start: 102, End: 105}}, report.CoverageGaps)
+	assert.Empty(t, report.Repairs)
+}
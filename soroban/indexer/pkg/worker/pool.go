@@ -2,6 +2,7 @@ package worker
 
 import (
 	"context"
+	"sort"
 	"sync"
 )
 
@@ -14,10 +15,18 @@ type Result struct {
 	Index int // Original index in the task list
 }
 
+// indexedTask is a task together with its submission order
+type indexedTask struct {
+	index int
+	task  Task
+}
+
 // Pool manages a pool of workers that execute tasks concurrently
 type Pool struct {
 	maxWorkers int
-	taskQueue  chan Task
+	taskQueue  chan indexedTask
+	submitted  int
+	ctx        context.Context
 	results    []Result
 	resultsMu  sync.Mutex
 	wg         sync.WaitGroup
@@ -31,13 +40,14 @@ func NewPool(maxWorkers int) *Pool {
 
 	return &Pool{
 		maxWorkers: maxWorkers,
-		taskQueue:  make(chan Task, maxWorkers*2), // Buffer to prevent blocking
+		taskQueue:  make(chan indexedTask, maxWorkers*2), // Buffer to prevent blocking
 		results:    make([]Result, 0),
 	}
 }
 
 // Start begins the worker pool
 func (p *Pool) Start(ctx context.Context) {
+	p.ctx = ctx
 	for i := 0; i < p.maxWorkers; i++ {
 		p.wg.Add(1)
 		go p.worker(ctx)
@@ -52,24 +62,43 @@ func (p *Pool) worker(ctx context.Context) {
 		select {
 		case <-ctx.Done():
 			return
-		case task, ok := <-p.taskQueue:
+		case item, ok := <-p.taskQueue:
 			if !ok {
 				return
 			}
 			// Execute the task
-			err := task(ctx)
+			err := item.task(ctx)
 
 			// Store result safely
-			p.resultsMu.Lock()
-			p.results = append(p.results, Result{Error: err})
-			p.resultsMu.Unlock()
+			p.addResult(Result{Error: err, Index: item.index})
 		}
 	}
 }
 
+// addResult records a task outcome
+func (p *Pool) addResult(result Result) {
+	p.resultsMu.Lock()
+	p.results = append(p.results, result)
+	p.resultsMu.Unlock()
+}
+
 // Submit adds a task to the worker pool
+// Tasks are numbered in submission order (Result.Index); if the pool's context is cancelled
+// before the task can be queued, it is recorded as failed with the context error
 func (p *Pool) Submit(task Task) {
-	p.taskQueue <- task
+	item := indexedTask{index: p.submitted, task: task}
+	p.submitted++
+
+	if p.ctx == nil {
+		p.taskQueue <- item
+		return
+	}
+
+	select {
+	case p.taskQueue <- item:
+	case <-p.ctx.Done():
+		p.addResult(Result{Error: p.ctx.Err(), Index: item.index})
+	}
 }
 
 // Wait closes the task queue and waits for all workers to finish
@@ -86,6 +115,7 @@ func (p *Pool) Wait() []Result {
 
 // Execute runs multiple tasks concurrently and collects results
 // This is a convenience method that creates a pool, executes tasks, and waits
+// Results are sorted by Index, i.e. in the order of tasks
 func Execute(ctx context.Context, maxWorkers int, tasks []Task) []Result {
 	pool := NewPool(maxWorkers)
 	pool.Start(ctx)
@@ -94,5 +124,7 @@ func Execute(ctx context.Context, maxWorkers int, tasks []Task) []Result {
 		pool.Submit(task)
 	}
 
-	return pool.Wait()
+	results := pool.Wait()
+	sort.Slice(results, func(i, j int) bool { return results[i].Index < results[j].Index })
+	return results
 }
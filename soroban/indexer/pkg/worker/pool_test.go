@@ -221,3 +221,57 @@ func TestPool_EmptyTasks(t *testing.T) {
 		t.Errorf("expected 0 results, got %d", len(results))
 	}
 }
+
+func TestExecute_ResultsInTaskOrder(t *testing.T) {
+	ctx := context.Background()
+	taskCount := 20
+
+	tasks := make([]Task, taskCount)
+	for i := 0; i < taskCount; i++ {
+		i := i
+		tasks[i] = func(ctx context.Context) error {
+			// Finish later tasks first
+			time.Sleep(time.Duration(taskCount-i) * time.Millisecond)
+			if i%3 == 0 {
+				return errors.New("multiple of three")
+			}
+			return nil
+		}
+	}
+
+	results := Execute(ctx, 4, tasks)
+	if len(results) != taskCount {
+		t.Fatalf("expected %d results, got %d", taskCount, len(results))
+	}
+
+	for i, result := range results {
+		if result.Index != i {
+			t.Errorf("result %d has index %d", i, result.Index)
+		}
+		if (result.Error != nil) != (i%3 == 0) {
+			t.Errorf("result %d error = %v", i, result.Error)
+		}
+	}
+}
+
+func TestPool_SubmitAfterCancel(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	pool := NewPool(1)
+	pool.Start(ctx)
+	cancel()
+
+	// Workers are gone - submitting more tasks than the buffer holds must not block
+	done := make(chan []Result)
+	go func() {
+		for i := 0; i < 10; i++ {
+			pool.Submit(func(ctx context.Context) error { return nil })
+		}
+		done <- pool.Wait()
+	}()
+
+	select {
+	case <-done:
+	case <-time.After(time.Second):
+		t.Fatal("Submit blocked after context cancellation")
+	}
+}